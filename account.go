@@ -4,7 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
 )
@@ -71,7 +71,7 @@ func (r *AccountResolver) resolveWithDB(snap CredentialSnapshot) AccountInfo {
 
 	info, err := r.fetchProfile(snap.AccessToken)
 	if err != nil {
-		log.Printf("Profile API error: %v — using hash as account ID", err)
+		slog.Warn("Profile API error, using hash as account ID", "error", err)
 		info = AccountInfo{AccountUUID: snap.RefreshTokenHash}
 	}
 
@@ -91,7 +91,7 @@ func (r *AccountResolver) resolveInMemory(snap CredentialSnapshot) AccountInfo {
 	// Fetch outside the lock to avoid holding it during HTTP I/O.
 	info, err := r.fetchProfile(snap.AccessToken)
 	if err != nil {
-		log.Printf("Profile API error: %v — using hash as account ID", err)
+		slog.Warn("Profile API error, using hash as account ID", "error", err)
 		// Cached so we don't hammer a failing API on every poll cycle;
 		// clears on token rotation or process restart.
 		info = AccountInfo{AccountUUID: snap.RefreshTokenHash}
@@ -131,7 +131,7 @@ func (r *AccountResolver) fetchProfile(accessToken string) (AccountInfo, error)
 		return AccountInfo{}, fmt.Errorf("parse profile: %w", err)
 	}
 	if raw.Account.UUID == "" {
-		log.Printf("Warning: profile API returned empty account UUID — response structure may have changed")
+		slog.Warn("Profile API returned empty account UUID, response structure may have changed")
 	}
 	return AccountInfo{
 		AccountUUID:      raw.Account.UUID,
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+// benchmarkWarmup is the number of untimed renders done first so font loading
+// and cache fills don't skew the measurements.
+const benchmarkWarmup = 10
+
+// iconBenchmark holds timing results for runIconBenchmark.
+type iconBenchmark struct {
+	N     int
+	Total time.Duration
+	Avg   time.Duration
+	Min   time.Duration
+	Max   time.Duration
+	P99   time.Duration
+}
+
+// runIconBenchmark renders and PNG-encodes the icon n times with synthetic
+// data using cfg's render settings, after benchmarkWarmup discarded renders.
+func runIconBenchmark(cfg Config, n int) (iconBenchmark, error) {
+	if n <= 0 {
+		return iconBenchmark{}, fmt.Errorf("benchmark count must be positive, got %d", n)
+	}
+	opts := RenderOptionsFromConfig(cfg)
+	render := func(i int) error {
+		// Vary utilization so every threshold color and fill level is exercised.
+		u := Utilization(i % 101)
+		proj := u * 1.5
+		state := QuotaState{FiveHour: &u, FiveHourProjected: &proj, SevenDay: &u}
+		_, err := encodePNG(renderIcon(state, cfg.Thresholds, opts))
+		return err
+	}
+
+	for i := range benchmarkWarmup {
+		if err := render(i); err != nil {
+			return iconBenchmark{}, err
+		}
+	}
+
+	durations := make([]time.Duration, n)
+	for i := range n {
+		start := time.Now()
+		if err := render(i); err != nil {
+			return iconBenchmark{}, err
+		}
+		durations[i] = time.Since(start)
+	}
+
+	slices.Sort(durations)
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return iconBenchmark{
+		N:     n,
+		Total: total,
+		Avg:   total / time.Duration(n),
+		Min:   durations[0],
+		Max:   durations[n-1],
+		P99:   durations[(n*99+99)/100-1],
+	}, nil
+}
+
+// printIconBenchmark writes the benchmark results in human-readable form.
+func printIconBenchmark(w io.Writer, b iconBenchmark) {
+	fmt.Fprintf(w, "Rendered %d icons in %dms (avg %dµs/icon)\n",
+		b.N, b.Total.Milliseconds(), b.Avg.Microseconds())
+	fmt.Fprintf(w, "min %dµs, max %dµs, p99 %dµs\n",
+		b.Min.Microseconds(), b.Max.Microseconds(), b.P99.Microseconds())
+}
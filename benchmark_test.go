@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunIconBenchmark(t *testing.T) {
+	b, err := runIconBenchmark(defaultConfig(), 10)
+	if err != nil {
+		t.Fatalf("runIconBenchmark() error: %v", err)
+	}
+	if b.N != 10 {
+		t.Errorf("N = %d, want 10", b.N)
+	}
+	if b.Avg <= 0 {
+		t.Errorf("Avg = %v, want positive", b.Avg)
+	}
+	if b.Min > b.Avg || b.Avg > b.Max || b.P99 > b.Max || b.P99 < b.Min {
+		t.Errorf("inconsistent stats: min %v avg %v p99 %v max %v", b.Min, b.Avg, b.P99, b.Max)
+	}
+
+	var buf bytes.Buffer
+	printIconBenchmark(&buf, b)
+	if !strings.HasPrefix(buf.String(), "Rendered 10 icons in ") {
+		t.Errorf("output = %q", buf.String())
+	}
+}
+
+func TestRunIconBenchmark_InvalidCount(t *testing.T) {
+	if _, err := runIconBenchmark(defaultConfig(), 0); err == nil {
+		t.Error("runIconBenchmark(0) should fail")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// completionShells lists the shells -generate-completion supports.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionValues maps flag names to the values offered for them.
+var completionValues = map[string][]string{
+	"font-name":           fontNames,
+	"indicator":           indicatorNames,
+	"color-source":        colorSources,
+	"icon-window":         iconWindows,
+	"quota-window":        iconWindows,
+	"time-display":        timeDisplays,
+	"log-format":          logFormats,
+	"generate-completion": completionShells,
+}
+
+// completionFlag is a flag as seen by the completion generators.
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool     // takes no argument
+	values []string // known argument values, nil when free-form
+}
+
+// completionFlags collects the flags defined in fs, sorted by name.
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:   f.Name,
+			usage:  f.Usage,
+			isBool: ok && b.IsBoolFlag(),
+			values: completionValues[f.Name],
+		})
+	})
+	return flags
+}
+
+// subcommandNames returns the subcommand words, sorted.
+func subcommandNames() []string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// generateCompletion writes a completion script for shell covering the flags
+// in fs and the subcommands.
+func generateCompletion(w io.Writer, shell string, fs *flag.FlagSet) error {
+	flags := completionFlags(fs)
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, flags)
+	case "zsh":
+		writeZshCompletion(w, flags)
+	case "fish":
+		writeFishCompletion(w, flags)
+	default:
+		return fmt.Errorf("unsupported shell %q, want one of: %s", shell, strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+// writeBashCompletion completes subcommands as the first word, flag values
+// after flags that have known values, and flag names otherwise.
+func writeBashCompletion(w io.Writer, flags []completionFlag) {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.name
+	}
+
+	fmt.Fprintln(w, "# bash completion for claude-quota")
+	fmt.Fprintln(w, "_claude_quota() {")
+	fmt.Fprintln(w, `	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `	case "$prev" in`)
+	for _, f := range flags {
+		if len(f.values) > 0 {
+			fmt.Fprintf(w, "\t-%s|--%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n",
+				f.name, f.name, strings.Join(f.values, " "))
+		}
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, `	if [[ $COMP_CWORD -eq 1 && $cur != -* ]]; then`)
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(subcommandNames(), " "))
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o default -F _claude_quota claude-quota")
+}
+
+// zshEscaper escapes a flag description for a single-quoted _arguments spec.
+var zshEscaper = strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`)
+
+// writeZshCompletion writes an _arguments spec with flag descriptions.
+func writeZshCompletion(w io.Writer, flags []completionFlag) {
+	fmt.Fprintln(w, "#compdef claude-quota")
+	fmt.Fprintln(w, "_arguments \\")
+	for _, f := range flags {
+		spec := fmt.Sprintf("-%s[%s]", f.name, zshEscaper.Replace(f.usage))
+		switch {
+		case f.isBool:
+		case len(f.values) > 0:
+			spec += fmt.Sprintf(":%s:(%s)", f.name, strings.Join(f.values, " "))
+		default:
+			spec += fmt.Sprintf(":%s:_default", f.name)
+		}
+		fmt.Fprintf(w, "  '%s' \\\n", spec)
+	}
+	fmt.Fprintf(w, "  '1::command:(%s)'\n", strings.Join(subcommandNames(), " "))
+}
+
+// fishEscaper escapes a string for single quotes in fish.
+var fishEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+// writeFishCompletion writes one complete command per flag. Flags use -o,
+// fish's form for single-dash long options.
+func writeFishCompletion(w io.Writer, flags []completionFlag) {
+	fmt.Fprintln(w, "# fish completion for claude-quota")
+	fmt.Fprintf(w, "complete -c claude-quota -n __fish_use_subcommand -f -a '%s'\n", strings.Join(subcommandNames(), " "))
+	for _, f := range flags {
+		line := "complete -c claude-quota -o " + f.name
+		switch {
+		case f.isBool:
+		case len(f.values) > 0:
+			line += fmt.Sprintf(" -x -a '%s'", strings.Join(f.values, " "))
+		default:
+			line += " -r"
+		}
+		line += fmt.Sprintf(" -d '%s'", fishEscaper.Replace(f.usage))
+		fmt.Fprintln(w, line)
+	}
+}
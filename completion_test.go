@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+// testCompletionFlags mirrors a few of main's flags.
+func testCompletionFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("claude-quota", flag.ContinueOnError)
+	fs.String("font-name", "", "icon font name")
+	fs.String("indicator", "", "indicator type")
+	fs.Int("poll-interval", 0, "poll interval in seconds")
+	fs.Bool("verbose", false, "show fetch counters [tooltip]")
+	return fs
+}
+
+func TestGenerateCompletion(t *testing.T) {
+	tests := []struct {
+		shell   string
+		fontArg string // how the shell script names the -font-name flag
+	}{
+		{"bash", "-font-name|--font-name)"},
+		{"zsh", "'-font-name[icon font name]:font-name:("},
+		{"fish", "complete -c claude-quota -o font-name -x -a '"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := generateCompletion(&buf, tt.shell, testCompletionFlags()); err != nil {
+				t.Fatalf("generateCompletion() error: %v", err)
+			}
+			out := buf.String()
+
+			_, rest, found := strings.Cut(out, tt.fontArg)
+			if !found {
+				t.Fatalf("output has no %q:\n%s", tt.fontArg, out)
+			}
+			fontLine, _, _ := strings.Cut(rest, "\n")
+			for _, name := range fontNames {
+				if !strings.Contains(fontLine, name) {
+					t.Errorf("font-name completion %q lacks %q", fontLine, name)
+				}
+			}
+			for _, want := range []string{"poll-interval", "verbose", "status", "bar-proj"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("output lacks %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateCompletion_Zsh_EscapesBrackets(t *testing.T) {
+	var buf bytes.Buffer
+	generateCompletion(&buf, "zsh", testCompletionFlags())
+	if !strings.Contains(buf.String(), `'-verbose[show fetch counters \[tooltip\]]'`) {
+		t.Errorf("zsh output does not escape brackets:\n%s", buf.String())
+	}
+}
+
+func TestGenerateCompletion_UnknownShell(t *testing.T) {
+	if err := generateCompletion(&bytes.Buffer{}, "tcsh", testCompletionFlags()); err == nil {
+		t.Error("generateCompletion(tcsh) should fail")
+	}
+}
@@ -1,55 +1,188 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"image/color"
+	"io"
+	"log/slog"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
 )
 
 // Config holds the widget configuration.
 type Config struct {
-	ClaudeHome          string     `json:"claude_home,omitempty"`
-	PollIntervalSeconds int        `json:"poll_interval_seconds"`
-	FontSize            float64    `json:"font_size"`
-	FontName            string     `json:"font_name"`
-	HaloSize            float64    `json:"halo_size"`
-	IconSize            int        `json:"icon_size"`
-	Indicator           string     `json:"indicator"`
-	ShowText            *bool      `json:"show_text"`
-	ShowAccount         bool       `json:"show_account"`
-	Stats               bool       `json:"stats"`
-	Thresholds          Thresholds `json:"thresholds"`
+	SchemaVersion               int               `json:"schema_version" toml:"schema_version"`
+	ClaudeHome                  string            `json:"claude_home,omitempty" toml:"claude_home"`
+	APIURL                      string            `json:"api_url,omitempty" toml:"api_url"`
+	AnthropicVersion            string            `json:"anthropic_version" toml:"anthropic_version"`
+	PollIntervalSeconds         int               `json:"poll_interval_seconds" toml:"poll_interval_seconds"`
+	PollJitterSeconds           int               `json:"poll_jitter_seconds" toml:"poll_jitter_seconds"`
+	TimeoutSeconds              int               `json:"timeout_seconds" toml:"timeout_seconds"`
+	MinUtilizationForProjection float64           `json:"min_utilization_for_projection" toml:"min_utilization_for_projection"`
+	FontSize                    float64           `json:"font_size" toml:"font_size"`
+	FontName                    string            `json:"font_name" toml:"font_name"`
+	HaloSize                    float64           `json:"halo_size" toml:"halo_size"`
+	IconSize                    int               `json:"icon_size" toml:"icon_size"`
+	Padding                     float64           `json:"padding" toml:"padding"`
+	IndicatorSizeRatio          float64           `json:"indicator_size_ratio" toml:"indicator_size_ratio"`
+	IconOpacity                 float64           `json:"icon_opacity" toml:"icon_opacity"`
+	BarCornerRadius             float64           `json:"bar_corner_radius" toml:"bar_corner_radius"`
+	RingGap                     float64           `json:"ring_gap" toml:"ring_gap"`
+	PrewarmIconCache            bool              `json:"prewarm_icon_cache" toml:"prewarm_icon_cache"`
+	BlinkWhenSaturated          bool              `json:"blink_when_saturated" toml:"blink_when_saturated"`
+	Indicator                   string            `json:"indicator" toml:"indicator"`
+	ColorSource                 string            `json:"color_source" toml:"color_source"`
+	IconWindow                  string            `json:"icon_window" toml:"icon_window"`
+	IconBackgroundColor         string            `json:"icon_background_color" toml:"icon_background_color"`
+	TextPosition                string            `json:"text_position" toml:"text_position"`
+	TimeDisplay                 string            `json:"time_display" toml:"time_display"`
+	MaxSaturationDisplayMinutes int               `json:"max_saturation_display_minutes" toml:"max_saturation_display_minutes"`
+	ModelAliases                map[string]string `json:"model_aliases,omitempty" toml:"model_aliases"`
+	ShowText                    *bool             `json:"show_text" toml:"show_text"`
+	ShowAccount                 bool              `json:"show_account" toml:"show_account"`
+	ShowProjection              bool              `json:"show_projection" toml:"show_projection"`
+	Stats                       bool              `json:"stats" toml:"stats"`
+	StrictJSON                  bool              `json:"strict_json" toml:"strict_json"`
+	TelemetryEnabled            bool              `json:"telemetry_enabled" toml:"telemetry_enabled"`
+	TelemetryURL                string            `json:"telemetry_url,omitempty" toml:"telemetry_url"`
+	MenuOrder                   []string          `json:"menu_order,omitempty" toml:"menu_order"`
+	LogFile                     string            `json:"log_file,omitempty" toml:"log_file"`
+	LogFormat                   string            `json:"log_format,omitempty" toml:"log_format"`
+	TitleFormat                 string            `json:"title_format,omitempty" toml:"title_format"`
+	TooltipFormat               string            `json:"tooltip_format,omitempty" toml:"tooltip_format"`
+	Verbose                     bool              `json:"-" toml:"-"` // set by -verbose only
+	Thresholds                  Thresholds        `json:"thresholds" toml:"thresholds"`
 }
 
 // Thresholds defines warning/critical utilization levels.
 type Thresholds struct {
-	Warning  float64 `json:"warning"`
-	Critical float64 `json:"critical"`
+	Warning  float64 `json:"warning" toml:"warning"`
+	Critical float64 `json:"critical" toml:"critical"`
 }
 
 var configPath string
 
+// sample_config.json is generateSampleConfig's output, kept in the repo as
+// a reference; TestSampleConfig_UpToDate fails when it is stale.
+//go:generate sh -c "go run . -sample-config > sample_config.json"
+
 func init() {
 	dir, err := os.UserConfigDir()
 	if err != nil {
 		dir = "."
 	}
 	configPath = filepath.Join(dir, "claude-quota", "config.json")
+	if toml := filepath.Join(dir, "claude-quota", "config.toml"); fileExists(toml) {
+		configPath = toml
+	}
+}
+
+// fileExists reports whether path exists and is not a directory.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// isTOMLConfig reports whether path should be parsed as TOML rather than JSON.
+func isTOMLConfig(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".toml")
+}
+
+// jsonConfigPath returns configPath with a .json extension, where saveConfig
+// writes. JSON is the canonical format even when a TOML file is loaded.
+func jsonConfigPath() string {
+	if !isTOMLConfig(configPath) {
+		return configPath
+	}
+	return strings.TrimSuffix(configPath, filepath.Ext(configPath)) + ".json"
+}
+
+// unmarshalConfig decodes data into cfg as TOML or JSON depending on path's extension.
+func unmarshalConfig(path string, data []byte, cfg *Config) error {
+	if isTOMLConfig(path) {
+		return toml.Unmarshal(data, cfg)
+	}
+	return json.Unmarshal(data, cfg)
+}
+
+// currentSchemaVersion is the config file schema_version written by this
+// release. Files without schema_version are version 0.
+const currentSchemaVersion = 1
+
+// migrateConfig upgrades cfg, read from a file at schema version from, to
+// currentSchemaVersion, one version at a time. Fields missing from the file
+// already hold their defaults; a step only needs to fix fields whose old
+// value means something else in the new version, for example a zero that
+// used to mean "unset". The file itself is only rewritten by
+// saveMigratedConfig.
+func migrateConfig(cfg *Config, from int) {
+	for v := max(from, 0); v < currentSchemaVersion; v++ {
+		switch v {
+		case 0:
+			// Version 1 introduced schema_version itself. Every field of a
+			// version 0 file keeps its meaning, and fields the file lacks
+			// keep their defaults.
+		}
+	}
+	cfg.SchemaVersion = currentSchemaVersion
+}
+
+// saveMigratedConfig writes back a config file that was read at an older
+// schema_version, so its migration runs once. Invalid values, which
+// loadConfig already reported, are saved as their defaults, as they are used.
+// TOML files are left alone: saveConfig only writes JSON. Called when the
+// tray starts, never by the read-only commands.
+func saveMigratedConfig(l configLoad) {
+	if l.FileVersion >= currentSchemaVersion || isTOMLConfig(configPath) {
+		return
+	}
+	file := l.File
+	_, err := file.Validate()
+	fixConfig(&file, err)
+	if err := saveConfig(file); err != nil {
+		slog.Error("Failed to save migrated config", "path", configPath, "error", err)
+		return
+	}
+	slog.Info("Migrated config", "path", configPath, "from", l.FileVersion, "to", currentSchemaVersion)
 }
 
 // defaultConfig returns a Config with default values.
 func defaultConfig() Config {
 	showText := true
 	return Config{
-		PollIntervalSeconds: 300,
-		FontSize:            34,
-		FontName:            "bold",
-		HaloSize:            2,
-		IconSize:            64,
-		Indicator:           "pie",
-		ShowText:            &showText,
+		SchemaVersion:               currentSchemaVersion,
+		AnthropicVersion:            defaultAnthropicVersion,
+		PollIntervalSeconds:         300,
+		PollJitterSeconds:           10,
+		TimeoutSeconds:              int(FetchTimeout / time.Second),
+		MinUtilizationForProjection: defaultMinProjectionUtilization,
+		FontSize:                    34,
+		FontName:                    "bold",
+		HaloSize:                    2,
+		IconSize:                    64,
+		Padding:                     defaultIconPadding,
+		IndicatorSizeRatio:          maxShapeScale,
+		IconOpacity:                 1,
+		RingGap:                     defaultRingGap,
+		Indicator:                   "pie",
+		ColorSource:                 "current",
+		IconWindow:                  "5h",
+		TextPosition:                "center",
+		TimeDisplay:                 "both",
+		MaxSaturationDisplayMinutes: 60,
+		ShowProjection:              true,
+		ShowText:                    &showText,
 		Thresholds: Thresholds{
 			Warning:  60,
 			Critical: 85,
@@ -65,102 +198,901 @@ func configShowText(cfg Config) bool {
 	return *cfg.ShowText
 }
 
-// loadConfig loads config from disk, creating a default if it doesn't exist.
-// Missing fields keep their defaults via json.Unmarshal into a pre-populated struct.
+// expandPath expands $VAR and ${VAR} references in s and cleans the result,
+// e.g. "$HOME/work" → "/home/user/work". Empty stays empty.
+func expandPath(s string) string {
+	if s == "" {
+		return ""
+	}
+	return filepath.Clean(os.ExpandEnv(s))
+}
+
+// configAPIURL returns the usage endpoint, defaulting to defaultUsageURL.
+func configAPIURL(cfg Config) string {
+	if cfg.APIURL == "" {
+		return defaultUsageURL
+	}
+	return cfg.APIURL
+}
+
+// validTimeoutSeconds reports whether s is an accepted timeout_seconds value.
+func validTimeoutSeconds(s int) bool {
+	return s > 0 && s <= maxTimeoutSeconds
+}
+
+// configTimeout returns the HTTP timeout, defaulting to FetchTimeout.
+func configTimeout(cfg Config) time.Duration {
+	if !validTimeoutSeconds(cfg.TimeoutSeconds) {
+		return FetchTimeout
+	}
+	return time.Duration(cfg.TimeoutSeconds) * time.Second
+}
+
+// ValidAPIURL reports whether s is an absolute http(s) URL.
+func ValidAPIURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// menuItemNames lists the menu_order identifiers, in the default menu order.
+var menuItemNames = []string{
+	"5h", "5h-projection", "5h-saturation",
+	"7d", "7d-projection", "7d-saturation", "7d-sonnet",
+	"separator", "updated", "refresh", "about", "quit",
+}
+
+// ValidMenuItemName reports whether name is a known menu_order identifier.
+func ValidMenuItemName(name string) bool {
+	return slices.Contains(menuItemNames, name)
+}
+
+// resolveMenuOrder returns the menu layout for order: the default layout when
+// empty, otherwise order with unknown and repeated items dropped (separators
+// may repeat). "quit" is appended when missing so the app can always be
+// exited from the menu.
+func resolveMenuOrder(order []string) []string {
+	if len(order) == 0 {
+		return slices.Clone(menuItemNames)
+	}
+	var resolved []string
+	for _, name := range order {
+		if !ValidMenuItemName(name) || (name != "separator" && slices.Contains(resolved, name)) {
+			continue
+		}
+		resolved = append(resolved, name)
+	}
+	if !slices.Contains(resolved, "quit") {
+		resolved = append(resolved, "quit")
+	}
+	return resolved
+}
+
+// bucketLabels maps API usage bucket names to their default display labels.
+var bucketLabels = map[string]string{
+	"five_hour":        "5h",
+	"seven_day":        "7d",
+	"seven_day_sonnet": "Sonnet 7d",
+}
+
+// bucketLabel returns the display label for an API usage bucket: its
+// model_aliases entry if set, otherwise the default label.
+func bucketLabel(aliases map[string]string, bucket string) string {
+	if label := aliases[bucket]; label != "" {
+		return label
+	}
+	return bucketLabels[bucket]
+}
+
+// validMinProjection reports whether f is a usable min_utilization_for_projection.
+func validMinProjection(f float64) bool {
+	return f >= 0 && f <= 100
+}
+
+// iconSizeMultiple is the granularity of icon_size. Other sizes put the center
+// of pie/arc indicators and bar fills between pixels.
+const iconSizeMultiple = 8
+
+// roundUpToMultiple rounds value up to the nearest multiple of multiple (> 0).
+func roundUpToMultiple(value, multiple int) int {
+	return (value + multiple - 1) / multiple * multiple
+}
+
+// alignIconSize rounds cfg.IconSize up to a multiple of iconSizeMultiple,
+// logging a warning when it changes.
+func alignIconSize(cfg *Config) {
+	if aligned := roundUpToMultiple(cfg.IconSize, iconSizeMultiple); aligned != cfg.IconSize {
+		slog.Warn("icon_size is not a multiple of the alignment, rounding up", "value", cfg.IconSize, "multiple", iconSizeMultiple, "using", aligned)
+		cfg.IconSize = aligned
+	}
+}
+
+// configIconBackground returns the parsed icon_background_color, transparent
+// when unset or invalid.
+func configIconBackground(cfg Config) color.RGBA {
+	c, _, _ := parseOptionalColor(cfg.IconBackgroundColor)
+	return c
+}
+
+// ConfigError is a hard validation failure of one config field, which
+// loadConfig replaces with its default.
+type ConfigError struct {
+	Field string // JSON name, e.g. "icon_size" or "thresholds.warning"
+	Msg   string
+
+	reset func(cfg *Config, defaults Config)
+}
+
+func (e *ConfigError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// configErrors returns the ConfigErrors joined in an error from Validate.
+func configErrors(err error) []*ConfigError {
+	if err == nil {
+		return nil
+	}
+	var errs []*ConfigError
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, configErrors(e)...)
+		}
+	} else if e, ok := err.(*ConfigError); ok {
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// configCheck validates one config field.
+type configCheck struct {
+	field string
+	// check returns why the field is invalid, or "" when it is valid.
+	check func(c Config) string
+	reset func(c *Config, d Config)
+}
+
+// oneOf returns the check message for a value not in names. Empty values
+// are accepted: loadConfig fills them from the defaults.
+func oneOf(value string, valid func(string) bool, names []string) string {
+	if value == "" || valid(value) {
+		return ""
+	}
+	return fmt.Sprintf("unknown value %q, want one of: %s", value, strings.Join(names, ", "))
+}
+
+// configChecks lists the hard validation rules in config file order.
+var configChecks = []configCheck{
+	{"icon_size", func(c Config) string {
+		if c.IconSize <= 0 {
+			return fmt.Sprintf("must be greater than 0, got %d", c.IconSize)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.IconSize = d.IconSize }},
+	{"font_size", func(c Config) string {
+		if c.FontSize <= 0 {
+			return fmt.Sprintf("must be greater than 0, got %g", c.FontSize)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.FontSize = d.FontSize }},
+	{"poll_interval_seconds", func(c Config) string {
+		if c.PollIntervalSeconds <= 0 {
+			return fmt.Sprintf("must be greater than 0, got %d", c.PollIntervalSeconds)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.PollIntervalSeconds = d.PollIntervalSeconds }},
+	{"poll_jitter_seconds", func(c Config) string {
+		if c.PollJitterSeconds < 0 {
+			return fmt.Sprintf("must not be negative, got %d", c.PollJitterSeconds)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.PollJitterSeconds = d.PollJitterSeconds }},
+	{"timeout_seconds", func(c Config) string {
+		if !validTimeoutSeconds(c.TimeoutSeconds) {
+			return fmt.Sprintf("must be 1-%d, got %d", maxTimeoutSeconds, c.TimeoutSeconds)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.TimeoutSeconds = d.TimeoutSeconds }},
+	{"min_utilization_for_projection", func(c Config) string {
+		if !validMinProjection(c.MinUtilizationForProjection) {
+			return fmt.Sprintf("must be 0-100, got %g", c.MinUtilizationForProjection)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.MinUtilizationForProjection = d.MinUtilizationForProjection }},
+	{"halo_size", func(c Config) string {
+		if c.HaloSize < 0 {
+			return fmt.Sprintf("must not be negative, got %g", c.HaloSize)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.HaloSize = d.HaloSize }},
+	{"padding", func(c Config) string {
+		if c.Padding < 0 || c.Padding > maxIconPadding {
+			return fmt.Sprintf("must be 0-%g, got %g", maxIconPadding, c.Padding)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.Padding = d.Padding }},
+	{"indicator_size_ratio", func(c Config) string {
+		if c.IndicatorSizeRatio < minShapeScale || c.IndicatorSizeRatio > maxShapeScale {
+			return fmt.Sprintf("must be %g-%g, got %g", minShapeScale, maxShapeScale, c.IndicatorSizeRatio)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.IndicatorSizeRatio = d.IndicatorSizeRatio }},
+	{"icon_opacity", func(c Config) string {
+		if c.IconOpacity < 0 || c.IconOpacity > 1 {
+			return fmt.Sprintf("must be 0-1, got %g", c.IconOpacity)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.IconOpacity = d.IconOpacity }},
+	{"bar_corner_radius", func(c Config) string {
+		if c.BarCornerRadius < 0 || c.BarCornerRadius > maxBarCornerRadius {
+			return fmt.Sprintf("must be 0-%g, got %g", maxBarCornerRadius, c.BarCornerRadius)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.BarCornerRadius = d.BarCornerRadius }},
+	{"ring_gap", func(c Config) string {
+		if c.RingGap < 0 || c.RingGap > maxRingGap {
+			return fmt.Sprintf("must be 0-%g, got %g", maxRingGap, c.RingGap)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.RingGap = d.RingGap }},
+	{"font_name", func(c Config) string {
+		return oneOf(c.FontName, ValidFontName, fontNames)
+	}, func(c *Config, d Config) { c.FontName = d.FontName }},
+	{"indicator", func(c Config) string {
+		return oneOf(c.Indicator, ValidIndicatorName, indicatorNames)
+	}, func(c *Config, d Config) { c.Indicator = d.Indicator }},
+	{"color_source", func(c Config) string {
+		return oneOf(c.ColorSource, ValidColorSource, colorSources)
+	}, func(c *Config, d Config) { c.ColorSource = d.ColorSource }},
+	{"icon_background_color", func(c Config) string {
+		if !ValidColor(c.IconBackgroundColor) {
+			return fmt.Sprintf("invalid color %q, want #rrggbb or #rrggbbaa", c.IconBackgroundColor)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.IconBackgroundColor = d.IconBackgroundColor }},
+	{"icon_window", func(c Config) string {
+		return oneOf(c.IconWindow, ValidIconWindow, iconWindows)
+	}, func(c *Config, d Config) { c.IconWindow = d.IconWindow }},
+	{"text_position", func(c Config) string {
+		return oneOf(c.TextPosition, ValidTextPosition, textPositions)
+	}, func(c *Config, d Config) { c.TextPosition = d.TextPosition }},
+	{"time_display", func(c Config) string {
+		return oneOf(c.TimeDisplay, ValidTimeDisplay, timeDisplays)
+	}, func(c *Config, d Config) { c.TimeDisplay = d.TimeDisplay }},
+	{"max_saturation_display_minutes", func(c Config) string {
+		if c.MaxSaturationDisplayMinutes < 0 {
+			return fmt.Sprintf("must not be negative, got %d", c.MaxSaturationDisplayMinutes)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.MaxSaturationDisplayMinutes = d.MaxSaturationDisplayMinutes }},
+	{"api_url", func(c Config) string {
+		if c.APIURL != "" && !ValidAPIURL(c.APIURL) {
+			return fmt.Sprintf("invalid URL %q, want http or https", c.APIURL)
+		}
+		return ""
+	}, func(c *Config, _ Config) { c.APIURL = "" }},
+	{"telemetry_url", func(c Config) string {
+		if c.TelemetryURL != "" && !ValidAPIURL(c.TelemetryURL) {
+			return fmt.Sprintf("invalid URL %q, want http or https", c.TelemetryURL)
+		}
+		return ""
+	}, func(c *Config, _ Config) { c.TelemetryURL = "" }},
+	{"log_format", func(c Config) string {
+		return oneOf(c.LogFormat, ValidLogFormat, logFormats)
+	}, func(c *Config, _ Config) { c.LogFormat = "" }},
+	{"thresholds.warning", func(c Config) string {
+		if c.Thresholds.Warning <= 0 || c.Thresholds.Warning > 100 {
+			return fmt.Sprintf("must be 1-100, got %g", c.Thresholds.Warning)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.Thresholds.Warning = d.Thresholds.Warning }},
+	{"thresholds.critical", func(c Config) string {
+		if c.Thresholds.Critical <= 0 || c.Thresholds.Critical > 100 {
+			return fmt.Sprintf("must be 1-100, got %g", c.Thresholds.Critical)
+		}
+		return ""
+	}, func(c *Config, d Config) { c.Thresholds.Critical = d.Thresholds.Critical }},
+}
+
+// Validate checks c without changing it. err joins a *ConfigError per
+// invalid field; warnings describe values that work but are likely
+// mistakes, such as thresholds.warning >= thresholds.critical.
+func (c Config) Validate() (warnings []string, err error) {
+	var errs []error
+	invalid := make(map[string]bool)
+	for _, check := range configChecks {
+		if msg := check.check(c); msg != "" {
+			errs = append(errs, &ConfigError{Field: check.field, Msg: msg, reset: check.reset})
+			invalid[check.field] = true
+		}
+	}
+
+	for _, bucket := range slices.Sorted(maps.Keys(c.ModelAliases)) {
+		if _, ok := bucketLabels[bucket]; !ok {
+			warnings = append(warnings, fmt.Sprintf("model_aliases: unknown bucket %q is ignored", bucket))
+		}
+	}
+	for _, name := range c.MenuOrder {
+		if !ValidMenuItemName(name) {
+			warnings = append(warnings, fmt.Sprintf("menu_order: unknown item %q is ignored", name))
+		}
+	}
+	if !invalid["thresholds.warning"] && !invalid["thresholds.critical"] && c.Thresholds.Warning >= c.Thresholds.Critical {
+		warnings = append(warnings, fmt.Sprintf("thresholds.warning (%g) >= thresholds.critical (%g), they are swapped",
+			c.Thresholds.Warning, c.Thresholds.Critical))
+	}
+	if c.SchemaVersion > currentSchemaVersion {
+		warnings = append(warnings, fmt.Sprintf("schema_version %d is newer than this release supports (%d), unknown settings are ignored",
+			c.SchemaVersion, currentSchemaVersion))
+	}
+	if c.TelemetryEnabled && c.TelemetryURL == "" {
+		warnings = append(warnings, "telemetry_enabled is set but telemetry_url is empty, nothing is sent")
+	}
+	return warnings, errors.Join(errs...)
+}
+
+// normalizeConfig fills empty fields from defaults, drops ignored entries
+// and orders the thresholds, after the checks in Validate have passed.
+func normalizeConfig(cfg *Config, defaults Config) {
+	alignIconSize(cfg)
+	for _, f := range []struct {
+		value *string
+		def   string
+	}{
+		{&cfg.FontName, defaults.FontName},
+		{&cfg.Indicator, defaults.Indicator},
+		{&cfg.ColorSource, defaults.ColorSource},
+		{&cfg.IconWindow, defaults.IconWindow},
+		{&cfg.TextPosition, defaults.TextPosition},
+		{&cfg.TimeDisplay, defaults.TimeDisplay},
+	} {
+		if *f.value == "" {
+			*f.value = f.def
+		}
+	}
+	for bucket := range cfg.ModelAliases {
+		if _, ok := bucketLabels[bucket]; !ok {
+			delete(cfg.ModelAliases, bucket)
+		}
+	}
+	if cfg.ShowText == nil {
+		cfg.ShowText = defaults.ShowText
+	}
+	if cfg.Thresholds.Warning >= cfg.Thresholds.Critical {
+		cfg.Thresholds.Warning, cfg.Thresholds.Critical = cfg.Thresholds.Critical, cfg.Thresholds.Warning
+	}
+}
+
+// overrideJSONEnv names the environment variable holding a JSON config blob
+// applied over the config file, e.g. for containers without a mounted file.
+const overrideJSONEnv = "CLAUDE_QUOTA_OVERRIDE_JSON"
+
+// configLoad is what loadConfigDetails read and resolved.
+type configLoad struct {
+	Config      Config // the validated config, as returned by loadConfig
+	Raw         Config // File with CLAUDE_QUOTA_OVERRIDE_JSON applied, before validation
+	File        Config // the config file alone, migrated to currentSchemaVersion
+	FileVersion int    // schema_version the file had before migration
+}
+
+// loadConfig loads config from disk, creating a default if it doesn't exist,
+// applies CLAUDE_QUOTA_OVERRIDE_JSON on top, then validates the result.
 func loadConfig() Config {
+	return loadConfigDetails().Config
+}
+
+// loadConfigDetails is loadConfig, also returning the config as read, before
+// invalid values were reset, for commands that report them.
+func loadConfigDetails() configLoad {
+	file, fileVersion := readConfigFile()
+	cfg := file
+	if v := os.Getenv(overrideJSONEnv); v != "" {
+		if err := applyJSONOverride(&cfg, v); err != nil {
+			slog.Error("Ignoring invalid environment variable", "name", overrideJSONEnv, "error", err)
+		}
+	}
+
+	raw := cfg
+	warnings, err := cfg.Validate()
+	for _, w := range warnings {
+		slog.Warn("Config warning", "warning", w)
+	}
+	for _, e := range configErrors(err) {
+		slog.Warn("Invalid config value, using default", "field", e.Field, "error", e.Msg)
+	}
+	fixConfig(&cfg, err)
+
+	return configLoad{Config: cfg, Raw: raw, File: file, FileVersion: fileVersion}
+}
+
+// fixConfig resets the fields of cfg that failed Validate with err to their
+// defaults, then normalizes cfg.
+func fixConfig(cfg *Config, err error) {
+	defaults := defaultConfig()
+	for _, e := range configErrors(err) {
+		e.reset(cfg, defaults)
+	}
+	normalizeConfig(cfg, defaults)
+}
+
+// readConfigFile reads the config file, creating a default one if it doesn't
+// exist, and returns it migrated along with the schema_version it was read
+// at. Missing fields keep their defaults via json.Unmarshal into a
+// pre-populated struct. The result is not validated.
+func readConfigFile() (Config, int) {
 	cfg := defaultConfig()
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(err) && !isTOMLConfig(configPath) {
 			if writeErr := saveConfig(cfg); writeErr != nil {
-				log.Printf("Failed to write default config: %v", writeErr)
+				slog.Error("Failed to write default config", "path", configPath, "error", writeErr)
 			} else {
-				log.Printf("Created default config at %s", configPath)
+				slog.Info("Created default config", "path", configPath)
 			}
-			return cfg
+			return cfg, currentSchemaVersion
 		}
-		log.Printf("Failed to read config %s: %v", configPath, err)
-		return cfg
+		slog.Error("Failed to read config", "path", configPath, "error", err)
+		return cfg, currentSchemaVersion
 	}
 
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		log.Printf("Failed to parse config %s: %v", configPath, err)
-		return defaultConfig()
+	// Unlike other fields, a missing schema_version must not take the
+	// default: it marks a version 0 file.
+	cfg.SchemaVersion = 0
+	if err := unmarshalConfig(configPath, data, &cfg); err != nil {
+		slog.Error("Failed to parse config", "path", configPath, "error", err)
+		return defaultConfig(), currentSchemaVersion
 	}
-
-	defaults := defaultConfig()
-	if cfg.IconSize <= 0 {
-		log.Printf("Invalid icon_size %d in config, using default %d", cfg.IconSize, defaults.IconSize)
-		cfg.IconSize = defaults.IconSize
+	version := cfg.SchemaVersion
+	if version < currentSchemaVersion {
+		migrateConfig(&cfg, version)
 	}
-	if cfg.FontSize <= 0 {
-		log.Printf("Invalid font_size %v in config, using default %v", cfg.FontSize, defaults.FontSize)
-		cfg.FontSize = defaults.FontSize
+	return cfg, version
+}
+
+// applyJSONOverride unmarshals the config JSON in jsonStr over cfg: fields
+// it sets replace those of cfg, the others are kept. On error cfg is left
+// unchanged.
+func applyJSONOverride(cfg *Config, jsonStr string) error {
+	// Copy the reference fields, which json.Unmarshal would write through.
+	next := *cfg
+	next.ModelAliases = maps.Clone(cfg.ModelAliases)
+	next.MenuOrder = slices.Clone(cfg.MenuOrder)
+	if cfg.ShowText != nil {
+		showText := *cfg.ShowText
+		next.ShowText = &showText
 	}
-	if cfg.PollIntervalSeconds <= 0 {
-		log.Printf("Invalid poll_interval_seconds %d in config, using default %d", cfg.PollIntervalSeconds, defaults.PollIntervalSeconds)
-		cfg.PollIntervalSeconds = defaults.PollIntervalSeconds
+	if err := json.Unmarshal([]byte(jsonStr), &next); err != nil {
+		return err
 	}
-	if cfg.HaloSize < 0 {
-		log.Printf("Invalid halo_size %v in config, using default %v", cfg.HaloSize, defaults.HaloSize)
-		cfg.HaloSize = defaults.HaloSize
+	*cfg = next
+	return nil
+}
+
+// generateConfigSchema returns a JSON Schema (draft-07) document describing
+// config.json, for editor validation and autocomplete. Defaults come from
+// defaultConfig() and constraints mirror the checks in Validate.
+func generateConfigSchema() []byte {
+	d := defaultConfig()
+	schema := map[string]any{
+		"$schema":     "http://json-schema.org/draft-07/schema#",
+		"title":       "claude-quota configuration",
+		"description": "Configuration file for the claude-quota systray widget.",
+		"type":        "object",
+		"properties": map[string]any{
+			"schema_version": map[string]any{
+				"type":        "integer",
+				"description": "Config file format version, set when the file is written. Older files are migrated on load.",
+				"default":     currentSchemaVersion,
+				"minimum":     0,
+			},
+			"claude_home": map[string]any{
+				"type":        "string",
+				"description": "Home directory containing .claude/.credentials.json (default: user home). $VAR references are expanded.",
+			},
+			"api_url": map[string]any{
+				"type":        "string",
+				"description": "Usage API endpoint, e.g. a local proxy.",
+				"default":     defaultUsageURL,
+				"format":      "uri",
+			},
+			"log_file": map[string]any{
+				"type":        "string",
+				"description": "Also append logs to this file, rotated at 10 MB with 3 backups.",
+			},
+			"log_format": map[string]any{
+				"type":        "string",
+				"description": "Structured log format. Empty keeps plain log lines.",
+				"enum":        append([]string{""}, logFormats...),
+			},
+			"anthropic_version": map[string]any{
+				"type":        "string",
+				"description": "anthropic-version header sent with API requests. Empty omits the header.",
+				"default":     defaultAnthropicVersion,
+			},
+			"poll_interval_seconds": map[string]any{
+				"type":        "integer",
+				"description": "Interval between quota fetches, in seconds.",
+				"default":     d.PollIntervalSeconds,
+				"minimum":     1,
+			},
+			"poll_jitter_seconds": map[string]any{
+				"type":        "integer",
+				"description": "Random delay of up to this many seconds added to each poll interval. 0 disables it.",
+				"default":     d.PollJitterSeconds,
+				"minimum":     0,
+			},
+			"timeout_seconds": map[string]any{
+				"type":        "integer",
+				"description": "HTTP timeout for API requests, in seconds.",
+				"default":     d.TimeoutSeconds,
+				"minimum":     1,
+				"maximum":     maxTimeoutSeconds,
+			},
+			"min_utilization_for_projection": map[string]any{
+				"type":        "number",
+				"description": "Utilization in % below which no projection is shown, as it is too noisy.",
+				"default":     d.MinUtilizationForProjection,
+				"minimum":     0,
+				"maximum":     100,
+			},
+			"font_size": map[string]any{
+				"type":             "number",
+				"description":      "Icon font size, relative to a 64px icon.",
+				"default":          d.FontSize,
+				"exclusiveMinimum": 0,
+			},
+			"font_name": map[string]any{
+				"type":        "string",
+				"description": "Icon font.",
+				"default":     d.FontName,
+				"enum":        fontNames,
+			},
+			"halo_size": map[string]any{
+				"type":        "number",
+				"description": "Text halo/outline size in pixels, relative to a 64px icon. 0 disables it.",
+				"default":     d.HaloSize,
+				"minimum":     0,
+			},
+			"icon_size": map[string]any{
+				"type":        "integer",
+				"description": "Icon size in pixels.",
+				"default":     d.IconSize,
+				"minimum":     1,
+			},
+			"padding": map[string]any{
+				"type":        "number",
+				"description": "Margin between the icon edge and the indicator outline, relative to a 64px icon.",
+				"default":     d.Padding,
+				"minimum":     0,
+				"maximum":     maxIconPadding,
+			},
+			"indicator_size_ratio": map[string]any{
+				"type":        "number",
+				"description": "Fraction of the available radius used by the pie and arc indicators.",
+				"default":     d.IndicatorSizeRatio,
+				"minimum":     minShapeScale,
+				"maximum":     maxShapeScale,
+			},
+			"icon_opacity": map[string]any{
+				"type":        "number",
+				"description": "Alpha multiplier for the whole icon, to tone down a bright icon.",
+				"default":     d.IconOpacity,
+				"minimum":     0,
+				"maximum":     1,
+			},
+			"bar_corner_radius": map[string]any{
+				"type":        "number",
+				"description": "Corner radius of the bar indicator in pixels, relative to a 64px icon. 0 draws square corners.",
+				"default":     d.BarCornerRadius,
+				"minimum":     0,
+				"maximum":     maxBarCornerRadius,
+			},
+			"ring_gap": map[string]any{
+				"type":        "number",
+				"description": "Gap between the ring-dual rings in pixels, relative to a 64px icon.",
+				"default":     d.RingGap,
+				"minimum":     0,
+				"maximum":     maxRingGap,
+			},
+			"prewarm_icon_cache": map[string]any{
+				"type":        "boolean",
+				"description": "Render icons for 0-100% in steps of 10 and the error icon at startup.",
+				"default":     d.PrewarmIconCache,
+			},
+			"blink_when_saturated": map[string]any{
+				"type":        "boolean",
+				"description": "Blink the tray icon once the projected 5h saturation time has passed.",
+				"default":     d.BlinkWhenSaturated,
+			},
+			"indicator": map[string]any{
+				"type":        "string",
+				"description": "Indicator style.",
+				"default":     d.Indicator,
+				"enum":        indicatorNames,
+			},
+			"color_source": map[string]any{
+				"type":        "string",
+				"description": "Utilization value that drives the icon color.",
+				"default":     d.ColorSource,
+				"enum":        colorSources,
+			},
+			"icon_background_color": map[string]any{
+				"type":        "string",
+				"description": "Icon background as #rrggbb or #rrggbbaa. Empty keeps it transparent.",
+				"default":     d.IconBackgroundColor,
+				"pattern":     "^(#([0-9a-fA-F]{6}|[0-9a-fA-F]{8}))?$",
+			},
+			"text_position": map[string]any{
+				"type":        "string",
+				"description": "Vertical position of the percentage text on the icon.",
+				"default":     d.TextPosition,
+				"enum":        textPositions,
+			},
+			"icon_window": map[string]any{
+				"type":        "string",
+				"description": "Quota window shown by the icon.",
+				"default":     d.IconWindow,
+				"enum":        iconWindows,
+			},
+			"time_display": map[string]any{
+				"type":        "string",
+				"description": "How reset times are shown: time left, local clock time, or both.",
+				"default":     d.TimeDisplay,
+				"enum":        timeDisplays,
+			},
+			"max_saturation_display_minutes": map[string]any{
+				"type":        "integer",
+				"description": "Hide the 5h saturation line while saturation is further away than this many minutes. 0 always shows it.",
+				"default":     d.MaxSaturationDisplayMinutes,
+				"minimum":     0,
+			},
+			"title_format": map[string]any{
+				"type":        "string",
+				"description": "Go template for the text shown next to the icon, e.g. \"{{.FiveHour}}%\". Empty shows no text.",
+				"default":     d.TitleFormat,
+			},
+			"tooltip_format": map[string]any{
+				"type":        "string",
+				"description": "Go template replacing the tooltip text, with the title_format fields, reset times and helpers such as {{FiveHourBar 10}}. Empty keeps the built-in tooltip.",
+				"default":     d.TooltipFormat,
+			},
+			"show_text": map[string]any{
+				"type":        "boolean",
+				"description": "Show the percentage text on the icon.",
+				"default":     *d.ShowText,
+			},
+			"show_account": map[string]any{
+				"type":        "boolean",
+				"description": "Show account email and organization in the menu.",
+				"default":     d.ShowAccount,
+			},
+			"show_projection": map[string]any{
+				"type":        "boolean",
+				"description": "Show the projected utilization lines in the menu and tooltip. Saturation lines are not affected.",
+				"default":     d.ShowProjection,
+			},
+			"stats": map[string]any{
+				"type":        "boolean",
+				"description": "Record quota snapshots to a local SQLite database.",
+				"default":     d.Stats,
+			},
+			"strict_json": map[string]any{
+				"type":        "boolean",
+				"description": "Fail fetches when the API response has fields this version does not know, instead of logging a warning.",
+				"default":     d.StrictJSON,
+			},
+			"telemetry_enabled": map[string]any{
+				"type":        "boolean",
+				"description": "Send anonymous usage statistics (version, OS, architecture, indicator, poll interval) to telemetry_url after each fetch.",
+				"default":     d.TelemetryEnabled,
+			},
+			"telemetry_url": map[string]any{
+				"type":        "string",
+				"description": "Endpoint receiving telemetry reports as JSON POSTs. Empty disables telemetry.",
+				"format":      "uri",
+			},
+			"model_aliases": map[string]any{
+				"type":                 "object",
+				"description":          "Display labels for the quota windows, keyed by API bucket name.",
+				"propertyNames":        map[string]any{"enum": slices.Sorted(maps.Keys(bucketLabels))},
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+			"menu_order": map[string]any{
+				"type":        "array",
+				"description": "Order of the tray menu items; items left out are hidden. Defaults to the order of the enum.",
+				"items": map[string]any{
+					"type": "string",
+					"enum": menuItemNames,
+				},
+			},
+			"thresholds": map[string]any{
+				"type":        "object",
+				"description": "Utilization levels (%) at which the icon turns yellow and red.",
+				"properties": map[string]any{
+					"warning": map[string]any{
+						"type":             "number",
+						"default":          d.Thresholds.Warning,
+						"exclusiveMinimum": 0,
+						"maximum":          100,
+					},
+					"critical": map[string]any{
+						"type":             "number",
+						"default":          d.Thresholds.Critical,
+						"exclusiveMinimum": 0,
+						"maximum":          100,
+					},
+				},
+			},
+		},
 	}
-	if cfg.FontName == "" || !ValidFontName(cfg.FontName) {
-		if cfg.FontName != "" {
-			log.Printf("Unknown font_name %q in config, using default %q", cfg.FontName, defaults.FontName)
+	// Marshaling only maps, strings, numbers, and bools cannot fail.
+	data, _ := json.MarshalIndent(schema, "", "  ")
+	return append(data, '\n')
+}
+
+// resetConfig deletes the config file and recreates it with defaults, in
+// the same format: a TOML config is rewritten as TOML. Unless assumeYes is
+// set, confirmation is read from in; anything other than "y" or "yes" aborts
+// without touching the file.
+func resetConfig(in io.Reader, out io.Writer, assumeYes bool) error {
+	if !assumeYes {
+		fmt.Fprintf(out, "Reset config at %s? [y/N]: ", configPath)
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		default:
+			fmt.Fprintln(out, "Aborted.")
+			return nil
 		}
-		cfg.FontName = defaults.FontName
 	}
-	if cfg.Indicator == "" || !ValidIndicatorName(cfg.Indicator) {
-		if cfg.Indicator != "" {
-			log.Printf("Unknown indicator %q in config, using default %q", cfg.Indicator, defaults.Indicator)
+
+	if isTOMLConfig(configPath) {
+		data, err := toml.Marshal(defaultConfig())
+		if err != nil {
+			return fmt.Errorf("marshal config: %w", err)
 		}
-		cfg.Indicator = defaults.Indicator
+		if err := writeFileSecure(configPath, data); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Config reset to defaults: %s\n", configPath)
+		return nil
 	}
-	if cfg.ShowText == nil {
-		cfg.ShowText = defaults.ShowText
+
+	if err := os.Remove(configPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove config: %w", err)
 	}
-	if cfg.Thresholds.Warning <= 0 || cfg.Thresholds.Warning > 100 {
-		log.Printf("Invalid thresholds.warning %v in config, using default %v", cfg.Thresholds.Warning, defaults.Thresholds.Warning)
-		cfg.Thresholds.Warning = defaults.Thresholds.Warning
+	if err := saveConfig(defaultConfig()); err != nil {
+		return err
 	}
-	if cfg.Thresholds.Critical <= 0 || cfg.Thresholds.Critical > 100 {
-		log.Printf("Invalid thresholds.critical %v in config, using default %v", cfg.Thresholds.Critical, defaults.Thresholds.Critical)
-		cfg.Thresholds.Critical = defaults.Thresholds.Critical
+	fmt.Fprintf(out, "Config reset to defaults: %s\n", configPath)
+	return nil
+}
+
+// FieldDiff is a config field whose value differs between two configs.
+// Field is the config key (dotted for nested keys); Old and New are the
+// JSON-encoded values.
+type FieldDiff struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// String formats the diff as "key: old → new".
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: %s \u2192 %s", d.Field, d.Old, d.New)
+}
+
+// configDiff returns the fields of b that differ from a, in struct order.
+// Fields not stored in the config file (json:"-") are ignored.
+func configDiff(a, b Config) []FieldDiff {
+	return diffStruct("", reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// diffStruct compares two values of the same struct type field by field,
+// recursing into nested structs with prefix "parent.".
+func diffStruct(prefix string, a, b reflect.Value) []FieldDiff {
+	var diffs []FieldDiff
+	t := a.Type()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "-" || name == "" {
+			continue
+		}
+		name = prefix + name
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Kind() == reflect.Struct {
+			diffs = append(diffs, diffStruct(name+".", fa, fb)...)
+			continue
+		}
+		if reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			continue
+		}
+		diffs = append(diffs, FieldDiff{Field: name, Old: diffValue(fa), New: diffValue(fb)})
 	}
-	if cfg.Thresholds.Warning >= cfg.Thresholds.Critical {
-		log.Printf("Config thresholds.warning (%.0f) >= thresholds.critical (%.0f), swapping", cfg.Thresholds.Warning, cfg.Thresholds.Critical)
-		cfg.Thresholds.Warning, cfg.Thresholds.Critical = cfg.Thresholds.Critical, cfg.Thresholds.Warning
+	return diffs
+}
+
+// diffValue JSON-encodes v for display, e.g. 300, "pie" or ["5h","7d"].
+func diffValue(v reflect.Value) string {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(data)
+}
+
+// printConfigDiff writes the validation warnings and errors of raw, the
+// config as read, then the fields of the resolved cfg that differ from the
+// defaults, one per line, or a note when there are none.
+func printConfigDiff(w io.Writer, cfg, raw Config) {
+	fmt.Fprintf(w, "Config: %s\n", configPath)
+	printConfigValidation(w, raw)
+	diffs := configDiff(defaultConfig(), cfg)
+	if len(diffs) == 0 {
+		fmt.Fprintln(w, "No differences from defaults.")
+		return
+	}
+	for _, d := range diffs {
+		fmt.Fprintln(w, d)
 	}
+}
 
-	return cfg
+// printConfigValidation writes the warnings and errors Validate reports for
+// cfg, one per line, and reports whether cfg is valid.
+func printConfigValidation(w io.Writer, cfg Config) bool {
+	warnings, err := cfg.Validate()
+	for _, msg := range warnings {
+		fmt.Fprintf(w, "Config warning: %s\n", msg)
+	}
+	for _, e := range configErrors(err) {
+		fmt.Fprintf(w, "Config error: %v\n", e)
+	}
+	return err == nil
 }
 
-// saveConfig writes config to disk with restrictive permissions (0600).
+// saveConfig writes config to disk with restrictive permissions (0600),
+// stamped with currentSchemaVersion. Configs that fail Validate are not written.
 func saveConfig(cfg Config) error {
+	cfg.SchemaVersion = currentSchemaVersion
+	if _, err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
-	return writeFileSecure(configPath, data)
+	return writeFileSecure(jsonConfigPath(), data)
 }
 
 // writeFileSecure writes data to path with 0600 permissions, creating parent dirs.
+// The data goes to <path>.tmp first and is renamed over path, so readers and
+// crashes never see a partially written file. os.Rename replaces an existing
+// file on Windows too (MoveFileEx with MOVEFILE_REPLACE_EXISTING).
 func writeFileSecure(path string, data []byte) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("create dir %s: %w", dir, err)
 	}
 
-	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", tmp, err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return fmt.Errorf("open %s: %w", path, err)
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", tmp, err)
 	}
-	defer f.Close()
 
-	if _, err := f.Write(data); err != nil {
-		return fmt.Errorf("write %s: %w", path, err)
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename %s: %w", tmp, err)
 	}
 	return nil
 }
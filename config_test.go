@@ -1,10 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/pelletier/go-toml/v2"
 )
 
 func TestLoadConfig_Default(t *testing.T) {
@@ -87,6 +95,227 @@ func TestLoadConfig_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_NegativePollJitter(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	dir := t.TempDir()
+	configPath = filepath.Join(dir, "config.json")
+	os.WriteFile(configPath, []byte(`{"poll_jitter_seconds": -3}`), 0600)
+
+	cfg := loadConfig()
+	if cfg.PollJitterSeconds != 10 {
+		t.Errorf("PollJitterSeconds = %d, want default 10", cfg.PollJitterSeconds)
+	}
+}
+
+func TestLoadConfig_Padding(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	for _, tt := range []struct {
+		json string
+		want float64
+	}{
+		{`{"padding": 0}`, 0},
+		{`{"padding": 8}`, 8},
+		{`{"padding": -1}`, 4},
+		{`{"padding": 40}`, 4},
+	} {
+		os.WriteFile(configPath, []byte(tt.json), 0600)
+		if cfg := loadConfig(); cfg.Padding != tt.want {
+			t.Errorf("%s: Padding = %v, want %v", tt.json, cfg.Padding, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_IndicatorSizeRatio(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	for _, tt := range []struct {
+		json string
+		want float64
+	}{
+		{`{}`, 1},
+		{`{"indicator_size_ratio": 0.5}`, 0.5},
+		{`{"indicator_size_ratio": 0.05}`, 1},
+		{`{"indicator_size_ratio": 1.5}`, 1},
+	} {
+		os.WriteFile(configPath, []byte(tt.json), 0600)
+		if cfg := loadConfig(); cfg.IndicatorSizeRatio != tt.want {
+			t.Errorf("%s: IndicatorSizeRatio = %v, want %v", tt.json, cfg.IndicatorSizeRatio, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_TOML(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	dir := t.TempDir()
+
+	configPath = filepath.Join(dir, "config.json")
+	os.WriteFile(configPath, []byte(`{
+		"poll_interval_seconds": 120,
+		"font_name": "mono",
+		"show_text": false,
+		"menu_order": ["five_hour", "quit"],
+		"thresholds": {"warning": 50, "critical": 90}
+	}`), 0600)
+	fromJSON := loadConfig()
+
+	configPath = filepath.Join(dir, "config.toml")
+	os.WriteFile(configPath, []byte(`# comments are allowed
+poll_interval_seconds = 120
+font_name = "mono"
+show_text = false
+menu_order = ["five_hour", "quit"]
+
+[thresholds]
+warning = 50
+critical = 90
+`), 0600)
+	fromTOML := loadConfig()
+
+	if !reflect.DeepEqual(fromTOML, fromJSON) {
+		t.Errorf("TOML config = %+v\nwant JSON equivalent %+v", fromTOML, fromJSON)
+	}
+	if fromTOML.PollIntervalSeconds != 120 || fromTOML.Thresholds.Critical != 90 {
+		t.Errorf("TOML values not applied: %+v", fromTOML)
+	}
+}
+
+func TestLoadConfig_MissingTOMLNotCreated(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+
+	cfg := loadConfig()
+	if cfg.PollIntervalSeconds != 300 {
+		t.Errorf("PollIntervalSeconds = %d, want default 300", cfg.PollIntervalSeconds)
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("missing TOML config should not be created, stat err = %v", err)
+	}
+}
+
+func TestRoundUpToMultiple(t *testing.T) {
+	for _, tt := range []struct{ in, want int }{
+		{1, 8}, {8, 8}, {22, 24}, {64, 64}, {65, 72}, {128, 128},
+	} {
+		if got := roundUpToMultiple(tt.in, 8); got != tt.want {
+			t.Errorf("roundUpToMultiple(%d, 8) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_IconSizeRounded(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	for _, tt := range []struct {
+		json string
+		want int
+	}{
+		{`{}`, 64},
+		{`{"icon_size": 65}`, 72},
+		{`{"icon_size": 128}`, 128},
+	} {
+		os.WriteFile(configPath, []byte(tt.json), 0600)
+		if cfg := loadConfig(); cfg.IconSize != tt.want {
+			t.Errorf("%s: IconSize = %d, want %d", tt.json, cfg.IconSize, tt.want)
+		}
+	}
+}
+
+func TestLoadConfig_InvalidTimeout(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	dir := t.TempDir()
+	configPath = filepath.Join(dir, "config.json")
+
+	for _, v := range []string{"0", "-1", "301"} {
+		os.WriteFile(configPath, []byte(`{"timeout_seconds": `+v+`}`), 0600)
+		if cfg := loadConfig(); cfg.TimeoutSeconds != 30 {
+			t.Errorf("timeout_seconds %s: TimeoutSeconds = %d, want default 30", v, cfg.TimeoutSeconds)
+		}
+	}
+}
+
+func TestResolveMenuOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []string
+		want  []string
+	}{
+		{"default", nil, menuItemNames},
+		{"custom", []string{"refresh", "separator", "5h", "7d", "quit"}, []string{"refresh", "separator", "5h", "7d", "quit"}},
+		{"quit appended", []string{"5h", "7d"}, []string{"5h", "7d", "quit"}},
+		{"unknown dropped", []string{"5h", "bogus", "quit"}, []string{"5h", "quit"}},
+		{"duplicates dropped", []string{"5h", "separator", "5h", "separator", "quit"}, []string{"5h", "separator", "separator", "quit"}},
+	}
+	for _, tc := range tests {
+		if got := resolveMenuOrder(tc.order); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: resolveMenuOrder(%v) = %v, want %v", tc.name, tc.order, got, tc.want)
+		}
+	}
+}
+
+func TestLoadConfig_MenuOrder(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	dir := t.TempDir()
+	configPath = filepath.Join(dir, "config.json")
+	os.WriteFile(configPath, []byte(`{"menu_order": ["refresh", "5h", "quit"]}`), 0600)
+
+	cfg := loadConfig()
+	if !slices.Equal(cfg.MenuOrder, []string{"refresh", "5h", "quit"}) {
+		t.Errorf("MenuOrder = %v, want [refresh 5h quit]", cfg.MenuOrder)
+	}
+}
+
+func TestLoadConfig_APIURL(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	dir := t.TempDir()
+	configPath = filepath.Join(dir, "config.json")
+
+	os.WriteFile(configPath, []byte(`{"api_url": "http://127.0.0.1:8080/usage"}`), 0600)
+	if cfg := loadConfig(); configAPIURL(cfg) != "http://127.0.0.1:8080/usage" {
+		t.Errorf("configAPIURL = %q, want config value", configAPIURL(cfg))
+	}
+
+	os.WriteFile(configPath, []byte(`{"api_url": "ftp://example.com"}`), 0600)
+	if cfg := loadConfig(); configAPIURL(cfg) != defaultUsageURL {
+		t.Errorf("configAPIURL = %q, want default for invalid api_url", configAPIURL(cfg))
+	}
+}
+
+func TestValidAPIURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://api.anthropic.com/api/oauth/usage", true},
+		{"http://localhost:8080", true},
+		{"ftp://example.com", false},
+		{"localhost:8080", false},
+		{"/usage", false},
+		{"", false},
+	}
+	for _, tc := range tests {
+		if got := ValidAPIURL(tc.url); got != tc.want {
+			t.Errorf("ValidAPIURL(%q) = %v, want %v", tc.url, got, tc.want)
+		}
+	}
+}
+
 func TestSaveConfig(t *testing.T) {
 	orig := configPath
 	defer func() { configPath = orig }()
@@ -133,3 +362,660 @@ func TestWriteFileSecure_Permissions(t *testing.T) {
 		t.Errorf("content = %q, want %q", string(data), "test")
 	}
 }
+
+func TestWriteFileSecure_Atomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	oldData := []byte(strings.Repeat("old ", 64<<10))
+	newData := []byte(strings.Repeat("new ", 64<<10))
+	if err := writeFileSecure(path, oldData); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	readErr := make(chan string, 1)
+	go func() {
+		defer close(readErr)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				readErr <- err.Error()
+				return
+			}
+			if !bytes.Equal(data, oldData) && !bytes.Equal(data, newData) {
+				readErr <- fmt.Sprintf("read %d bytes of partial content", len(data))
+				return
+			}
+		}
+	}()
+
+	for i := range 50 {
+		data := oldData
+		if i%2 == 0 {
+			data = newData
+		}
+		if err := writeFileSecure(path, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	if msg, ok := <-readErr; ok {
+		t.Errorf("concurrent read: %s", msg)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file should not remain, stat err = %v", err)
+	}
+}
+
+func TestGenerateConfigSchema(t *testing.T) {
+	var schema struct {
+		Schema     string                    `json:"$schema"`
+		Type       string                    `json:"type"`
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(generateConfigSchema(), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if schema.Schema != "http://json-schema.org/draft-07/schema#" {
+		t.Errorf("$schema = %q, want draft-07", schema.Schema)
+	}
+	if schema.Type != "object" {
+		t.Errorf("type = %q, want object", schema.Type)
+	}
+
+	// Every config key must be documented.
+	data, _ := json.Marshal(defaultConfig())
+	var keys map[string]any
+	json.Unmarshal(data, &keys)
+	keys["claude_home"] = "" // omitempty
+	for key := range keys {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("schema missing property %q", key)
+		}
+	}
+
+	if got := schema.Properties["poll_interval_seconds"]["default"]; got != float64(300) {
+		t.Errorf("poll_interval_seconds default = %v, want 300", got)
+	}
+}
+
+func TestGenerateConfigSchema_Enums(t *testing.T) {
+	var schema struct {
+		Properties map[string]struct {
+			Enum []string `json:"enum"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(generateConfigSchema(), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	indicators := schema.Properties["indicator"].Enum
+	for _, name := range []string{"pie", "bar", "arc", "bar-proj", "bar-dual", "ring-dual", "text"} {
+		if !slices.Contains(indicators, name) {
+			t.Errorf("indicator enum missing %q: %v", name, indicators)
+		}
+	}
+	for _, name := range indicators {
+		if !ValidIndicatorName(name) {
+			t.Errorf("indicator enum contains %q, rejected by ValidIndicatorName", name)
+		}
+	}
+
+	fonts := schema.Properties["font_name"].Enum
+	for _, name := range []string{"bold", "regular", "mono", "monobold", "bitmap"} {
+		if !slices.Contains(fonts, name) {
+			t.Errorf("font_name enum missing %q: %v", name, fonts)
+		}
+	}
+	for _, name := range fonts {
+		if !ValidFontName(name) {
+			t.Errorf("font_name enum contains %q, rejected by ValidFontName", name)
+		}
+	}
+}
+
+func TestResetConfig_AssumeYes(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configPath, []byte(`{"poll_interval_seconds": 42, "font_name": "mono", "stats": true}`), 0600)
+
+	var out bytes.Buffer
+	if err := resetConfig(strings.NewReader(""), &out, true); err != nil {
+		t.Fatalf("resetConfig() error: %v", err)
+	}
+	if !strings.Contains(out.String(), configPath) {
+		t.Errorf("output should contain config path: %q", out.String())
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	defaults := defaultConfig()
+	if cfg.PollIntervalSeconds != defaults.PollIntervalSeconds {
+		t.Errorf("PollIntervalSeconds = %d, want default %d", cfg.PollIntervalSeconds, defaults.PollIntervalSeconds)
+	}
+	if cfg.FontName != defaults.FontName {
+		t.Errorf("FontName = %q, want default %q", cfg.FontName, defaults.FontName)
+	}
+	if cfg.Stats {
+		t.Error("Stats = true, want default false")
+	}
+}
+
+func TestResetConfig_TOML(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(configPath, []byte("poll_interval_seconds = 42\nfont_name = \"mono\"\n"), 0600)
+
+	var out bytes.Buffer
+	if err := resetConfig(strings.NewReader(""), &out, true); err != nil {
+		t.Fatalf("resetConfig() error: %v", err)
+	}
+	if !strings.Contains(out.String(), configPath) {
+		t.Errorf("output should contain the TOML path: %q", out.String())
+	}
+	if _, err := os.Stat(jsonConfigPath()); !os.IsNotExist(err) {
+		t.Errorf("resetConfig() wrote %s for a TOML config", jsonConfigPath())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+	var cfg Config
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("reset config is not TOML: %v", err)
+	}
+	defaults := defaultConfig()
+	if cfg.PollIntervalSeconds != defaults.PollIntervalSeconds || cfg.FontName != defaults.FontName {
+		t.Errorf("reset config poll_interval_seconds = %d, font_name = %q; want the defaults",
+			cfg.PollIntervalSeconds, cfg.FontName)
+	}
+}
+
+func TestResetConfig_Prompt(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	custom := `{"poll_interval_seconds": 42}`
+
+	for _, tc := range []struct {
+		answer string
+		reset  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	} {
+		os.WriteFile(configPath, []byte(custom), 0600)
+		var out bytes.Buffer
+		if err := resetConfig(strings.NewReader(tc.answer), &out, false); err != nil {
+			t.Fatalf("resetConfig(%q) error: %v", tc.answer, err)
+		}
+		if !strings.Contains(out.String(), "Reset config at "+configPath+"? [y/N]: ") {
+			t.Errorf("resetConfig(%q) missing prompt: %q", tc.answer, out.String())
+		}
+		data, _ := os.ReadFile(configPath)
+		if reset := string(data) != custom; reset != tc.reset {
+			t.Errorf("resetConfig(%q) reset = %v, want %v", tc.answer, reset, tc.reset)
+		}
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	data := `{"poll_interval_seconds": 60, "indicator": "bar", "thresholds": {"warning": 50, "critical": 85}}`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := configDiff(defaultConfig(), loadConfig())
+	want := []FieldDiff{
+		{Field: "poll_interval_seconds", Old: "300", New: "60"},
+		{Field: "indicator", Old: `"pie"`, New: `"bar"`},
+		{Field: "thresholds.warning", Old: "60", New: "50"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("configDiff() = %v, want %v", got, want)
+	}
+	if len(got) > 0 && got[0].String() != "poll_interval_seconds: 300 → 60" {
+		t.Errorf("String() = %q", got[0].String())
+	}
+}
+
+func TestConfigDiff_None(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Verbose = true // not a config file field
+	if got := configDiff(defaultConfig(), cfg); len(got) != 0 {
+		t.Errorf("configDiff() = %v, want none", got)
+	}
+}
+
+func TestSampleConfig_MatchesDefaults(t *testing.T) {
+	sampleConfig := generateSampleConfig()
+	var cfg Config
+	if err := json.Unmarshal(sampleConfig, &cfg); err != nil {
+		t.Fatalf("sample config does not parse: %v", err)
+	}
+	if diffs := configDiff(defaultConfig(), cfg); len(diffs) != 0 {
+		t.Errorf("sample config differs from defaultConfig(): %v", diffs)
+	}
+
+	var raw struct {
+		Comments map[string]string `json:"_comments"`
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(sampleConfig, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(sampleConfig, &fields); err != nil {
+		t.Fatal(err)
+	}
+	typ := reflect.TypeOf(Config{})
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if _, ok := fields[name]; !ok {
+			t.Errorf("sample config lacks %q", name)
+		}
+		if raw.Comments[name] == "" {
+			t.Errorf("sample config has no _comments entry for %q", name)
+		}
+	}
+}
+
+func TestSampleConfig_UpToDate(t *testing.T) {
+	committed, err := os.ReadFile("sample_config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generateSampleConfig(), committed) {
+		t.Error("sample_config.json is stale, run go generate")
+	}
+}
+
+func TestLoadConfig_ModelAliases(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	data := `{"model_aliases": {"seven_day_sonnet": "S4", "opus": "O"}}`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := loadConfig()
+	want := map[string]string{"seven_day_sonnet": "S4"}
+	if !reflect.DeepEqual(cfg.ModelAliases, want) {
+		t.Errorf("ModelAliases = %v, want %v (unknown bucket dropped)", cfg.ModelAliases, want)
+	}
+	v := Utilization(42)
+	if got := formatQuotaLine(bucketLabel(cfg.ModelAliases, "seven_day_sonnet"), &v, nil, testNow, "both"); got != "S4: 42%" {
+		t.Errorf("sonnet line = %q, want %q", got, "S4: 42%")
+	}
+	if got := bucketLabel(cfg.ModelAliases, "five_hour"); got != "5h" {
+		t.Errorf("bucketLabel(five_hour) = %q, want default %q", got, "5h")
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/tmp/testuser")
+	t.Setenv("CQ_WORK", "work")
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"$HOME/work", filepath.Clean("/tmp/testuser/work")},
+		{"${HOME}/${CQ_WORK}/", filepath.Clean("/tmp/testuser/work")},
+		{"/plain/path", filepath.Clean("/plain/path")},
+		{"$CQ_UNSET_VAR/x", filepath.Clean("/x")},
+	}
+	for _, tc := range tests {
+		if got := expandPath(tc.in); got != tc.want {
+			t.Errorf("expandPath(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	got := resolveCredentialsPath(expandPath("$HOME/work"), "", "")
+	want := filepath.Join("/tmp/testuser/work", ".claude", ".credentials.json")
+	if got != want {
+		t.Errorf("credentials path = %q, want %q", got, want)
+	}
+}
+
+func TestConfigValidate_Default(t *testing.T) {
+	warnings, err := defaultConfig().Validate()
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("Validate() = %v, %v; want no warnings or errors", warnings, err)
+	}
+}
+
+func TestConfigValidate_OneInvalidField(t *testing.T) {
+	tests := []struct {
+		field  string
+		mutate func(c *Config)
+	}{
+		{"icon_size", func(c *Config) { c.IconSize = -1 }},
+		{"font_size", func(c *Config) { c.FontSize = 0 }},
+		{"poll_interval_seconds", func(c *Config) { c.PollIntervalSeconds = 0 }},
+		{"poll_jitter_seconds", func(c *Config) { c.PollJitterSeconds = -1 }},
+		{"timeout_seconds", func(c *Config) { c.TimeoutSeconds = maxTimeoutSeconds + 1 }},
+		{"min_utilization_for_projection", func(c *Config) { c.MinUtilizationForProjection = 101 }},
+		{"halo_size", func(c *Config) { c.HaloSize = -1 }},
+		{"padding", func(c *Config) { c.Padding = maxIconPadding + 1 }},
+		{"indicator_size_ratio", func(c *Config) { c.IndicatorSizeRatio = 0 }},
+		{"icon_opacity", func(c *Config) { c.IconOpacity = 1.5 }},
+		{"bar_corner_radius", func(c *Config) { c.BarCornerRadius = -1 }},
+		{"ring_gap", func(c *Config) { c.RingGap = maxRingGap + 1 }},
+		{"font_name", func(c *Config) { c.FontName = "comic" }},
+		{"indicator", func(c *Config) { c.Indicator = "donut" }},
+		{"color_source", func(c *Config) { c.ColorSource = "average" }},
+		{"icon_background_color", func(c *Config) { c.IconBackgroundColor = "red" }},
+		{"icon_window", func(c *Config) { c.IconWindow = "1h" }},
+		{"text_position", func(c *Config) { c.TextPosition = "left" }},
+		{"time_display", func(c *Config) { c.TimeDisplay = "never" }},
+		{"max_saturation_display_minutes", func(c *Config) { c.MaxSaturationDisplayMinutes = -5 }},
+		{"api_url", func(c *Config) { c.APIURL = "ftp://example.com" }},
+		{"telemetry_url", func(c *Config) { c.TelemetryURL = "not a url" }},
+		{"log_format", func(c *Config) { c.LogFormat = "xml" }},
+		{"thresholds.warning", func(c *Config) { c.Thresholds.Warning = 0 }},
+		{"thresholds.critical", func(c *Config) { c.Thresholds.Critical = 150 }},
+	}
+	if len(tests) != len(configChecks) {
+		t.Fatalf("test covers %d fields, configChecks has %d", len(tests), len(configChecks))
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			cfg := defaultConfig()
+			tt.mutate(&cfg)
+			_, err := cfg.Validate()
+			errs := configErrors(err)
+			if len(errs) != 1 || errs[0].Field != tt.field {
+				t.Fatalf("Validate() errors = %v, want one for %s", errs, tt.field)
+			}
+			var ce *ConfigError
+			if !errors.As(err, &ce) || ce.Field != tt.field {
+				t.Errorf("errors.As(*ConfigError) = %v, want field %s", ce, tt.field)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_Warnings(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Thresholds = Thresholds{Warning: 80, Critical: 80}
+	cfg.MenuOrder = []string{"refresh", "bogus"}
+	cfg.ModelAliases = map[string]string{"seven_day_opus": "Opus"}
+	cfg.TelemetryEnabled = true
+
+	warnings, err := cfg.Validate()
+	if err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	for _, want := range []string{"thresholds.warning", "menu_order", "model_aliases", "telemetry_url"} {
+		if !slices.ContainsFunc(warnings, func(w string) bool { return strings.Contains(w, want) }) {
+			t.Errorf("warnings = %q, want one about %s", warnings, want)
+		}
+	}
+	if len(warnings) != 4 {
+		t.Errorf("got %d warnings, want 4: %q", len(warnings), warnings)
+	}
+}
+
+func TestSaveConfig_Invalid(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	cfg := defaultConfig()
+	cfg.IconSize = -1
+	if err := saveConfig(cfg); err == nil {
+		t.Fatal("saveConfig() accepted an invalid config")
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Errorf("config file written despite validation error: %v", err)
+	}
+}
+
+func TestPrintConfigValidation(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Thresholds = Thresholds{Warning: 90, Critical: 50}
+	cfg.RingGap = -1
+
+	var buf bytes.Buffer
+	if printConfigValidation(&buf, cfg) {
+		t.Error("printConfigValidation() = true, want false")
+	}
+	want := "Config warning: thresholds.warning (90) >= thresholds.critical (50), they are swapped\n" +
+		"Config error: ring_gap: must be 0-8, got -1\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintConfigDiff_ReportsRawConfig(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"ring_gap": -1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(overrideJSONEnv, `{"thresholds": {"warning": 90, "critical": 50}}`)
+
+	load := loadConfigDetails()
+	var buf bytes.Buffer
+	printConfigDiff(&buf, load.Config, load.Raw)
+	for _, want := range []string{
+		"Config error: ring_gap: must be 0-8, got -1",
+		"Config warning: thresholds.warning (90) >= thresholds.critical (50), they are swapped",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("config diff missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestLoadConfig_OverrideJSON(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"poll_interval_seconds": 120, "font_size": 20}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(overrideJSONEnv, `{"poll_interval_seconds": 45, "thresholds": {"warning": 50, "critical": 70}}`)
+
+	cfg := loadConfig()
+	if cfg.PollIntervalSeconds != 45 {
+		t.Errorf("PollIntervalSeconds = %d, want 45 from the override", cfg.PollIntervalSeconds)
+	}
+	if cfg.FontSize != 20 {
+		t.Errorf("FontSize = %v, want 20 kept from the file", cfg.FontSize)
+	}
+	if cfg.Thresholds != (Thresholds{Warning: 50, Critical: 70}) {
+		t.Errorf("Thresholds = %+v, want 50/70", cfg.Thresholds)
+	}
+}
+
+func TestLoadConfig_OverrideJSONValidated(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	t.Setenv(overrideJSONEnv, `{"poll_interval_seconds": -5}`)
+
+	if cfg := loadConfig(); cfg.PollIntervalSeconds != defaultConfig().PollIntervalSeconds {
+		t.Errorf("PollIntervalSeconds = %d, want the default after validation", cfg.PollIntervalSeconds)
+	}
+}
+
+func TestApplyJSONOverride_Invalid(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ModelAliases = map[string]string{"five_hour": "5h"}
+	cfg.MenuOrder = []string{"5h", "quit"}
+	want := defaultConfig()
+	want.ModelAliases = map[string]string{"five_hour": "5h"}
+	want.MenuOrder = []string{"5h", "quit"}
+
+	for _, s := range []string{
+		`{not json`,
+		`{"model_aliases": {"seven_day": "Week"}, "menu_order": ["7d"], "show_text": false, "poll_interval_seconds": "fast"}`,
+	} {
+		if err := applyJSONOverride(&cfg, s); err == nil {
+			t.Errorf("applyJSONOverride(%q) succeeded", s)
+		}
+		if !reflect.DeepEqual(cfg, want) {
+			t.Errorf("applyJSONOverride(%q) changed cfg to %+v", s, cfg)
+		}
+	}
+}
+
+func TestLoadConfig_MigratesV0(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	// A version 0 file: no schema_version and only the fields it set.
+	if err := os.WriteFile(configPath, []byte(`{"poll_interval_seconds": 120, "indicator": "bar"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := loadConfig()
+	want := defaultConfig()
+	want.PollIntervalSeconds = 120
+	want.Indicator = "bar"
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("loadConfig() = %+v, want %+v", cfg, want)
+	}
+	if cfg.SchemaVersion != currentSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", cfg.SchemaVersion, currentSchemaVersion)
+	}
+}
+
+func TestSaveMigratedConfig(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	v0 := []byte(`{"poll_interval_seconds": 120}`)
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, v0, 0600); err != nil {
+		t.Fatal(err)
+	}
+	logs := captureSlog(t)
+	load := loadConfigDetails()
+	if load.FileVersion != 0 {
+		t.Errorf("FileVersion = %d, want 0", load.FileVersion)
+	}
+	// Loading alone, as the read-only commands do, neither rewrites the
+	// file nor reports a migration.
+	if data, _ := os.ReadFile(configPath); !bytes.Equal(data, v0) {
+		t.Errorf("loadConfigDetails() rewrote the file: %s", data)
+	}
+	if strings.Contains(logs.String(), "Migrated config") {
+		t.Errorf("migration logged before saving:\n%s", logs)
+	}
+
+	saveMigratedConfig(load)
+	if !strings.Contains(logs.String(), "Migrated config") {
+		t.Errorf("migration not logged:\n%s", logs)
+	}
+	load = loadConfigDetails()
+	if load.FileVersion != currentSchemaVersion || load.Config.PollIntervalSeconds != 120 {
+		t.Errorf("after save: FileVersion = %d, PollIntervalSeconds = %d; want %d, 120",
+			load.FileVersion, load.Config.PollIntervalSeconds, currentSchemaVersion)
+	}
+
+	// TOML files are never rewritten.
+	configPath = filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte("poll_interval_seconds = 120\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	saveMigratedConfig(loadConfigDetails())
+	if _, err := os.Stat(jsonConfigPath()); !os.IsNotExist(err) {
+		t.Errorf("saveMigratedConfig() wrote %s for a TOML config", jsonConfigPath())
+	}
+}
+
+func TestSaveMigratedConfig_InvalidValues(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	// A version 0 file with an out-of-range value.
+	if err := os.WriteFile(configPath, []byte(`{"poll_interval_seconds": 120, "ring_gap": -1}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	logs := captureSlog(t)
+	saveMigratedConfig(loadConfigDetails())
+	if strings.Contains(logs.String(), "Failed to save migrated config") {
+		t.Fatalf("migration not saved:\n%s", logs)
+	}
+	load := loadConfigDetails()
+	if load.FileVersion != currentSchemaVersion {
+		t.Errorf("FileVersion = %d, want %d", load.FileVersion, currentSchemaVersion)
+	}
+	if load.File.PollIntervalSeconds != 120 || load.File.RingGap != defaultConfig().RingGap {
+		t.Errorf("saved poll_interval_seconds = %d, ring_gap = %v; want 120 and the default",
+			load.File.PollIntervalSeconds, load.File.RingGap)
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	for _, from := range []int{-1, 0, currentSchemaVersion} {
+		cfg := defaultConfig()
+		cfg.SchemaVersion = from
+		migrateConfig(&cfg, from)
+		if !reflect.DeepEqual(cfg, defaultConfig()) {
+			t.Errorf("migrateConfig(from %d) = %+v, want the defaults", from, cfg)
+		}
+	}
+}
+
+func TestSaveConfig_WritesSchemaVersion(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	cfg := defaultConfig()
+	cfg.SchemaVersion = 0
+	if err := saveConfig(cfg); err != nil {
+		t.Fatalf("saveConfig() error: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.SchemaVersion == nil || *saved.SchemaVersion != currentSchemaVersion {
+		t.Errorf("saved schema_version = %v, want %d", saved.SchemaVersion, currentSchemaVersion)
+	}
+}
+
+func TestConfigValidate_NewerSchemaVersion(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.SchemaVersion = currentSchemaVersion + 1
+	warnings, err := cfg.Validate()
+	if err != nil || len(warnings) != 1 || !strings.Contains(warnings[0], "schema_version") {
+		t.Errorf("Validate() = %q, %v; want one schema_version warning", warnings, err)
+	}
+}
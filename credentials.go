@@ -1,14 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 )
@@ -17,6 +19,10 @@ var ErrTokenExpired = errors.New("OAuth token has expired — run 'claude login'
 
 var credentialsPath string
 
+// keychainService is the service name used by Claude Code (via keytar) in the macOS Keychain.
+// The account is the current OS username.
+const keychainService = "Claude Code-credentials"
+
 func init() {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -25,6 +31,23 @@ func init() {
 	credentialsPath = filepath.Join(home, ".claude", ".credentials.json")
 }
 
+// resolveCredentialsPath returns the credentials file under the Claude home
+// directory, with priority flag > env > config. When none is set, the
+// current credentialsPath (the user home default) is returned unchanged.
+func resolveCredentialsPath(cfgClaudeHome, envClaudeHome, flagClaudeHome string) string {
+	home := flagClaudeHome
+	if home == "" {
+		home = envClaudeHome
+	}
+	if home == "" {
+		home = cfgClaudeHome
+	}
+	if home == "" {
+		return credentialsPath
+	}
+	return filepath.Join(home, ".claude", ".credentials.json")
+}
+
 // credentialsFile represents the on-disk ~/.claude/.credentials.json structure.
 type credentialsFile struct {
 	ClaudeAiOauth struct {
@@ -44,6 +67,7 @@ type OAuthCredentials struct {
 	expiresAt        int64 // ms since epoch
 	subscriptionType string
 	rateLimitTier    string
+	path             string // credentials file read exclusively; empty uses the platform sources
 }
 
 // NewOAuthCredentials loads credentials from disk and returns a manager.
@@ -55,20 +79,80 @@ func NewOAuthCredentials() (*OAuthCredentials, error) {
 	return oc, nil
 }
 
-// loadFromFile reads credentials from ~/.claude/.credentials.json.
+// NewOAuthCredentialsFromFile loads credentials from the file at path only,
+// ignoring the Keychain, the token environment variables and
+// credentialsPath; reloads re-read the same file.
+func NewOAuthCredentialsFromFile(path string) (*OAuthCredentials, error) {
+	oc := &OAuthCredentials{path: path}
+	if err := oc.load(); err != nil {
+		return nil, err
+	}
+	return oc, nil
+}
+
+// load reads the credentials file given to NewOAuthCredentialsFromFile, or
+// else the platform sources (see loadPlatform).
+func (oc *OAuthCredentials) load() error {
+	if oc.path != "" {
+		return oc.loadFromFile()
+	}
+	return oc.loadPlatform()
+}
+
+// filePath returns the credentials file to read.
+func (oc *OAuthCredentials) filePath() string {
+	if oc.path != "" {
+		return oc.path
+	}
+	return credentialsPath
+}
+
+// checkCredentialFilePermissions returns an error when the file at path is
+// readable by group or others. Always nil on Windows, where Unix mode bits
+// don't reflect the ACLs.
+func checkCredentialFilePermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0o044 != 0 {
+		return fmt.Errorf("credentials file is too permissive (mode %04o), run: chmod 600 %s", perm, path)
+	}
+	return nil
+}
+
+// permWarnOnce limits the permission warning to once per run, since the
+// credentials file is re-read on every account refresh.
+var permWarnOnce sync.Once
+
+// loadFromFile reads credentials from the file at filePath, by default
+// ~/.claude/.credentials.json.
 func (oc *OAuthCredentials) loadFromFile() error {
-	data, err := os.ReadFile(credentialsPath)
+	path := oc.filePath()
+	if err := checkCredentialFilePermissions(path); err != nil && !os.IsNotExist(err) {
+		permWarnOnce.Do(func() { slog.Warn("Insecure credentials file", "error", err) })
+	}
+
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("cannot read Claude credentials from %s: %w\nRun 'claude login' to authenticate Claude Code first", credentialsPath, err)
+		// Reading a directory fails with a platform-specific message
+		// ("is a directory", "Access is denied"), so say it plainly.
+		if info, statErr := os.Stat(path); statErr == nil && info.IsDir() {
+			return fmt.Errorf("credentials path %q is a directory, not a file", path)
+		}
+		return fmt.Errorf("cannot read Claude credentials from %s: %w\nRun 'claude login' to authenticate Claude Code first", path, err)
 	}
 
 	var creds credentialsFile
 	if err := json.Unmarshal(data, &creds); err != nil {
-		return fmt.Errorf("cannot parse Claude credentials from %s: %w\nRun 'claude login' to authenticate Claude Code first", credentialsPath, err)
+		return fmt.Errorf("cannot parse Claude credentials from %s: %w\nRun 'claude login' to authenticate Claude Code first", path, err)
 	}
 
 	if creds.ClaudeAiOauth.AccessToken == "" {
-		return fmt.Errorf("missing OAuth access token in %s\nRun 'claude login' to authenticate Claude Code first", credentialsPath)
+		return fmt.Errorf("missing OAuth access token in %s\nRun 'claude login' to authenticate Claude Code first", path)
 	}
 
 	oc.accessToken = creds.ClaudeAiOauth.AccessToken
@@ -79,6 +163,25 @@ func (oc *OAuthCredentials) loadFromFile() error {
 	return nil
 }
 
+// credentialsFilePreCheck exits with guidance when the credentials file is
+// missing. On Windows it additionally prints WSL guidance.
+func credentialsFilePreCheck() {
+	if _, err := os.Stat(credentialsPath); os.IsNotExist(err) {
+		fmt.Println("Claude Code credentials not found.")
+		fmt.Printf("Expected: %s\n", credentialsPath)
+		fmt.Println("\nRun 'claude login' to authenticate Claude Code first.")
+		if runtime.GOOS == "windows" {
+			fmt.Println("\nIf Claude Code is installed in WSL, use -claude-home to point to")
+			fmt.Println(`the WSL home directory, e.g.:`)
+			fmt.Println(`  claude-quota -claude-home \\wsl$\<distro>\home\<username>`)
+			fmt.Println(`Run "wsl -l -q" to list available WSL distributions.`)
+			fmt.Print("\nPress enter to continue...")
+			bufio.NewReader(os.Stdin).ReadBytes('\n')
+		}
+		os.Exit(1)
+	}
+}
+
 // isExpired checks if the access token is expired (with 60s margin).
 // expiresAt == 0 means unknown expiry; assume valid.
 func (oc *OAuthCredentials) isExpired() bool {
@@ -96,14 +199,14 @@ func (oc *OAuthCredentials) GetAccessToken() (string, error) {
 	defer oc.mu.Unlock()
 
 	if oc.isExpired() {
-		log.Println("OAuth token expired, reloading credentials from disk...")
+		slog.Info("OAuth token expired, reloading credentials from disk")
 		if err := oc.load(); err != nil {
 			return "", fmt.Errorf("%w (reload failed: %v)", ErrTokenExpired, err)
 		}
 		if oc.isExpired() {
 			return "", ErrTokenExpired
 		}
-		log.Println("Reloaded valid token from disk")
+		slog.Info("Reloaded valid token from disk")
 	}
 	return oc.accessToken, nil
 }
@@ -165,6 +268,40 @@ func (oc *OAuthCredentials) SubscriptionType() string {
 	return oc.subscriptionType
 }
 
+// ExpiresAt returns the access token expiry time, or nil when unknown.
+func (oc *OAuthCredentials) ExpiresAt() *time.Time {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if oc.expiresAt == 0 {
+		return nil
+	}
+	t := time.UnixMilli(oc.expiresAt)
+	return &t
+}
+
+// CredentialInfo is non-sensitive credential metadata, safe to log or
+// report. It never holds token values.
+type CredentialInfo struct {
+	ExpiresAt       time.Time // zero when the expiry is unknown
+	IsExpired       bool      // expired or within a minute of expiry
+	HasRefreshToken bool
+}
+
+// Inspect returns metadata about the held credentials without exposing the
+// access or refresh token.
+func (oc *OAuthCredentials) Inspect() CredentialInfo {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	info := CredentialInfo{
+		IsExpired:       oc.isExpired(),
+		HasRefreshToken: oc.refreshToken != "",
+	}
+	if oc.expiresAt != 0 {
+		info.ExpiresAt = time.UnixMilli(oc.expiresAt)
+	}
+	return info
+}
+
 // RateLimitTier returns the rate limit tier from the credentials file.
 func (oc *OAuthCredentials) RateLimitTier() string {
 	oc.mu.Lock()
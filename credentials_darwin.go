@@ -5,30 +5,26 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os/exec"
 	"os/user"
 	"strings"
 )
 
-// keychainService is the service name used by Claude Code (via keytar) in the macOS Keychain.
-// The account is the current OS username.
-const keychainService = "Claude Code-credentials"
-
 // loadKeychainFn is the function used to fetch credentials from the Keychain.
 // It can be overridden in tests to bypass the real Keychain lookup.
 var loadKeychainFn = loadFromKeychain
 
-// load tries to read credentials from the macOS Keychain first.
+// loadPlatform tries to read credentials from the macOS Keychain first.
 // If the Keychain lookup fails for any reason, it falls back to the JSON file.
-func (oc *OAuthCredentials) load() error {
+func (oc *OAuthCredentials) loadPlatform() error {
 	creds, err := loadKeychainFn()
 	if err != nil {
-		log.Printf("Keychain lookup failed (%v), falling back to credentials file", err)
+		slog.Warn("Keychain lookup failed, falling back to credentials file", "error", err)
 		return oc.loadFromFile()
 	}
 	if creds.ClaudeAiOauth.AccessToken == "" {
-		log.Printf("Keychain entry found but accessToken is empty, falling back to credentials file")
+		slog.Warn("Keychain entry found but accessToken is empty, falling back to credentials file")
 		return oc.loadFromFile()
 	}
 	oc.accessToken = creds.ClaudeAiOauth.AccessToken
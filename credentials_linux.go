@@ -0,0 +1,47 @@
+//go:build linux
+
+package main
+
+import (
+	"log/slog"
+	"os"
+)
+
+// Environment variables that supply OAuth tokens directly, bypassing the
+// credentials file. Intended for CI and other headless setups.
+const (
+	envAccessToken  = "CLAUDE_OAUTH_ACCESS_TOKEN"
+	envRefreshToken = "CLAUDE_OAUTH_REFRESH_TOKEN"
+)
+
+// envCredentialsSet reports whether both token env vars are set.
+func envCredentialsSet() bool {
+	return os.Getenv(envAccessToken) != "" && os.Getenv(envRefreshToken) != ""
+}
+
+// loadPlatform reads credentials from the token env vars when both are set, and from
+// the JSON file otherwise. Env tokens carry no expiry, so they are treated as
+// valid until the API rejects them.
+func (oc *OAuthCredentials) loadPlatform() error {
+	if envCredentialsSet() {
+		if oc.accessToken == "" {
+			slog.Info("Using OAuth tokens from environment, credentials file ignored", "access_token_var", envAccessToken, "refresh_token_var", envRefreshToken)
+		}
+		oc.accessToken = os.Getenv(envAccessToken)
+		oc.refreshToken = os.Getenv(envRefreshToken)
+		oc.expiresAt = 0
+		oc.subscriptionType = ""
+		oc.rateLimitTier = ""
+		return nil
+	}
+	return oc.loadFromFile()
+}
+
+// credentialsPreCheck verifies the credentials file exists before loading,
+// unless tokens are supplied through the environment.
+func credentialsPreCheck() {
+	if envCredentialsSet() {
+		return
+	}
+	credentialsFilePreCheck()
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_EnvCredentials(t *testing.T) {
+	orig := credentialsPath
+	defer func() { credentialsPath = orig }()
+	// Point at a missing file: the env path must not touch it.
+	credentialsPath = filepath.Join(t.TempDir(), "missing.json")
+
+	t.Setenv(envAccessToken, "env-access")
+	t.Setenv(envRefreshToken, "env-refresh")
+
+	oc, err := NewOAuthCredentials()
+	if err != nil {
+		t.Fatalf("NewOAuthCredentials() error: %v", err)
+	}
+	if oc.accessToken != "env-access" {
+		t.Errorf("accessToken = %q, want %q", oc.accessToken, "env-access")
+	}
+	if oc.refreshToken != "env-refresh" {
+		t.Errorf("refreshToken = %q, want %q", oc.refreshToken, "env-refresh")
+	}
+	if oc.isExpired() {
+		t.Error("env credentials should not be considered expired")
+	}
+}
+
+func TestLoad_EnvCredentialsPartial(t *testing.T) {
+	orig := credentialsPath
+	defer func() { credentialsPath = orig }()
+	credentialsPath = filepath.Join(t.TempDir(), "missing.json")
+
+	// Only one of the two vars set: fall back to the (missing) file.
+	t.Setenv(envAccessToken, "env-access")
+	t.Setenv(envRefreshToken, "")
+
+	if _, err := NewOAuthCredentials(); err == nil {
+		t.Error("NewOAuthCredentials() should fail without both env vars and no file")
+	}
+}
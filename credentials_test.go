@@ -5,8 +5,11 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -97,6 +100,25 @@ func TestLoad_MissingFile(t *testing.T) {
 	}
 }
 
+func TestLoadFromFile_Directory(t *testing.T) {
+	orig := credentialsPath
+	defer func() { credentialsPath = orig }()
+
+	credentialsPath = t.TempDir()
+
+	oc := &OAuthCredentials{}
+	err := oc.loadFromFile()
+	want := fmt.Sprintf("credentials path %q is a directory, not a file", credentialsPath)
+	if err == nil || err.Error() != want {
+		t.Errorf("loadFromFile() error = %v, want %q", err, want)
+	}
+
+	credentialsPath = filepath.Join(credentialsPath, "missing.json")
+	if err := oc.loadFromFile(); err == nil || strings.Contains(err.Error(), "is a directory, not a file") {
+		t.Errorf("loadFromFile() error for missing file = %v, want a read error", err)
+	}
+}
+
 func TestLoad_InvalidJSON(t *testing.T) {
 	orig := credentialsPath
 	defer func() { credentialsPath = orig }()
@@ -249,6 +271,57 @@ func TestCredentialsPath_CustomPath(t *testing.T) {
 	}
 }
 
+func TestResolveCredentialsPath(t *testing.T) {
+	orig := credentialsPath
+	defer func() { credentialsPath = orig }()
+	credentialsPath = filepath.Join("default", ".claude", ".credentials.json")
+
+	join := func(home string) string { return filepath.Join(home, ".claude", ".credentials.json") }
+	tests := []struct {
+		name                       string
+		cfgHome, envHome, flagHome string
+		want                       string
+	}{
+		{"none", "", "", "", credentialsPath},
+		{"config only", "/cfg", "", "", join("/cfg")},
+		{"env only", "", "/env", "", join("/env")},
+		{"flag only", "", "", "/flag", join("/flag")},
+		{"env over config", "/cfg", "/env", "", join("/env")},
+		{"all set, flag wins", "/cfg", "/env", "/flag", join("/flag")},
+	}
+	for _, tc := range tests {
+		if got := resolveCredentialsPath(tc.cfgHome, tc.envHome, tc.flagHome); got != tc.want {
+			t.Errorf("%s: resolveCredentialsPath(%q, %q, %q) = %q, want %q", tc.name, tc.cfgHome, tc.envHome, tc.flagHome, got, tc.want)
+		}
+	}
+}
+
+func TestCheckCredentialFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits not enforced on Windows")
+	}
+	path := writeTestCredentials(t, "tok", 0)
+
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkCredentialFilePermissions(path); err != nil {
+		t.Errorf("mode 0600: unexpected error %v", err)
+	}
+
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := checkCredentialFilePermissions(path)
+	if err == nil {
+		t.Fatal("mode 0644: expected an error")
+	}
+	want := "credentials file is too permissive (mode 0644), run: chmod 600 " + path
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
+
 func TestReloadAndSnapshot_Changed(t *testing.T) {
 	orig := credentialsPath
 	defer func() { credentialsPath = orig }()
@@ -345,3 +418,42 @@ func TestLoad_ParsesSubscriptionType(t *testing.T) {
 		t.Errorf("RateLimitTier() = %q, want %q", oc.RateLimitTier(), "tier4")
 	}
 }
+
+func TestExpiresAt(t *testing.T) {
+	oc := &OAuthCredentials{}
+	if got := oc.ExpiresAt(); got != nil {
+		t.Errorf("ExpiresAt() = %v, want nil for unknown expiry", got)
+	}
+	ms := time.Now().Add(time.Hour).UnixMilli()
+	oc.expiresAt = ms
+	got := oc.ExpiresAt()
+	if got == nil || got.UnixMilli() != ms {
+		t.Errorf("ExpiresAt() = %v, want %d ms", got, ms)
+	}
+}
+
+func TestOAuthCredentials_Inspect(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	oc := &OAuthCredentials{accessToken: "secret-access", refreshToken: "secret-refresh", expiresAt: expires.UnixMilli()}
+	info := oc.Inspect()
+	if !info.ExpiresAt.Equal(expires) {
+		t.Errorf("ExpiresAt = %v, want %v", info.ExpiresAt, expires)
+	}
+	if info.IsExpired {
+		t.Error("IsExpired = true for a token valid for an hour")
+	}
+	if !info.HasRefreshToken {
+		t.Error("HasRefreshToken = false, want true")
+	}
+	if dump := fmt.Sprintf("%+v", info); strings.Contains(dump, "secret") {
+		t.Errorf("Inspect leaks a token: %s", dump)
+	}
+
+	expired := (&OAuthCredentials{accessToken: "tok", expiresAt: time.Now().Add(-time.Minute).UnixMilli()}).Inspect()
+	if !expired.IsExpired || expired.HasRefreshToken {
+		t.Errorf("expired Inspect = %+v, want IsExpired and no refresh token", expired)
+	}
+	if unknown := (&OAuthCredentials{accessToken: "tok"}).Inspect(); !unknown.ExpiresAt.IsZero() || unknown.IsExpired {
+		t.Errorf("unknown expiry Inspect = %+v, want zero ExpiresAt, not expired", unknown)
+	}
+}
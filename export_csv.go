@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// csvHeader is the header row written by ExportCSV.
+var csvHeader = []string{
+	"timestamp", "five_hour_pct", "seven_day_pct", "seven_day_sonnet_pct",
+	"five_hour_projected", "seven_day_projected",
+}
+
+// ExportCSV writes the recorded fetch snapshots in store to w as CSV, oldest
+// first, with an RFC 3339 UTC timestamp. Unknown values are empty cells.
+func ExportCSV(store *StatsStore, w io.Writer) error {
+	rows, err := store.db.Query(`
+		SELECT fetched_at, five_hour_util, seven_day_util, seven_day_sonnet_util,
+			five_hour_projected, seven_day_projected
+		FROM fetch_stats ORDER BY fetched_at, id`)
+	if err != nil {
+		return fmt.Errorf("query stats: %w", err)
+	}
+	defer rows.Close()
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var fetchedAt int64
+		var fiveHour, sevenDay, sonnet, fiveHourProj, sevenDayProj sql.NullFloat64
+		if err := rows.Scan(&fetchedAt, &fiveHour, &sevenDay, &sonnet, &fiveHourProj, &sevenDayProj); err != nil {
+			return fmt.Errorf("read stats: %w", err)
+		}
+		record := []string{time.Unix(fetchedAt, 0).UTC().Format(time.RFC3339)}
+		for _, v := range []sql.NullFloat64{fiveHour, sevenDay, sonnet, fiveHourProj, sevenDayProj} {
+			record = append(record, csvFloat(v))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read stats: %w", err)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvFloat formats v for a CSV cell, or "" when NULL.
+func csvFloat(v sql.NullFloat64) string {
+	if !v.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(v.Float64, 'f', -1, 64)
+}
+
+// runExportCSV writes the stats database to a CSV file at path.
+// Returns the process exit code: 0 on success, 1 on any error.
+func runExportCSV(w io.Writer, path string) int {
+	store, err := NewStatsStore()
+	if err != nil {
+		fmt.Fprintf(w, "Stats error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(w, "Export error: %v\n", err)
+		return 1
+	}
+	err = ExportCSV(store, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(w, "Export error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExportCSV(t *testing.T) {
+	store := newTestStore(t)
+	u := func(v float64) *Utilization { x := Utilization(v); return &x }
+	for i := range 5 {
+		at := time.Date(2026, 3, 1, 12, i, 0, 0, time.UTC)
+		state := QuotaState{LastUpdate: &at, FiveHour: u(float64(10 * i)), SevenDay: u(5.5)}
+		if i%2 == 0 {
+			state.FiveHourProjected = u(float64(20 * i))
+		}
+		store.RecordFetch(state, "acct")
+	}
+
+	var buf bytes.Buffer
+	if err := ExportCSV(store, &buf); err != nil {
+		t.Fatalf("ExportCSV() error: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(records) != 6 {
+		t.Fatalf("got %d rows, want header + 5", len(records))
+	}
+	if !slices.Equal(records[0], csvHeader) {
+		t.Errorf("header = %v, want %v", records[0], csvHeader)
+	}
+	if want := []string{"2026-03-01T12:00:00Z", "0", "5.5", "", "0", ""}; !slices.Equal(records[1], want) {
+		t.Errorf("row 1 = %q, want %q", records[1], want)
+	}
+	if want := []string{"2026-03-01T12:01:00Z", "10", "5.5", "", "", ""}; !slices.Equal(records[2], want) {
+		t.Errorf("row 2 = %q, want %q (nil projection as empty cell)", records[2], want)
+	}
+}
+
+func TestRunExportCSV(t *testing.T) {
+	store := newTestStore(t)
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.RecordFetch(QuotaState{LastUpdate: &at}, "")
+
+	path := filepath.Join(t.TempDir(), "quota.csv")
+	var out bytes.Buffer
+	if code := runExportCSV(&out, path); code != 0 {
+		t.Fatalf("runExportCSV() = %d, want 0; output: %s", code, out.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "timestamp,five_hour_pct,seven_day_pct,seven_day_sonnet_pct,five_hour_projected,seven_day_projected\n" +
+		"2026-03-01T12:00:00Z,,,,,\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+}
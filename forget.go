@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// errStoredCredentialsNotFound is returned by deleteStoredCredentialsFn when
+// the credential store has no entry for the service.
+var errStoredCredentialsNotFound = errors.New("no entry found")
+
+// deleteStoredCredentialsFn removes the service's entry from the platform
+// credential store. Package-level var so tests can stub it.
+var deleteStoredCredentialsFn = deleteStoredCredentials
+
+// forgetCredentials removes cached Claude credentials from the platform
+// credential store (if any) and deletes the credentials file, printing the
+// outcome of each step to out. Asks for confirmation on in unless assumeYes.
+// Returns the process exit code.
+func forgetCredentials(in io.Reader, out io.Writer, assumeYes bool) int {
+	if !assumeYes {
+		fmt.Fprintf(out, "This also logs Claude Code out. Forget stored credentials? [y/N]: ")
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+		default:
+			fmt.Fprintln(out, "Aborted.")
+			return 0
+		}
+	}
+
+	code := 0
+	if credentialStoreName != "" {
+		switch err := deleteStoredCredentialsFn(keychainService); {
+		case err == nil:
+			fmt.Fprintf(out, "%s: removed %q\n", credentialStoreName, keychainService)
+		case errors.Is(err, errStoredCredentialsNotFound):
+			fmt.Fprintf(out, "%s: no %q entry\n", credentialStoreName, keychainService)
+		default:
+			fmt.Fprintf(out, "%s: %v\n", credentialStoreName, err)
+			code = 1
+		}
+	}
+
+	switch err := os.Remove(credentialsPath); {
+	case err == nil:
+		fmt.Fprintf(out, "Credentials file: removed %s\n", credentialsPath)
+	case os.IsNotExist(err):
+		fmt.Fprintf(out, "Credentials file: %s does not exist\n", credentialsPath)
+	default:
+		fmt.Fprintf(out, "Credentials file: %v\n", err)
+		code = 1
+	}
+	return code
+}
@@ -0,0 +1,35 @@
+//go:build darwin
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"os/user"
+)
+
+// credentialStoreName labels the platform credential store in output.
+// Package-level var so tests can enable the store step on any platform.
+var credentialStoreName = "Keychain"
+
+// securityItemNotFound is the exit status of `security` when no item matches.
+const securityItemNotFound = 44
+
+// deleteStoredCredentials removes the service's generic password for the
+// current user from the macOS Keychain.
+func deleteStoredCredentials(service string) error {
+	u, err := user.Current()
+	if err != nil {
+		return fmt.Errorf("cannot determine current user: %w", err)
+	}
+	err = exec.Command("security", "delete-generic-password", "-s", service, "-a", u.Username).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityItemNotFound {
+		return errStoredCredentialsNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("security command failed: %w", err)
+	}
+	return nil
+}
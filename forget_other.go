@@ -0,0 +1,13 @@
+//go:build !darwin
+
+package main
+
+// credentialStoreName is empty: Claude Code keeps its credentials only in the
+// credentials file (or environment) outside macOS. Package-level var so
+// tests can enable the store step.
+var credentialStoreName = ""
+
+// deleteStoredCredentials is a no-op without a platform credential store.
+func deleteStoredCredentials(string) error {
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubCredentialStore enables the credential store step with a stubbed delete
+// function returning err, and records the service names it was called with.
+func stubCredentialStore(t *testing.T, err error) *[]string {
+	t.Helper()
+	origName, origFn := credentialStoreName, deleteStoredCredentialsFn
+	t.Cleanup(func() { credentialStoreName, deleteStoredCredentialsFn = origName, origFn })
+
+	var calls []string
+	credentialStoreName = "Test store"
+	deleteStoredCredentialsFn = func(service string) error {
+		calls = append(calls, service)
+		return err
+	}
+	return &calls
+}
+
+func useTempCredentialsPath(t *testing.T, create bool) string {
+	t.Helper()
+	orig := credentialsPath
+	t.Cleanup(func() { credentialsPath = orig })
+	credentialsPath = filepath.Join(t.TempDir(), ".credentials.json")
+	if create {
+		os.WriteFile(credentialsPath, []byte("{}"), 0600)
+	}
+	return credentialsPath
+}
+
+func TestForgetCredentials(t *testing.T) {
+	calls := stubCredentialStore(t, nil)
+	path := useTempCredentialsPath(t, true)
+
+	var out strings.Builder
+	if code := forgetCredentials(nil, &out, true); code != 0 {
+		t.Errorf("exit code = %d, want 0\n%s", code, out.String())
+	}
+	if len(*calls) != 1 || (*calls)[0] != "Claude Code-credentials" {
+		t.Errorf("delete called with %q, want [Claude Code-credentials]", *calls)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("credentials file should be removed, stat err = %v", err)
+	}
+	for _, want := range []string{"Test store: removed", "Credentials file: removed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestForgetCredentials_NothingStored(t *testing.T) {
+	stubCredentialStore(t, errStoredCredentialsNotFound)
+	useTempCredentialsPath(t, false)
+
+	var out strings.Builder
+	if code := forgetCredentials(nil, &out, true); code != 0 {
+		t.Errorf("exit code = %d, want 0 when nothing is stored\n%s", code, out.String())
+	}
+	for _, want := range []string{"Test store: no", "does not exist"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestForgetCredentials_StoreError(t *testing.T) {
+	stubCredentialStore(t, errors.New("access denied"))
+	useTempCredentialsPath(t, false)
+
+	var out strings.Builder
+	if code := forgetCredentials(nil, &out, true); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), "access denied") {
+		t.Errorf("output should include the error:\n%s", out.String())
+	}
+}
+
+func TestForgetCredentials_Aborted(t *testing.T) {
+	calls := stubCredentialStore(t, nil)
+	path := useTempCredentialsPath(t, true)
+
+	var out strings.Builder
+	forgetCredentials(strings.NewReader("n\n"), &out, false)
+	if len(*calls) != 0 {
+		t.Error("store should not be touched when aborted")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("credentials file should be kept when aborted: %v", err)
+	}
+}
@@ -2,29 +2,52 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"text/template"
 	"time"
 )
 
-// formatTimeRemaining returns a human-readable duration until resetTime.
+// formatTimeRemaining returns a human-readable duration from now until resetTime.
 // Returns "unknown" if resetTime is nil, "now" if already past.
-func formatTimeRemaining(resetTime *time.Time) string {
+func formatTimeRemaining(resetTime *time.Time, now time.Time) string {
 	if resetTime == nil {
 		return "unknown"
 	}
 
-	delta := time.Until(*resetTime)
+	delta := resetTime.Sub(now)
 	if delta < 0 {
 		return "now"
 	}
+	return formatDuration(delta, false)
+}
 
-	totalSec := int(delta.Seconds())
+// formatDuration renders d as "Xh Ym", or "Xm" under an hour. With showSeconds,
+// sub-hour durations include seconds: "Xm Ys", or "Xs" under a minute.
+// Negative durations are clamped to zero.
+func formatDuration(d time.Duration, showSeconds bool) string {
+	if d < 0 {
+		d = 0
+	}
+	totalSec := int(d.Seconds())
 	hours := totalSec / 3600
 	minutes := (totalSec % 3600) / 60
+	seconds := totalSec % 60
 
-	if hours > 0 {
+	switch {
+	case hours > 0:
 		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case !showSeconds:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
 	}
-	return fmt.Sprintf("%dm", minutes)
 }
 
 // formatResetDate formats a reset time as local "Day HH:MM".
@@ -37,55 +60,253 @@ func formatResetDate(resetTime *time.Time) string {
 }
 
 // formatUpdatedAgo returns "Updated: Xs ago" / "Xm Ys ago" / "Xh Ym ago" for the given time.
-func formatUpdatedAgo(t *time.Time) string {
+func formatUpdatedAgo(t *time.Time, now time.Time) string {
 	if t == nil {
 		return "Updated: --"
 	}
-	ago := time.Since(*t)
-	if ago < 0 {
-		ago = 0
-	}
-	totalSec := int(ago.Seconds())
-	if totalSec < 60 {
-		return fmt.Sprintf("Updated: %ds ago", totalSec)
+	return fmt.Sprintf("Updated: %s ago", formatDuration(now.Sub(*t), true))
+}
+
+// tokenExpiryWarnWindow is how far ahead a token expiry starts being shown.
+const tokenExpiryWarnWindow = 24 * time.Hour
+
+// formatTokenExpiry returns "(token expires in Xh Ym)" when the token expires
+// within tokenExpiryWarnWindow, or "" if unknown, already expired, or further out.
+func formatTokenExpiry(expiresAt *time.Time, now time.Time) string {
+	if expiresAt == nil {
+		return ""
 	}
-	minutes := totalSec / 60
-	seconds := totalSec % 60
-	if minutes < 60 {
-		return fmt.Sprintf("Updated: %dm %ds ago", minutes, seconds)
+	remaining := expiresAt.Sub(now)
+	if remaining <= 0 || remaining > tokenExpiryWarnWindow {
+		return ""
 	}
-	hours := minutes / 60
-	minutes = minutes % 60
-	return fmt.Sprintf("Updated: %dh %dm ago", hours, minutes)
+	return fmt.Sprintf("(token expires in %s)", formatTimeRemaining(expiresAt, now))
 }
 
 // formatSaturationLine returns a formatted saturation line, or "" if nil.
-func formatSaturationLine(saturation *time.Time) string {
+func formatSaturationLine(saturation *time.Time, now time.Time) string {
 	if saturation == nil {
 		return ""
 	}
-	remaining := formatTimeRemaining(saturation)
+	remaining := formatTimeRemaining(saturation, now)
 	date := formatResetDate(saturation)
 	return fmt.Sprintf("  - saturates in %s, %s", remaining, date)
 }
 
+// showSaturation reports whether a saturation line is worth showing: the
+// saturation time is known and at most max away. max <= 0 means no limit.
+func showSaturation(saturation *time.Time, now time.Time, max time.Duration) bool {
+	return saturation != nil && (max <= 0 || saturation.Sub(now) <= max)
+}
+
 // formatProjectionLine returns a formatted projection line, or "" if nil.
-func formatProjectionLine(projected *float64) string {
+// When low and high are set, the relative margin is appended, e.g. " (±10%)".
+func formatProjectionLine(projected, low, high *Utilization) string {
 	if projected == nil {
 		return ""
 	}
-	return fmt.Sprintf("  - projected ~%.0f%% at reset", *projected)
+	line := fmt.Sprintf("  - projected ~%s at reset", *projected)
+	if low != nil && high != nil && *projected > 0 && *high > *low {
+		margin := (*high - *low) / 2 / *projected * 100
+		line += fmt.Sprintf(" (±%s)", margin)
+	}
+	return line
+}
+
+// timeDisplays lists the time_display values: "relative" shows the time left
+// until reset, "absolute" the local reset time, "both" (default) shows both.
+var timeDisplays = []string{"relative", "absolute", "both"}
+
+// ValidTimeDisplay returns true if s is a known time_display value.
+func ValidTimeDisplay(s string) bool {
+	return slices.Contains(timeDisplays, s)
 }
 
-// formatQuotaLine formats a single quota line with remaining time and date.
-func formatQuotaLine(label string, utilization *float64, resets *time.Time) string {
+// formatQuotaLine formats a single quota line with the reset time rendered
+// according to timeDisplay, e.g. "5h: 42% (resets in 2h 30m, Mon 14:30)".
+// Unknown timeDisplay values behave like "both".
+func formatQuotaLine(label string, utilization *Utilization, resets *time.Time, now time.Time, timeDisplay string) string {
 	if utilization == nil {
 		return fmt.Sprintf("%s: --", label)
 	}
-	remaining := formatTimeRemaining(resets)
+	if resets == nil {
+		return fmt.Sprintf("%s: %s", label, *utilization)
+	}
+	remaining := formatTimeRemaining(resets, now)
 	date := formatResetDate(resets)
-	if date != "" {
-		return fmt.Sprintf("%s: %.0f%% (resets in %s, %s)", label, *utilization, remaining, date)
+	switch timeDisplay {
+	case "relative":
+		return fmt.Sprintf("%s: %s (resets in %s)", label, *utilization, remaining)
+	case "absolute":
+		return fmt.Sprintf("%s: %s (resets %s)", label, *utilization, date)
+	default:
+		return fmt.Sprintf("%s: %s (resets in %s, %s)", label, *utilization, remaining, date)
+	}
+}
+
+// formatFetchStats returns "Fetches: N (M errors)".
+func formatFetchStats(stats QuotaStats) string {
+	return fmt.Sprintf("Fetches: %d (%d errors)", stats.FetchCount, stats.FetchErrorCount)
+}
+
+// titleData holds the fields available to title_format templates.
+// Percentages are rounded numbers without the % sign, or "" when unknown.
+type titleData struct {
+	FiveHour       string
+	SevenDay       string
+	SevenDaySonnet string
+	Projected      string
+	Error          string
+}
+
+// titlePercent formats u as a bare rounded percentage, or "" if nil.
+func titlePercent(u *Utilization) string {
+	if u == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.0f", float64(*u))
+}
+
+// newTitleData returns the title_format view of state.
+func newTitleData(state QuotaState) titleData {
+	return titleData{
+		FiveHour:       titlePercent(state.FiveHour),
+		SevenDay:       titlePercent(state.SevenDay),
+		SevenDaySonnet: titlePercent(state.SevenDaySonnet),
+		Projected:      titlePercent(state.FiveHourProjected),
+		Error:          state.Error,
+	}
+}
+
+// parseTitleFormat parses the title_format template s once, at startup.
+// It returns nil for an empty s, and logs a warning and returns nil when s
+// does not parse or fails to render, e.g. on an unknown field.
+func parseTitleFormat(s string) *template.Template {
+	if s == "" {
+		return nil
+	}
+	t, err := template.New("title").Parse(s)
+	if err == nil {
+		// Every field is a string, so a template that renders empty data
+		// renders any state.
+		err = t.Execute(io.Discard, titleData{})
+	}
+	if err != nil {
+		slog.Warn("Invalid title_format", "title_format", s, "error", err)
+		return nil
+	}
+	return t
+}
+
+// formatTitle renders the parsed title_format template t against state, e.g.
+// "{{.FiveHour}}%" → "42%". A nil or failing template yields "" so the tray
+// title is simply left blank.
+func formatTitle(t *template.Template, state QuotaState) string {
+	if t == nil {
+		return ""
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, newTitleData(state)); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+// summaryBarWidth is the number of cells in the PrintSummary progress bar.
+const summaryBarWidth = 20
+
+// progressBarPartials are the cells for a partially filled position, indexed
+// by the filled quarters (0-3). Empty and quarter-filled cells look the same.
+var progressBarPartials = []string{"░", "░", "▒", "▓"}
+
+// formatProgressBar renders value/max as a width-cell bar with quarter-cell
+// precision followed by the percentage, e.g. "[████░░░░░░] 42%".
+// The bar is clamped to 0-100%; the percentage is not. A non-positive max
+// renders an empty bar at 0%.
+func formatProgressBar(value, max float64, width int) string {
+	frac := 0.0
+	if max > 0 {
+		frac = value / max
+	}
+	quarters := int(math.Max(0, math.Min(1, frac)) * float64(width) * 4)
+	full := quarters / 4
+
+	var b strings.Builder
+	b.WriteString("[")
+	b.WriteString(strings.Repeat("█", full))
+	if full < width {
+		b.WriteString(progressBarPartials[quarters%4])
+		b.WriteString(strings.Repeat("░", width-full-1))
+	}
+	fmt.Fprintf(&b, "] %.0f%%", frac*100)
+	return b.String()
+}
+
+// formatASCIIBar renders u as exactly width cells of '#' (used) and '-'
+// (free), e.g. "####------" for 42% at width 10. Nil renders an empty bar.
+func formatASCIIBar(u *Utilization, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	filled := 0
+	if u != nil {
+		filled = int(math.Round(math.Max(0, math.Min(1, u.Fraction())) * float64(width)))
+	}
+	return strings.Repeat("#", filled) + strings.Repeat("-", width-filled)
+}
+
+// utilizationLevel names the threshold band of u: "ok", "warning",
+// "critical", or "unknown" when nil. Mirrors colorForUtilization.
+func utilizationLevel(u *Utilization, th Thresholds) string {
+	switch {
+	case u == nil:
+		return "unknown"
+	case float64(*u) >= th.Critical:
+		return "critical"
+	case float64(*u) >= th.Warning:
+		return "warning"
+	}
+	return "ok"
+}
+
+// PrintSummary writes a human-readable table of the quota windows in state:
+// current and projected utilization, a utilization bar, time to reset and,
+// when projected, time to saturation.
+func PrintSummary(state QuotaState, w io.Writer) {
+	if state.Error != "" {
+		fmt.Fprintf(w, "Error: %s\n", state.Error)
+		return
+	}
+
+	now := time.Now()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WINDOW\tUSED\tPROJECTED\tRESETS IN\tSATURATES IN")
+	windows := []struct {
+		label      string
+		util       *Utilization
+		projected  *Utilization
+		resets     *time.Time
+		saturation *time.Time
+	}{
+		{"5h", state.FiveHour, state.FiveHourProjected, state.FiveHourResets, state.FiveHourSaturation},
+		{"7d", state.SevenDay, state.SevenDayProjected, state.SevenDayResets, state.SevenDaySaturation},
+		{"Sonnet 7d", state.SevenDaySonnet, nil, state.SevenDaySonnetResets, nil},
+	}
+	for _, win := range windows {
+		used, projected, resets, saturates := "--", "--", "--", "--"
+		if win.util != nil {
+			used = formatProgressBar(float64(*win.util), 100, summaryBarWidth)
+		}
+		if win.projected != nil {
+			projected = "~" + win.projected.String()
+		}
+		if win.resets != nil {
+			resets = formatTimeRemaining(win.resets, now)
+		}
+		if win.saturation != nil {
+			saturates = formatTimeRemaining(win.saturation, now)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", win.label, used, projected, resets, saturates)
 	}
-	return fmt.Sprintf("%s: %.0f%%", label, *utilization)
+	tw.Flush()
 }
@@ -1,34 +1,42 @@
 package main
 
 import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
+// testNow is a fixed reference time so relative formatting is deterministic.
+var testNow = time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+
 func TestFormatTimeRemaining_Nil(t *testing.T) {
-	if got := formatTimeRemaining(nil); got != "unknown" {
-		t.Errorf("formatTimeRemaining(nil) = %q, want %q", got, "unknown")
+	if got := formatTimeRemaining(nil, testNow); got != "unknown" {
+		t.Errorf("formatTimeRemaining(nil, testNow) = %q, want %q", got, "unknown")
 	}
 }
 
 func TestFormatTimeRemaining_Past(t *testing.T) {
-	past := time.Now().Add(-10 * time.Minute)
-	if got := formatTimeRemaining(&past); got != "now" {
+	past := testNow.Add(-10 * time.Minute)
+	if got := formatTimeRemaining(&past, testNow); got != "now" {
 		t.Errorf("formatTimeRemaining(past) = %q, want %q", got, "now")
 	}
 }
 
 func TestFormatTimeRemaining_MinutesOnly(t *testing.T) {
-	future := time.Now().Add(45*time.Minute + 30*time.Second)
-	got := formatTimeRemaining(&future)
+	future := testNow.Add(45*time.Minute + 30*time.Second)
+	got := formatTimeRemaining(&future, testNow)
 	if got != "45m" {
 		t.Errorf("formatTimeRemaining(+45m30s) = %q, want %q", got, "45m")
 	}
 }
 
 func TestFormatTimeRemaining_HoursAndMinutes(t *testing.T) {
-	future := time.Now().Add(2*time.Hour + 15*time.Minute + 10*time.Second)
-	got := formatTimeRemaining(&future)
+	future := testNow.Add(2*time.Hour + 15*time.Minute + 10*time.Second)
+	got := formatTimeRemaining(&future, testNow)
 	if got != "2h 15m" {
 		t.Errorf("formatTimeRemaining(+2h15m) = %q, want %q", got, "2h 15m")
 	}
@@ -51,55 +59,102 @@ func TestFormatResetDate_Format(t *testing.T) {
 }
 
 func TestFormatUpdatedAgo_Nil(t *testing.T) {
-	if got := formatUpdatedAgo(nil); got != "Updated: --" {
-		t.Errorf("formatUpdatedAgo(nil) = %q, want %q", got, "Updated: --")
+	if got := formatUpdatedAgo(nil, testNow); got != "Updated: --" {
+		t.Errorf("formatUpdatedAgo(nil, testNow) = %q, want %q", got, "Updated: --")
 	}
 }
 
 func TestFormatUpdatedAgo_Seconds(t *testing.T) {
-	ts := time.Now().Add(-30 * time.Second)
-	got := formatUpdatedAgo(&ts)
-	// Should be ~30s, allow some slack.
-	if got != "Updated: 30s ago" && got != "Updated: 31s ago" {
+	ts := testNow.Add(-30 * time.Second)
+	got := formatUpdatedAgo(&ts, testNow)
+	if got != "Updated: 30s ago" {
 		t.Errorf("formatUpdatedAgo(-30s) = %q", got)
 	}
 }
 
 func TestFormatUpdatedAgo_Minutes(t *testing.T) {
-	ts := time.Now().Add(-3*time.Minute - 12*time.Second)
-	got := formatUpdatedAgo(&ts)
-	if got != "Updated: 3m 12s ago" && got != "Updated: 3m 13s ago" {
+	ts := testNow.Add(-3*time.Minute - 12*time.Second)
+	got := formatUpdatedAgo(&ts, testNow)
+	if got != "Updated: 3m 12s ago" {
 		t.Errorf("formatUpdatedAgo(-3m12s) = %q", got)
 	}
 }
 
 func TestFormatUpdatedAgo_Hours(t *testing.T) {
-	ts := time.Now().Add(-1*time.Hour - 5*time.Minute)
-	got := formatUpdatedAgo(&ts)
+	ts := testNow.Add(-1*time.Hour - 5*time.Minute)
+	got := formatUpdatedAgo(&ts, testNow)
 	if got != "Updated: 1h 5m ago" {
 		t.Errorf("formatUpdatedAgo(-1h5m) = %q", got)
 	}
 }
 
 func TestFormatUpdatedAgo_Future(t *testing.T) {
-	ts := time.Now().Add(10 * time.Second)
-	got := formatUpdatedAgo(&ts)
+	ts := testNow.Add(10 * time.Second)
+	got := formatUpdatedAgo(&ts, testNow)
 	// Negative duration is clamped to 0.
 	if got != "Updated: 0s ago" {
 		t.Errorf("formatUpdatedAgo(future) = %q, want %q", got, "Updated: 0s ago")
 	}
 }
 
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d           time.Duration
+		showSeconds bool
+		want        string
+	}{
+		{0, false, "0m"},
+		{0, true, "0s"},
+		{-5 * time.Minute, false, "0m"},
+		{-5 * time.Second, true, "0s"},
+		{59 * time.Second, false, "0m"},
+		{59 * time.Second, true, "59s"},
+		{60 * time.Second, true, "1m 0s"},
+		{45*time.Minute + 30*time.Second, false, "45m"},
+		{45*time.Minute + 30*time.Second, true, "45m 30s"},
+		{59*time.Minute + 59*time.Second, true, "59m 59s"},
+		{time.Hour, false, "1h 0m"},
+		{time.Hour, true, "1h 0m"},
+		{2*time.Hour + 15*time.Minute + 30*time.Second, true, "2h 15m"},
+		{49 * time.Hour, false, "49h 0m"},
+	}
+	for _, tc := range tests {
+		if got := formatDuration(tc.d, tc.showSeconds); got != tc.want {
+			t.Errorf("formatDuration(%v, %v) = %q, want %q", tc.d, tc.showSeconds, got, tc.want)
+		}
+	}
+}
+
+func TestShowSaturation(t *testing.T) {
+	sat := testNow.Add(2 * time.Hour)
+	tests := []struct {
+		name string
+		sat  *time.Time
+		max  time.Duration
+		want bool
+	}{
+		{"nil", nil, 0, false},
+		{"no limit", &sat, 0, true},
+		{"within limit", &sat, 2 * time.Hour, true},
+		{"beyond limit", &sat, time.Hour, false},
+	}
+	for _, tc := range tests {
+		if got := showSaturation(tc.sat, testNow, tc.max); got != tc.want {
+			t.Errorf("%s: showSaturation = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
 func TestFormatSaturationLine_Nil(t *testing.T) {
-	got := formatSaturationLine(nil)
+	got := formatSaturationLine(nil, testNow)
 	if got != "" {
-		t.Errorf("formatSaturationLine(nil) = %q, want %q", got, "")
+		t.Errorf("formatSaturationLine(nil, testNow) = %q, want %q", got, "")
 	}
 }
 
 func TestFormatSaturationLine_Future(t *testing.T) {
-	sat := time.Now().Add(1*time.Hour + 15*time.Minute + 30*time.Second)
-	got := formatSaturationLine(&sat)
+	sat := testNow.Add(1*time.Hour + 15*time.Minute + 30*time.Second)
+	got := formatSaturationLine(&sat, testNow)
 	date := formatResetDate(&sat)
 	expect := "  - saturates in 1h 15m, " + date
 	if got != expect {
@@ -108,15 +163,15 @@ func TestFormatSaturationLine_Future(t *testing.T) {
 }
 
 func TestFormatQuotaLine_NilUtilization(t *testing.T) {
-	got := formatQuotaLine("5h", nil, nil)
+	got := formatQuotaLine("5h", nil, nil, testNow, "both")
 	if got != "5h: --" {
 		t.Errorf("formatQuotaLine(nil) = %q, want %q", got, "5h: --")
 	}
 }
 
 func TestFormatQuotaLine_WithUtilization_NoResets(t *testing.T) {
-	v := 42.0
-	got := formatQuotaLine("7d", &v, nil)
+	v := Utilization(42)
+	got := formatQuotaLine("7d", &v, nil, testNow, "both")
 	// No reset date => no parens, but formatTimeRemaining returns "unknown".
 	// Since formatResetDate(nil) == "", it uses the short format.
 	if got != "7d: 42%" {
@@ -125,10 +180,9 @@ func TestFormatQuotaLine_WithUtilization_NoResets(t *testing.T) {
 }
 
 func TestFormatQuotaLine_WithUtilization_WithResets(t *testing.T) {
-	v := 73.0
-	// Add extra seconds to avoid rounding down across the minute boundary.
-	resets := time.Now().Add(2*time.Hour + 30*time.Minute + 30*time.Second)
-	got := formatQuotaLine("5h", &v, &resets)
+	v := Utilization(73)
+	resets := testNow.Add(2*time.Hour + 30*time.Minute + 30*time.Second)
+	got := formatQuotaLine("5h", &v, &resets, testNow, "both")
 	date := formatResetDate(&resets)
 	expect := "5h: 73% (resets in 2h 30m, " + date + ")"
 	if got != expect {
@@ -136,17 +190,201 @@ func TestFormatQuotaLine_WithUtilization_WithResets(t *testing.T) {
 	}
 }
 
+func TestFormatQuotaLine_TimeDisplay(t *testing.T) {
+	v := Utilization(73)
+	resets := testNow.Add(2*time.Hour + 30*time.Minute + 30*time.Second)
+	date := formatResetDate(&resets)
+	tests := []struct {
+		timeDisplay string
+		resets      *time.Time
+		want        string
+	}{
+		{"relative", &resets, "5h: 73% (resets in 2h 30m)"},
+		{"absolute", &resets, "5h: 73% (resets " + date + ")"},
+		{"both", &resets, "5h: 73% (resets in 2h 30m, " + date + ")"},
+		{"relative", nil, "5h: 73%"},
+		{"absolute", nil, "5h: 73%"},
+		{"both", nil, "5h: 73%"},
+	}
+	for _, tt := range tests {
+		if got := formatQuotaLine("5h", &v, tt.resets, testNow, tt.timeDisplay); got != tt.want {
+			t.Errorf("formatQuotaLine(%s, resets=%v) = %q, want %q", tt.timeDisplay, tt.resets != nil, got, tt.want)
+		}
+	}
+}
+
 func TestFormatProjectionLine_Nil(t *testing.T) {
-	got := formatProjectionLine(nil)
+	got := formatProjectionLine(nil, nil, nil)
 	if got != "" {
 		t.Errorf("formatProjectionLine(nil) = %q, want %q", got, "")
 	}
 }
 
 func TestFormatProjectionLine_Value(t *testing.T) {
-	proj := 35.7
-	got := formatProjectionLine(&proj)
+	proj := Utilization(35.7)
+	got := formatProjectionLine(&proj, nil, nil)
 	if got != "  - projected ~36% at reset" {
 		t.Errorf("formatProjectionLine(35.7) = %q, want %q", got, "  - projected ~36% at reset")
 	}
 }
+
+func TestFormatProjectionLine_Interval(t *testing.T) {
+	proj, low, high := Utilization(56), Utilization(50.4), Utilization(61.6)
+	got := formatProjectionLine(&proj, &low, &high)
+	if got != "  - projected ~56% at reset (±10%)" {
+		t.Errorf("formatProjectionLine = %q, want %q", got, "  - projected ~56% at reset (±10%)")
+	}
+}
+
+func TestFormatTokenExpiry_Nil(t *testing.T) {
+	if got := formatTokenExpiry(nil, testNow); got != "" {
+		t.Errorf("formatTokenExpiry(nil, testNow) = %q, want empty", got)
+	}
+}
+
+func TestFormatTokenExpiry_WithinWindow(t *testing.T) {
+	ts := testNow.Add(2*time.Hour + 5*time.Minute + 10*time.Second)
+	got := formatTokenExpiry(&ts, testNow)
+	if got != "(token expires in 2h 5m)" {
+		t.Errorf("formatTokenExpiry(+2h5m) = %q, want %q", got, "(token expires in 2h 5m)")
+	}
+}
+
+func TestFormatTokenExpiry_FarFuture(t *testing.T) {
+	ts := testNow.Add(48 * time.Hour)
+	if got := formatTokenExpiry(&ts, testNow); got != "" {
+		t.Errorf("formatTokenExpiry(+48h) = %q, want empty", got)
+	}
+}
+
+func TestFormatTokenExpiry_Past(t *testing.T) {
+	ts := testNow.Add(-time.Minute)
+	if got := formatTokenExpiry(&ts, testNow); got != "" {
+		t.Errorf("formatTokenExpiry(past) = %q, want empty", got)
+	}
+}
+
+func TestFormatProgressBar(t *testing.T) {
+	tests := []struct {
+		value, max float64
+		want       string
+	}{
+		{42, 100, "[████░░░░░░] 42%"},
+		{0, 100, "[░░░░░░░░░░] 0%"},
+		{45, 100, "[████▒░░░░░] 45%"},
+		{47.5, 100, "[████▓░░░░░] 48%"},
+		{100, 100, "[██████████] 100%"},
+		{150, 100, "[██████████] 150%"},
+		{-5, 100, "[░░░░░░░░░░] -5%"},
+		{3, 4, "[███████▒░░] 75%"},
+		{10, 0, "[░░░░░░░░░░] 0%"},
+	}
+	for _, tc := range tests {
+		got := formatProgressBar(tc.value, tc.max, 10)
+		if got != tc.want {
+			t.Errorf("formatProgressBar(%v, %v, 10) = %q, want %q", tc.value, tc.max, got, tc.want)
+		}
+		bar := got[:strings.Index(got, "]")+1]
+		if n := utf8.RuneCountInString(bar); n != 12 {
+			t.Errorf("formatProgressBar(%v, %v, 10) bar has %d runes, want 12", tc.value, tc.max, n)
+		}
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	v5 := Utilization(25)
+	p5 := Utilization(120)
+	v7 := Utilization(75)
+	resets := time.Now().Add(2*time.Hour + 30*time.Minute + 30*time.Second)
+	sat := time.Now().Add(45*time.Minute + 30*time.Second)
+	state := QuotaState{
+		FiveHour:           &v5,
+		FiveHourProjected:  &p5,
+		FiveHourResets:     &resets,
+		FiveHourSaturation: &sat,
+		SevenDay:           &v7,
+	}
+
+	var buf bytes.Buffer
+	PrintSummary(state, &buf)
+	out := buf.String()
+
+	for _, want := range []string{"WINDOW", "5h", "25%", "~120%", "2h 30m", "45m", "7d", "75%", "Sonnet 7d"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+
+	lines := strings.Split(out, "\n")
+	fiveHourBar := strings.Count(lines[1], "█")
+	sevenDayBar := strings.Count(lines[2], "█")
+	if fiveHourBar != summaryBarWidth/4 {
+		t.Errorf("5h bar has %d cells, want %d", fiveHourBar, summaryBarWidth/4)
+	}
+	if sevenDayBar != summaryBarWidth*3/4 {
+		t.Errorf("7d bar has %d cells, want %d", sevenDayBar, summaryBarWidth*3/4)
+	}
+	if strings.Count(lines[3], "█") != 0 {
+		t.Errorf("Sonnet bar should be empty without data: %q", lines[3])
+	}
+}
+
+func TestPrintSummary_Error(t *testing.T) {
+	var buf bytes.Buffer
+	PrintSummary(QuotaState{Error: "HTTP 500"}, &buf)
+	if buf.String() != "Error: HTTP 500\n" {
+		t.Errorf("PrintSummary(error) = %q", buf.String())
+	}
+}
+
+func TestFormatFetchStats(t *testing.T) {
+	got := formatFetchStats(QuotaStats{FetchCount: 42, FetchErrorCount: 2})
+	if got != "Fetches: 42 (2 errors)" {
+		t.Errorf("formatFetchStats = %q, want %q", got, "Fetches: 42 (2 errors)")
+	}
+}
+
+func TestFormatTitle(t *testing.T) {
+	v5, v7, vs, proj := Utilization(42.4), Utilization(17.6), Utilization(3), Utilization(88)
+	state := QuotaState{
+		FiveHour:          &v5,
+		SevenDay:          &v7,
+		SevenDaySonnet:    &vs,
+		FiveHourProjected: &proj,
+	}
+	tests := []struct {
+		tmpl  string
+		state QuotaState
+		want  string
+	}{
+		{"", state, ""},
+		{"{{.FiveHour}}%", state, "42%"},
+		{"{{.SevenDay}}%", state, "18%"},
+		{"{{.SevenDaySonnet}}%", state, "3%"},
+		{"{{.Projected}}%", state, "88%"},
+		{"{{.FiveHour}}/{{.SevenDay}}", QuotaState{}, "/"},
+		{"{{if .Error}}!{{else}}{{.FiveHour}}%{{end}}", QuotaState{Error: "boom"}, "!"},
+		{"{{.Error}}", QuotaState{Error: "boom"}, "boom"},
+	}
+	for _, tt := range tests {
+		if got := formatTitle(parseTitleFormat(tt.tmpl), tt.state); got != tt.want {
+			t.Errorf("formatTitle(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTitle_Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, tmpl := range []string{"{{.FiveHour", "{{.Unknown}}"} {
+		buf.Reset()
+		if got := parseTitleFormat(tmpl); got != nil {
+			t.Errorf("parseTitleFormat(%q) = %v, want nil", tmpl, got)
+		}
+		if !strings.Contains(buf.String(), "Invalid title_format") {
+			t.Errorf("parseTitleFormat(%q) logged %q, want a warning", tmpl, buf.String())
+		}
+	}
+}
@@ -8,9 +8,13 @@ require (
 	fyne.io/systray v1.12.0
 	github.com/fogleman/gg v1.3.0
 	github.com/minio/selfupdate v0.6.0
+	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/ulikunitz/xz v0.5.15
 	golang.org/x/image v0.35.0
 	golang.org/x/mod v0.31.0
+	golang.org/x/net v0.38.0
+	golang.org/x/sys v0.37.0
+	modernc.org/sqlite v1.46.1
 )
 
 require (
@@ -22,12 +26,10 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/ncruces/go-strftime v1.0.0 // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
-	golang.org/x/crypto v0.0.0-20211209193657-4570a0811e8b // indirect
+	golang.org/x/crypto v0.36.0 // indirect
 	golang.org/x/exp v0.0.0-20251023183803-a4bb9ffd2546 // indirect
-	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.33.0 // indirect
 	modernc.org/libc v1.67.6 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
 	modernc.org/memory v1.11.0 // indirect
-	modernc.org/sqlite v1.46.1 // indirect
 )
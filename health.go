@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// healthResponse is the JSON body served on /health.
+type healthResponse struct {
+	Status                   string  `json:"status"`                      // "ok" or "degraded"
+	LastFetchAgeSeconds      int64   `json:"last_fetch_age_seconds"`      // -1 before the first successful fetch
+	LastFetchDurationSeconds float64 `json:"last_fetch_duration_seconds"` // 0 before the first fetch
+	Error                    string  `json:"error"`
+}
+
+// HealthHandler reports "ok" with HTTP 200 while the last successful fetch is
+// at most two poll intervals old and the state holds no error, otherwise
+// "degraded" with HTTP 503. fetchDuration reports the latency of the last fetch.
+func HealthHandler(state func() QuotaState, fetchDuration func() time.Duration, pollInterval time.Duration, now func() time.Time) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s := state()
+		resp := healthResponse{
+			Status:                   "ok",
+			LastFetchAgeSeconds:      -1,
+			LastFetchDurationSeconds: fetchDuration().Seconds(),
+			Error:                    s.Error,
+		}
+		stale := true
+		if s.LastUpdate != nil {
+			age := now().Sub(*s.LastUpdate)
+			resp.LastFetchAgeSeconds = int64(age / time.Second)
+			stale = age > 2*pollInterval
+		}
+
+		code := http.StatusOK
+		if stale || s.Error != "" {
+			resp.Status = "degraded"
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// startHealthServer serves HealthHandler on addr at /health in the background.
+func startHealthServer(addr string, state func() QuotaState, fetchDuration func() time.Duration, pollInterval time.Duration) {
+	mux := http.NewServeMux()
+	mux.Handle("/health", HealthHandler(state, fetchDuration, pollInterval, time.Now))
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("Healthcheck server failed", "addr", addr, "error", err)
+		}
+	}()
+}
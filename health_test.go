@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getHealth(t *testing.T, state QuotaState) (int, healthResponse) {
+	t.Helper()
+	mux := http.NewServeMux()
+	fetchDuration := func() time.Duration { return 250 * time.Millisecond }
+	mux.Handle("/health", HealthHandler(func() QuotaState { return state }, fetchDuration, 5*time.Minute, fixedClock(testNow)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body healthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestHealthHandler_OK(t *testing.T) {
+	last := testNow.Add(-42 * time.Second)
+	code, body := getHealth(t, QuotaState{LastUpdate: &last})
+	if code != http.StatusOK {
+		t.Errorf("status code = %d, want 200", code)
+	}
+	want := healthResponse{Status: "ok", LastFetchAgeSeconds: 42, LastFetchDurationSeconds: 0.25}
+	if body != want {
+		t.Errorf("body = %+v, want %+v", body, want)
+	}
+}
+
+func TestHealthHandler_Degraded(t *testing.T) {
+	recent := testNow.Add(-time.Minute)
+	stale := testNow.Add(-11 * time.Minute)
+	tests := []struct {
+		name  string
+		state QuotaState
+		age   int64
+	}{
+		{"error", QuotaState{Error: "HTTP 500", LastUpdate: &recent}, 60},
+		{"stale", QuotaState{LastUpdate: &stale}, 660},
+		{"never fetched", QuotaState{}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := getHealth(t, tt.state)
+			if code != http.StatusServiceUnavailable {
+				t.Errorf("status code = %d, want 503", code)
+			}
+			if body.Status != "degraded" || body.LastFetchAgeSeconds != tt.age || body.Error != tt.state.Error {
+				t.Errorf("body = %+v", body)
+			}
+		})
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"image/color"
 	"image/png"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/fogleman/gg"
@@ -29,22 +32,83 @@ var builtinFonts = map[string][]byte{
 	"monobold": gomonobold.TTF,
 }
 
+// fontNames lists all accepted font names: the built-in TTF fonts plus "bitmap".
+var fontNames = []string{"bold", "regular", "mono", "monobold", "bitmap"}
+
+// indicatorNames lists all known indicator types.
+var indicatorNames = []string{"pie", "bar", "arc", "bar-proj", "bar-dual", "ring-dual", "text"}
+
+// colorSources lists all known icon color sources.
+var colorSources = []string{"current", "projected"}
+
+// iconWindows lists the quota windows the icon can show.
+var iconWindows = []string{"5h", "7d"}
+
+// textPositions lists the vertical positions of the icon text.
+var textPositions = []string{"center", "top", "bottom"}
+
 // ValidFontName returns true if the name is a known built-in font.
 func ValidFontName(name string) bool {
-	if name == "bitmap" {
-		return true
-	}
-	_, ok := builtinFonts[name]
-	return ok
+	return slices.Contains(fontNames, name)
 }
 
 // ValidIndicatorName returns true if the name is a known indicator type.
 func ValidIndicatorName(name string) bool {
-	switch name {
-	case "pie", "bar", "arc", "bar-proj":
-		return true
+	return slices.Contains(indicatorNames, name)
+}
+
+// ValidColorSource returns true if the name is a known icon color source.
+func ValidColorSource(name string) bool {
+	return slices.Contains(colorSources, name)
+}
+
+// ValidTextPosition returns true if the name is a known icon text position.
+func ValidTextPosition(name string) bool {
+	return slices.Contains(textPositions, name)
+}
+
+// textY returns the vertical center of the icon text for position: a
+// quarter of the way down for "top", three quarters for "bottom", and the
+// middle of the icon otherwise.
+func textY(iconSize int, position string) float64 {
+	switch position {
+	case "top":
+		return float64(iconSize) * 0.25
+	case "bottom":
+		return float64(iconSize) * 0.75
+	}
+	return float64(iconSize) / 2
+}
+
+// parseOptionalColor parses a "#rrggbb" or "#rrggbbaa" hex color. The empty
+// string yields ok=false and no error, meaning "no color".
+func parseOptionalColor(s string) (c color.RGBA, ok bool, err error) {
+	if s == "" {
+		return color.RGBA{}, false, nil
+	}
+	hexStr, found := strings.CutPrefix(s, "#")
+	if !found || (len(hexStr) != 6 && len(hexStr) != 8) {
+		return color.RGBA{}, false, fmt.Errorf("invalid color %q: want #rrggbb or #rrggbbaa", s)
 	}
-	return false
+	if len(hexStr) == 6 {
+		hexStr += "ff"
+	}
+	v, err := strconv.ParseUint(hexStr, 16, 32)
+	if err != nil {
+		return color.RGBA{}, false, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+	return color.RGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, true, nil
+}
+
+// ValidColor returns true if s is empty or a valid parseOptionalColor color.
+func ValidColor(s string) bool {
+	_, _, err := parseOptionalColor(s)
+	return err == nil
+}
+
+// ValidIconWindow returns true if the name is a known icon window.
+func ValidIconWindow(name string) bool {
+	return slices.Contains(iconWindows, name)
 }
 
 // TTF font cache: parsed once per font name, faces cached per size.
@@ -100,11 +164,11 @@ func loadTTFFace(fontName string, size float64) (font.Face, error) {
 }
 
 // colorForUtilization returns the appropriate color based on utilization and thresholds.
-func colorForUtilization(utilization *float64, thresholds Thresholds) color.RGBA {
+func colorForUtilization(utilization *Utilization, thresholds Thresholds) color.RGBA {
 	if utilization == nil {
 		return color.RGBA{128, 128, 128, 255} // Gray
 	}
-	v := *utilization
+	v := float64(*utilization)
 	if v >= thresholds.Critical {
 		return color.RGBA{220, 53, 69, 255} // Red
 	}
@@ -114,9 +178,37 @@ func colorForUtilization(utilization *float64, thresholds Thresholds) color.RGBA
 	return color.RGBA{40, 167, 69, 255} // Green
 }
 
+// resolveIconUtilization returns the utilization the icon shows: the 7d window
+// for "7d", the 5h window otherwise.
+func resolveIconUtilization(state QuotaState, window string) *Utilization {
+	if window == "7d" {
+		return state.SevenDay
+	}
+	return state.FiveHour
+}
+
+// resolveIconProjection is like resolveIconUtilization for the projection at reset.
+func resolveIconProjection(state QuotaState, window string) *Utilization {
+	if window == "7d" {
+		return state.SevenDayProjected
+	}
+	return state.FiveHourProjected
+}
+
+// resolveColorSource returns the utilization value that drives the icon color.
+// "projected" uses the icon window's projection at reset, falling back to the
+// current value when no projection is available; anything else uses the
+// current value.
+func resolveColorSource(state QuotaState, src, window string) *Utilization {
+	if projected := resolveIconProjection(state, window); src == "projected" && projected != nil {
+		return projected
+	}
+	return resolveIconUtilization(state, window)
+}
+
 // clampFrac converts a percentage (0–100+) to a fraction clamped to [0, 1].
-func clampFrac(pct float64) float64 {
-	f := pct / 100.0
+func clampFrac(pct Utilization) float64 {
+	f := pct.Fraction()
 	if f < 0 {
 		return 0
 	}
@@ -128,22 +220,86 @@ func clampFrac(pct float64) float64 {
 
 // RenderOptions holds rendering configuration for icon generation.
 type RenderOptions struct {
-	FontSize  float64
-	IconSize  int
-	FontName  string
-	HaloSize  float64
-	Indicator string
-	ShowText  bool
+	FontSize     float64
+	IconSize     int
+	FontName     string
+	HaloSize     float64
+	Indicator    string
+	ShowText     bool
+	ColorSource  string
+	Padding      float64    // outer margin in pixels, relative to a 64px icon
+	ShapeScale   float64    // fraction of the padded radius used by pie and arc, 0.1-1
+	IconWindow   string     // quota window shown: "5h" (default) or "7d"
+	Background   color.RGBA // fill behind the indicator; zero value keeps it transparent
+	Opacity      float64    // alpha multiplier for the whole icon, 0-1
+	BarRadius    float64    // corner radius of the bar indicator, relative to a 64px icon
+	RingGap      float64    // gap between the ring-dual rings, relative to a 64px icon
+	TextPosition string     // vertical text position: "center" (default), "top" or "bottom"
+}
+
+// RenderOptionsFromConfig maps the rendering fields of cfg to RenderOptions.
+// New Config fields that affect the icon belong here.
+func RenderOptionsFromConfig(cfg Config) RenderOptions {
+	return RenderOptions{
+		FontSize:     cfg.FontSize,
+		IconSize:     cfg.IconSize,
+		FontName:     cfg.FontName,
+		HaloSize:     cfg.HaloSize,
+		Indicator:    cfg.Indicator,
+		ShowText:     configShowText(cfg),
+		ColorSource:  cfg.ColorSource,
+		IconWindow:   cfg.IconWindow,
+		Padding:      cfg.Padding,
+		ShapeScale:   cfg.IndicatorSizeRatio,
+		Background:   configIconBackground(cfg),
+		Opacity:      cfg.IconOpacity,
+		BarRadius:    cfg.BarCornerRadius,
+		RingGap:      cfg.RingGap,
+		TextPosition: cfg.TextPosition,
+	}
 }
 
 // drawParams holds shared, pre-scaled rendering parameters for internal draw functions.
 type drawParams struct {
-	fontSize float64 // FontSize * scale
-	iconSize int
-	s        float64 // scale factor (iconSize / 64)
-	fontName string
-	haloSize float64 // HaloSize * scale
-	showText bool
+	fontSize     float64 // FontSize * scale
+	iconSize     int
+	s            float64 // scale factor (iconSize / 64)
+	fontName     string
+	haloSize     float64 // HaloSize * scale
+	showText     bool
+	padding      float64 // Padding, unscaled (multiply by s)
+	shapeScale   float64 // pie and arc radius multiplier
+	barRadius    float64 // BarRadius, unscaled (multiply by s)
+	ringGap      float64 // RingGap, unscaled (multiply by s)
+	textPosition string  // see textY
+}
+
+// defaultIconPadding is the outer margin the indicators were designed around.
+const defaultIconPadding = 4.0
+
+// Bounds for RenderOptions.ShapeScale (config indicator_size_ratio).
+const (
+	minShapeScale = 0.1
+	maxShapeScale = 1.0
+)
+
+// maxIconPadding bounds the padding so indicators keep a usable size.
+const maxIconPadding = 16.0
+
+// maxBarCornerRadius bounds bar_corner_radius to a pill shape at 64px.
+const maxBarCornerRadius = 32.0
+
+// Default and bound for RenderOptions.RingGap (config ring_gap).
+const (
+	defaultRingGap = 2.0
+	maxRingGap     = 8.0
+)
+
+// barInset returns how much further in than the edge the bar indicators are
+// drawn. Bars already reach the edge at the default padding, so smaller
+// paddings leave them unchanged.
+func (p drawParams) barInset() float64 {
+	return math.Max(p.padding-defaultIconPadding, 0) * p.s
 }
 
 // renderIcon creates an RGBA icon image of the given size based on the quota state.
@@ -151,18 +307,27 @@ func renderIcon(state QuotaState, thresholds Thresholds, opts RenderOptions) ima
 	dc := gg.NewContext(opts.IconSize, opts.IconSize)
 	dc.SetColor(color.RGBA{0, 0, 0, 0})
 	dc.Clear()
+	if opts.Background.A != 0 {
+		dc.SetColor(opts.Background)
+		dc.Clear()
+	}
 
-	utilization := state.FiveHour
-	col := colorForUtilization(utilization, thresholds)
+	utilization := resolveIconUtilization(state, opts.IconWindow)
+	col := colorForUtilization(resolveColorSource(state, opts.ColorSource, opts.IconWindow), thresholds)
 
 	s := float64(opts.IconSize) / 64.0 // scale factor relative to base size 64
 	p := drawParams{
-		fontSize: opts.FontSize * s,
-		iconSize: opts.IconSize,
-		s:        s,
-		fontName: opts.FontName,
-		haloSize: opts.HaloSize * s,
-		showText: opts.ShowText,
+		fontSize:     opts.FontSize * s,
+		iconSize:     opts.IconSize,
+		s:            s,
+		fontName:     opts.FontName,
+		haloSize:     opts.HaloSize * s,
+		showText:     opts.ShowText,
+		padding:      opts.Padding,
+		shapeScale:   opts.ShapeScale,
+		barRadius:    opts.BarRadius,
+		ringGap:      opts.RingGap,
+		textPosition: opts.TextPosition,
 	}
 
 	if state.TokenExpired {
@@ -174,29 +339,53 @@ func renderIcon(state QuotaState, thresholds Thresholds, opts RenderOptions) ima
 		case "bar":
 			drawBarIcon(dc, utilization, col, p)
 		case "bar-proj":
-			var projected *float64
+			projected := resolveIconProjection(state, opts.IconWindow)
 			var projCol color.RGBA
-			if state.FiveHourProjected != nil {
-				projected = state.FiveHourProjected
+			if projected != nil {
 				projCol = mutedColor(colorForUtilization(projected, thresholds))
 			}
 			drawBarProjIcon(dc, utilization, col, projected, projCol, p)
+		case "bar-dual":
+			drawBarDualIcon(dc, state.FiveHour, colorForUtilization(state.FiveHour, thresholds),
+				state.SevenDay, colorForUtilization(state.SevenDay, thresholds), p)
 		case "arc":
 			drawArcIcon(dc, utilization, col, p)
+		case "ring-dual":
+			drawRingDualIcon(dc, state.FiveHour, colorForUtilization(state.FiveHour, thresholds),
+				state.SevenDay, colorForUtilization(state.SevenDay, thresholds), p)
+		case "text":
+			drawTextOnlyIcon(dc, utilization, col, p)
 		default:
 			drawNormalIcon(dc, utilization, col, p)
 		}
-		drawUtilizationText(dc, utilization, p)
+		if opts.Indicator != "text" {
+			drawUtilizationText(dc, utilization, p)
+		}
 	}
 
-	return dc.Image()
+	img := dc.Image()
+	if rgba, ok := img.(*image.RGBA); ok && opts.Opacity < 1 {
+		return applyOpacity(rgba, opts.Opacity)
+	}
+	return img
+}
+
+// applyOpacity scales the alpha of every pixel of img by opacity (0-1), in
+// place, and returns img. Since image.RGBA is alpha-premultiplied, the color
+// channels are scaled too.
+func applyOpacity(img *image.RGBA, opacity float64) *image.RGBA {
+	opacity = math.Max(0, math.Min(1, opacity))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(math.Round(float64(img.Pix[i]) * opacity))
+	}
+	return img
 }
 
 // drawExpiredIcon draws an amber warning triangle with "!" for token expiry.
 func drawExpiredIcon(dc *gg.Context, p drawParams) {
 	amber := color.RGBA{255, 193, 7, 255}
 	center := float64(p.iconSize) / 2
-	margin := 4.0 * p.s
+	margin := p.padding * p.s
 
 	// Triangle vertices: top-center, bottom-left, bottom-right
 	topX, topY := center, margin
@@ -219,7 +408,7 @@ func drawExpiredIcon(dc *gg.Context, p drawParams) {
 // drawErrorIcon draws a gray circle with a red X.
 func drawErrorIcon(dc *gg.Context, p drawParams) {
 	center := float64(p.iconSize) / 2
-	radius := float64(p.iconSize)/2 - 4*p.s
+	radius := float64(p.iconSize)/2 - p.padding*p.s
 
 	// Gray filled circle
 	dc.SetColor(color.RGBA{80, 80, 80, 255})
@@ -227,7 +416,7 @@ func drawErrorIcon(dc *gg.Context, p drawParams) {
 	dc.Fill()
 
 	// Red X
-	xOff := 16.0 * p.s
+	xOff := (p.padding + 12) * p.s
 	dc.SetColor(color.RGBA{220, 53, 69, 255})
 	dc.SetLineWidth(6 * p.s)
 	dc.DrawLine(xOff, xOff, float64(p.iconSize)-xOff, float64(p.iconSize)-xOff)
@@ -237,9 +426,9 @@ func drawErrorIcon(dc *gg.Context, p drawParams) {
 }
 
 // drawNormalIcon draws the ring outline, pie slice, and text.
-func drawNormalIcon(dc *gg.Context, utilization *float64, col color.RGBA, p drawParams) {
+func drawNormalIcon(dc *gg.Context, utilization *Utilization, col color.RGBA, p drawParams) {
 	center := float64(p.iconSize) / 2
-	outerRadius := float64(p.iconSize)/2 - 4*p.s
+	outerRadius := (float64(p.iconSize)/2 - p.padding*p.s) * p.shapeScale
 
 	// Outer ring
 	dc.SetColor(col)
@@ -254,7 +443,7 @@ func drawNormalIcon(dc *gg.Context, utilization *float64, col color.RGBA, p draw
 	// Pie slice
 	extent := clampFrac(*utilization) * 2 * math.Pi
 	if extent > 0 {
-		pieRadius := float64(p.iconSize)/2 - 8*p.s
+		pieRadius := outerRadius - 4*p.s*p.shapeScale
 		startAngle := -math.Pi / 2 // top
 		endAngle := startAngle + extent
 
@@ -268,14 +457,20 @@ func drawNormalIcon(dc *gg.Context, utilization *float64, col color.RGBA, p draw
 }
 
 // drawBarIcon draws a vertical filling bar indicator (bottom to top).
-func drawBarIcon(dc *gg.Context, utilization *float64, col color.RGBA, p drawParams) {
+func drawBarIcon(dc *gg.Context, utilization *Utilization, col color.RGBA, p drawParams) {
 	border := 2 * p.s
 	size := float64(p.iconSize)
+	inset := p.barInset()
 
 	// Border rectangle
+	radius := p.barRadius * p.s
 	dc.SetColor(col)
 	dc.SetLineWidth(border)
-	dc.DrawRectangle(border/2, border/2, size-border, size-border)
+	if radius > 0 {
+		dc.DrawRoundedRectangle(inset+border/2, inset+border/2, size-2*inset-border, size-2*inset-border, radius)
+	} else {
+		dc.DrawRectangle(inset+border/2, inset+border/2, size-2*inset-border, size-2*inset-border)
+	}
 	dc.Stroke()
 
 	if utilization == nil {
@@ -283,23 +478,43 @@ func drawBarIcon(dc *gg.Context, utilization *float64, col color.RGBA, p drawPar
 	}
 
 	// Filled portion from bottom
-	innerMargin := border + p.s
+	innerMargin := inset + border + p.s
 	innerW := size - 2*innerMargin
 	innerH := size - 2*innerMargin
 	fillH := innerH * clampFrac(*utilization)
 
 	if fillH > 0 {
+		// Keep the fill's top corners concentric with the border's.
+		innerRadius := math.Max(radius-(innerMargin-inset-border/2), 0)
 		dc.SetColor(col)
-		dc.DrawRectangle(innerMargin, innerMargin+innerH-fillH, innerW, fillH)
+		if innerRadius > 0 {
+			drawTopRoundedRectangle(dc, innerMargin, innerMargin+innerH-fillH, innerW, fillH, innerRadius)
+		} else {
+			dc.DrawRectangle(innerMargin, innerMargin+innerH-fillH, innerW, fillH)
+		}
 		dc.Fill()
 	}
 }
 
+// drawTopRoundedRectangle adds a rectangle path whose top corners are rounded
+// with radius r, clamped to fit, and whose bottom corners are square.
+func drawTopRoundedRectangle(dc *gg.Context, x, y, w, h, r float64) {
+	r = math.Min(r, math.Min(w/2, h))
+	dc.NewSubPath()
+	dc.MoveTo(x, y+h)
+	dc.LineTo(x, y+r)
+	dc.DrawArc(x+r, y+r, r, math.Pi, 1.5*math.Pi)
+	dc.LineTo(x+w-r, y)
+	dc.DrawArc(x+w-r, y+r, r, 1.5*math.Pi, 2*math.Pi)
+	dc.LineTo(x+w, y+h)
+	dc.ClosePath()
+}
+
 // drawArcIcon draws a progress ring (thick arc stroke filling clockwise from 12 o'clock).
-func drawArcIcon(dc *gg.Context, utilization *float64, col color.RGBA, p drawParams) {
+func drawArcIcon(dc *gg.Context, utilization *Utilization, col color.RGBA, p drawParams) {
 	center := float64(p.iconSize) / 2
 	strokeWidth := 6 * p.s
-	radius := float64(p.iconSize)/2 - strokeWidth/2 - 2*p.s
+	radius := (float64(p.iconSize)/2 - (p.padding+1)*p.s) * p.shapeScale
 
 	// Background track ring (dim gray)
 	dc.SetColor(color.RGBA{60, 60, 60, 255})
@@ -324,6 +539,60 @@ func drawArcIcon(dc *gg.Context, utilization *float64, col color.RGBA, p drawPar
 	}
 }
 
+// drawRingDualIcon draws two thin concentric progress rings: 5h outside,
+// where its faster-changing value is most visible, and 7d inside, separated
+// by the ring gap. Each ring uses its own threshold color and shows only its
+// track when its utilization is nil.
+func drawRingDualIcon(dc *gg.Context, fiveHour *Utilization, fiveHourCol color.RGBA, sevenDay *Utilization, sevenDayCol color.RGBA, p drawParams) {
+	center := float64(p.iconSize) / 2
+	strokeWidth := 2 * p.s
+	outer := (float64(p.iconSize)/2 - (p.padding+1)*p.s) * p.shapeScale
+	inner := outer - strokeWidth - p.ringGap*p.s
+
+	drawProgressRing(dc, center, outer, strokeWidth, fiveHour, fiveHourCol)
+	if inner > strokeWidth/2 {
+		drawProgressRing(dc, center, inner, strokeWidth, sevenDay, sevenDayCol)
+	}
+}
+
+// drawProgressRing strokes a dim track circle of radius r around (c, c), then
+// the arc for utilization clockwise from 12 o'clock.
+func drawProgressRing(dc *gg.Context, c, r, width float64, utilization *Utilization, col color.RGBA) {
+	dc.SetColor(color.RGBA{60, 60, 60, 255})
+	dc.SetLineWidth(width)
+	dc.DrawCircle(c, c, r)
+	dc.Stroke()
+
+	if utilization == nil {
+		return
+	}
+	extent := clampFrac(*utilization) * 2 * math.Pi
+	if extent > 0 {
+		startAngle := -math.Pi / 2 // 12 o'clock
+		dc.SetColor(col)
+		dc.SetLineWidth(width)
+		dc.DrawArc(c, c, r, startAngle, startAngle+extent)
+		dc.Stroke()
+	}
+}
+
+// textOnlyFontScale enlarges the font for the text indicator, which has no
+// graphic competing for space.
+const textOnlyFontScale = 1.3
+
+// drawTextOnlyIcon draws just the utilization number, in the threshold color,
+// on a transparent background; the halo keeps it readable on any panel.
+// Shows "--" when utilization is unknown. Always drawn, regardless of ShowText.
+func drawTextOnlyIcon(dc *gg.Context, utilization *Utilization, col color.RGBA, p drawParams) {
+	text := "--"
+	if utilization != nil {
+		text = fmt.Sprintf("%d", int(*utilization))
+	}
+	center := float64(p.iconSize) / 2
+	drawCenteredText(dc, text, center, textY(p.iconSize, p.textPosition), p.fontSize*textOnlyFontScale, p.haloSize, p.fontName,
+		col, color.RGBA{0, 0, 0, 255})
+}
+
 // mutedColor returns a desaturated version of the color by blending 50% toward
 // medium gray. This keeps the hue recognizable while being visually distinct
 // from the full-brightness variant, even on dark backgrounds.
@@ -338,22 +607,23 @@ func mutedColor(c color.RGBA) color.RGBA {
 
 // drawBarProjIcon draws two side-by-side vertical bars: left = actual 5h consumption,
 // right = projected 5h consumption at window reset (muted colors).
-func drawBarProjIcon(dc *gg.Context, utilization *float64, col color.RGBA, projected *float64, projCol color.RGBA, p drawParams) {
+func drawBarProjIcon(dc *gg.Context, utilization *Utilization, col color.RGBA, projected *Utilization, projCol color.RGBA, p drawParams) {
 	border := 2 * p.s
 	size := float64(p.iconSize)
+	inset := p.barInset()
 	gap := 1 * p.s
 
 	// Border rectangle
 	dc.SetColor(col)
 	dc.SetLineWidth(border)
-	dc.DrawRectangle(border/2, border/2, size-border, size-border)
+	dc.DrawRectangle(inset+border/2, inset+border/2, size-2*inset-border, size-2*inset-border)
 	dc.Stroke()
 
 	if utilization == nil {
 		return
 	}
 
-	innerMargin := border + p.s
+	innerMargin := inset + border + p.s
 	innerW := size - 2*innerMargin
 	innerH := size - 2*innerMargin
 
@@ -380,15 +650,60 @@ func drawBarProjIcon(dc *gg.Context, utilization *float64, col color.RGBA, proje
 	}
 }
 
-// drawUtilizationText draws the utilization percentage centered on the icon.
-// Called once from renderIcon after the indicator shape has been drawn.
-func drawUtilizationText(dc *gg.Context, utilization *float64, p drawParams) {
+// drawBarDualIcon draws two side-by-side vertical bars: left = 5h utilization,
+// right = 7d utilization, each colored by its own threshold. The border uses the
+// 5h color. Either bar is omitted when its utilization is nil.
+func drawBarDualIcon(dc *gg.Context, fiveHour *Utilization, fiveHourCol color.RGBA, sevenDay *Utilization, sevenDayCol color.RGBA, p drawParams) {
+	border := 2 * p.s
+	size := float64(p.iconSize)
+	inset := p.barInset()
+	gap := 1 * p.s
+
+	// Border rectangle
+	dc.SetColor(fiveHourCol)
+	dc.SetLineWidth(border)
+	dc.DrawRectangle(inset+border/2, inset+border/2, size-2*inset-border, size-2*inset-border)
+	dc.Stroke()
+
+	innerMargin := inset + border + p.s
+	innerW := size - 2*innerMargin
+	innerH := size - 2*innerMargin
+
+	// Column widths: split inner area into two columns with a gap.
+	colW := (innerW - gap) / 2
+
+	// Left bar: 5h utilization.
+	if fiveHour != nil {
+		fillH := innerH * clampFrac(*fiveHour)
+		if fillH > 0 {
+			dc.SetColor(fiveHourCol)
+			dc.DrawRectangle(innerMargin, innerMargin+innerH-fillH, colW, fillH)
+			dc.Fill()
+		}
+	}
+
+	// Right bar: 7d utilization.
+	if sevenDay != nil {
+		fillH := innerH * clampFrac(*sevenDay)
+		if fillH > 0 {
+			rightX := innerMargin + colW + gap
+			dc.SetColor(sevenDayCol)
+			dc.DrawRectangle(rightX, innerMargin+innerH-fillH, colW, fillH)
+			dc.Fill()
+		}
+	}
+}
+
+// drawUtilizationText draws the utilization percentage horizontally centered
+// on the icon, at the height given by textY. Called once from renderIcon
+// after the indicator shape has been drawn.
+func drawUtilizationText(dc *gg.Context, utilization *Utilization, p drawParams) {
 	if !p.showText || utilization == nil {
 		return
 	}
 	center := float64(p.iconSize) / 2
 	text := fmt.Sprintf("%d", int(*utilization))
-	drawCenteredText(dc, text, center, center, p.fontSize, p.haloSize, p.fontName,
+	drawCenteredText(dc, text, center, textY(p.iconSize, p.textPosition), p.fontSize, p.haloSize, p.fontName,
 		color.RGBA{255, 255, 255, 255}, color.RGBA{0, 0, 0, 255})
 }
 
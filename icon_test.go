@@ -1,19 +1,73 @@
 package main
 
 import (
+	"image"
 	"image/color"
+	"reflect"
 	"testing"
 )
 
 // testOpts returns RenderOptions with common test defaults.
 func testOpts() RenderOptions {
 	return RenderOptions{
-		FontSize:  34,
-		IconSize:  64,
-		FontName:  "bold",
-		HaloSize:  2,
-		Indicator: "pie",
-		ShowText:  true,
+		FontSize:   34,
+		IconSize:   64,
+		FontName:   "bold",
+		HaloSize:   2,
+		Indicator:  "pie",
+		ShowText:   true,
+		Padding:    4,
+		ShapeScale: 1,
+		Opacity:    1,
+	}
+}
+
+func TestRenderOptionsFromConfig(t *testing.T) {
+	cfg := defaultConfig()
+	opts := RenderOptionsFromConfig(cfg)
+
+	fields := []struct {
+		name      string
+		got, want any
+	}{
+		{"FontSize", opts.FontSize, cfg.FontSize},
+		{"IconSize", opts.IconSize, cfg.IconSize},
+		{"FontName", opts.FontName, cfg.FontName},
+		{"HaloSize", opts.HaloSize, cfg.HaloSize},
+		{"Indicator", opts.Indicator, cfg.Indicator},
+		{"ShowText", opts.ShowText, true},
+		{"ColorSource", opts.ColorSource, cfg.ColorSource},
+		{"Padding", opts.Padding, cfg.Padding},
+		{"ShapeScale", opts.ShapeScale, cfg.IndicatorSizeRatio},
+		{"IconWindow", opts.IconWindow, cfg.IconWindow},
+		{"Background", opts.Background, color.RGBA{}},
+		{"Opacity", opts.Opacity, cfg.IconOpacity},
+		{"BarRadius", opts.BarRadius, cfg.BarCornerRadius},
+		{"RingGap", opts.RingGap, cfg.RingGap},
+		{"TextPosition", opts.TextPosition, cfg.TextPosition},
+	}
+	if n := reflect.TypeOf(opts).NumField(); n != len(fields) {
+		t.Fatalf("RenderOptions has %d fields, test checks %d", n, len(fields))
+	}
+	for _, f := range fields {
+		if f.got != f.want {
+			t.Errorf("%s = %v, want %v", f.name, f.got, f.want)
+		}
+	}
+}
+
+func TestRenderOptionsFromConfig_Overrides(t *testing.T) {
+	cfg := defaultConfig()
+	hide := false
+	cfg.ShowText = &hide
+	cfg.IconBackgroundColor = "#102030"
+
+	opts := RenderOptionsFromConfig(cfg)
+	if opts.ShowText {
+		t.Error("ShowText = true, want false")
+	}
+	if want := (color.RGBA{0x10, 0x20, 0x30, 0xff}); opts.Background != want {
+		t.Errorf("Background = %v, want %v", opts.Background, want)
 	}
 }
 
@@ -28,7 +82,7 @@ func TestColorForUtilization_Nil(t *testing.T) {
 
 func TestColorForUtilization_Green(t *testing.T) {
 	th := Thresholds{Warning: 60, Critical: 85}
-	v := 30.0
+	v := Utilization(30)
 	got := colorForUtilization(&v, th)
 	want := color.RGBA{40, 167, 69, 255}
 	if got != want {
@@ -38,7 +92,7 @@ func TestColorForUtilization_Green(t *testing.T) {
 
 func TestColorForUtilization_Yellow(t *testing.T) {
 	th := Thresholds{Warning: 60, Critical: 85}
-	v := 60.0
+	v := Utilization(60)
 	got := colorForUtilization(&v, th)
 	want := color.RGBA{255, 193, 7, 255}
 	if got != want {
@@ -48,7 +102,7 @@ func TestColorForUtilization_Yellow(t *testing.T) {
 
 func TestColorForUtilization_YellowMid(t *testing.T) {
 	th := Thresholds{Warning: 60, Critical: 85}
-	v := 75.0
+	v := Utilization(75)
 	got := colorForUtilization(&v, th)
 	want := color.RGBA{255, 193, 7, 255}
 	if got != want {
@@ -58,7 +112,7 @@ func TestColorForUtilization_YellowMid(t *testing.T) {
 
 func TestColorForUtilization_Red(t *testing.T) {
 	th := Thresholds{Warning: 60, Critical: 85}
-	v := 85.0
+	v := Utilization(85)
 	got := colorForUtilization(&v, th)
 	want := color.RGBA{220, 53, 69, 255}
 	if got != want {
@@ -68,7 +122,7 @@ func TestColorForUtilization_Red(t *testing.T) {
 
 func TestColorForUtilization_RedHigh(t *testing.T) {
 	th := Thresholds{Warning: 60, Critical: 85}
-	v := 100.0
+	v := Utilization(100)
 	got := colorForUtilization(&v, th)
 	want := color.RGBA{220, 53, 69, 255}
 	if got != want {
@@ -78,7 +132,7 @@ func TestColorForUtilization_RedHigh(t *testing.T) {
 
 func TestColorForUtilization_CustomThresholds(t *testing.T) {
 	th := Thresholds{Warning: 40, Critical: 70}
-	v := 50.0
+	v := Utilization(50)
 	got := colorForUtilization(&v, th)
 	want := color.RGBA{255, 193, 7, 255} // yellow (>=40 but <70)
 	if got != want {
@@ -88,7 +142,7 @@ func TestColorForUtilization_CustomThresholds(t *testing.T) {
 
 func TestColorForUtilization_ZeroBoundary(t *testing.T) {
 	th := Thresholds{Warning: 60, Critical: 85}
-	v := 0.0
+	v := Utilization(0)
 	got := colorForUtilization(&v, th)
 	want := color.RGBA{40, 167, 69, 255} // green
 	if got != want {
@@ -97,7 +151,7 @@ func TestColorForUtilization_ZeroBoundary(t *testing.T) {
 }
 
 func TestRenderIcon_ProducesImage(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	img := renderIcon(state, th, testOpts())
@@ -107,6 +161,74 @@ func TestRenderIcon_ProducesImage(t *testing.T) {
 	}
 }
 
+func TestRenderIcon_Padding(t *testing.T) {
+	th := Thresholds{Warning: 60, Critical: 85}
+	for _, indicator := range []string{"pie", "arc", "bar"} {
+		opts := testOpts()
+		opts.Indicator = indicator
+		opts.ShowText = false
+
+		opts.Padding = 0
+		tight := renderIcon(QuotaState{}, th, opts)
+		opts.Padding = 8
+		loose := renderIcon(QuotaState{}, th, opts)
+
+		for _, img := range []image.Image{tight, loose} {
+			if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 64 {
+				t.Errorf("%s: size = %dx%d, want 64x64", indicator, b.Dx(), b.Dy())
+			}
+		}
+		// Top-center pixel next to the edge: covered by the outline at
+		// padding 0, empty at padding 8.
+		probe := image.Pt(32, 1)
+		if _, _, _, a := tight.At(probe.X, probe.Y).RGBA(); a == 0 {
+			t.Errorf("%s: padding=0 should draw at %v", indicator, probe)
+		}
+		if _, _, _, a := loose.At(probe.X, probe.Y).RGBA(); a != 0 {
+			t.Errorf("%s: padding=8 should leave %v empty", indicator, probe)
+		}
+	}
+}
+
+// opaqueHeight returns the height of the bounding box of non-transparent pixels.
+func opaqueHeight(img image.Image) int {
+	b := img.Bounds()
+	top, bottom := -1, -1
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0 {
+				if top < 0 {
+					top = y
+				}
+				bottom = y
+				break
+			}
+		}
+	}
+	if top < 0 {
+		return 0
+	}
+	return bottom - top + 1
+}
+
+func TestRenderIcon_ShapeScale(t *testing.T) {
+	th := Thresholds{Warning: 60, Critical: 85}
+	v := Utilization(50)
+	for _, indicator := range []string{"pie", "arc"} {
+		opts := testOpts()
+		opts.Indicator = indicator
+		opts.ShowText = false
+		full := opaqueHeight(renderIcon(QuotaState{FiveHour: &v}, th, opts))
+		opts.ShapeScale = 0.5
+		half := opaqueHeight(renderIcon(QuotaState{FiveHour: &v}, th, opts))
+
+		// The stroke width is not scaled, so allow a few pixels of slack.
+		if diff := half*2 - full; diff < -4 || diff > 10 {
+			t.Errorf("%s: height at 0.5 = %d, want about half of %d", indicator, half, full)
+		}
+	}
+}
+
 func TestRenderIcon_ErrorState(t *testing.T) {
 	state := QuotaState{Error: "something broke"}
 	th := Thresholds{Warning: 60, Critical: 85}
@@ -155,7 +277,7 @@ func TestRenderIcon_TokenExpired(t *testing.T) {
 }
 
 func TestRenderIcon_CustomSize(t *testing.T) {
-	v := 50.0
+	v := Utilization(50)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -192,7 +314,7 @@ func TestRenderIcon_CustomSize_Expired(t *testing.T) {
 }
 
 func TestRenderIcon_SmallSize(t *testing.T) {
-	v := 75.0
+	v := Utilization(75)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -217,7 +339,7 @@ func TestRenderIcon_SmallSize_Expired(t *testing.T) {
 }
 
 func TestRenderIcon_BitmapFont(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -231,7 +353,7 @@ func TestRenderIcon_BitmapFont(t *testing.T) {
 }
 
 func TestRenderIcon_MonoFont(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -245,7 +367,7 @@ func TestRenderIcon_MonoFont(t *testing.T) {
 
 func TestRenderIcon_BitmapScaling(t *testing.T) {
 	for _, size := range []int{24, 32, 48, 64, 128} {
-		v := 42.0
+		v := Utilization(42)
 		state := QuotaState{FiveHour: &v}
 		th := Thresholds{Warning: 60, Critical: 85}
 		opts := testOpts()
@@ -262,7 +384,7 @@ func TestRenderIcon_BitmapScaling(t *testing.T) {
 }
 
 func TestRenderIcon_NoHalo(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -275,7 +397,7 @@ func TestRenderIcon_NoHalo(t *testing.T) {
 }
 
 func TestRenderIcon_LargeHalo(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -318,7 +440,7 @@ func TestValidFontName(t *testing.T) {
 }
 
 func TestValidIndicatorName(t *testing.T) {
-	valid := []string{"pie", "bar", "arc", "bar-proj"}
+	valid := []string{"pie", "bar", "arc", "bar-proj", "bar-dual", "ring-dual", "text"}
 	for _, name := range valid {
 		if !ValidIndicatorName(name) {
 			t.Errorf("ValidIndicatorName(%q) = false, want true", name)
@@ -334,7 +456,7 @@ func TestValidIndicatorName(t *testing.T) {
 }
 
 func TestRenderIcon_BarIndicator(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -359,7 +481,7 @@ func TestRenderIcon_BarIndicator_NilUtilization(t *testing.T) {
 }
 
 func TestRenderIcon_ArcIndicator(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -384,7 +506,7 @@ func TestRenderIcon_ArcIndicator_NilUtilization(t *testing.T) {
 }
 
 func TestRenderIcon_IndicatorsDiffer(t *testing.T) {
-	v := 50.0
+	v := Utilization(50)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 
@@ -414,7 +536,7 @@ func TestRenderIcon_IndicatorsDiffer(t *testing.T) {
 }
 
 func TestRenderIcon_ShowTextFalse(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 
@@ -484,8 +606,8 @@ func TestRenderIcon_ExpiredIgnoresIndicator(t *testing.T) {
 }
 
 func TestRenderIcon_BarProj(t *testing.T) {
-	v := 42.0
-	proj := 75.0
+	v := Utilization(42)
+	proj := Utilization(75)
 	state := QuotaState{FiveHour: &v, FiveHourProjected: &proj}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -498,7 +620,7 @@ func TestRenderIcon_BarProj(t *testing.T) {
 }
 
 func TestRenderIcon_BarProj_NilProjection(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
 	opts := testOpts()
@@ -523,8 +645,8 @@ func TestRenderIcon_BarProj_NilUtilization(t *testing.T) {
 }
 
 func TestRenderIcon_BarProj_DiffersFromBar(t *testing.T) {
-	v := 50.0
-	proj := 80.0
+	v := Utilization(50)
+	proj := Utilization(80)
 	stateWithProj := QuotaState{FiveHour: &v, FiveHourProjected: &proj}
 	stateNoProj := QuotaState{FiveHour: &v}
 	th := Thresholds{Warning: 60, Critical: 85}
@@ -546,8 +668,8 @@ func TestRenderIcon_BarProj_DiffersFromBar(t *testing.T) {
 }
 
 func TestRenderIcon_BarProj_ShowTextFalse(t *testing.T) {
-	v := 42.0
-	proj := 75.0
+	v := Utilization(42)
+	proj := Utilization(75)
 	state := QuotaState{FiveHour: &v, FiveHourProjected: &proj}
 	th := Thresholds{Warning: 60, Critical: 85}
 
@@ -568,6 +690,209 @@ func TestRenderIcon_BarProj_ShowTextFalse(t *testing.T) {
 	}
 }
 
+func TestRenderIcon_BarDual(t *testing.T) {
+	v5 := Utilization(42)
+	v7 := Utilization(75)
+	state := QuotaState{FiveHour: &v5, SevenDay: &v7}
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+	opts.Indicator = "bar-dual"
+	img := renderIcon(state, th, opts)
+	bounds := img.Bounds()
+	if bounds.Dx() != 64 || bounds.Dy() != 64 {
+		t.Errorf("renderIcon bar-dual size = %dx%d, want 64x64", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestRenderIcon_BarDual_NilUtilization(t *testing.T) {
+	v := Utilization(42)
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+	opts.Indicator = "bar-dual"
+
+	for name, state := range map[string]QuotaState{
+		"nil 7d":   {FiveHour: &v},
+		"nil 5h":   {SevenDay: &v},
+		"nil both": {},
+	} {
+		img := renderIcon(state, th, opts)
+		bounds := img.Bounds()
+		if bounds.Dx() != 64 || bounds.Dy() != 64 {
+			t.Errorf("renderIcon bar-dual %s size = %dx%d, want 64x64", name, bounds.Dx(), bounds.Dy())
+		}
+	}
+}
+
+func TestRenderIcon_BarDual_DiffersFromBarAndBarProj(t *testing.T) {
+	v5 := Utilization(50)
+	v7 := Utilization(20)
+	proj := Utilization(80)
+	state := QuotaState{FiveHour: &v5, FiveHourProjected: &proj, SevenDay: &v7}
+	th := Thresholds{Warning: 60, Critical: 85}
+
+	barOpts := testOpts()
+	barOpts.Indicator = "bar"
+	barProjOpts := testOpts()
+	barProjOpts.Indicator = "bar-proj"
+	barDualOpts := testOpts()
+	barDualOpts.Indicator = "bar-dual"
+
+	barData, _ := encodePNG(renderIcon(state, th, barOpts))
+	barProjData, _ := encodePNG(renderIcon(state, th, barProjOpts))
+	barDualData, _ := encodePNG(renderIcon(state, th, barDualOpts))
+
+	if string(barDualData) == string(barData) {
+		t.Error("bar-dual should differ from plain bar")
+	}
+	if string(barDualData) == string(barProjData) {
+		t.Error("bar-dual should differ from bar-proj")
+	}
+}
+
+func TestRenderIcon_BarDual_SevenDayColor(t *testing.T) {
+	v5 := Utilization(10)
+	v7 := Utilization(90)
+	state := QuotaState{FiveHour: &v5, SevenDay: &v7}
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+	opts.Indicator = "bar-dual"
+	opts.ShowText = false
+	img := renderIcon(state, th, opts)
+
+	// Near the bottom of the right column: 7d bar in red.
+	r, g, b, _ := img.At(48, 58).RGBA()
+	red := color.RGBA{220, 53, 69, 255}
+	if uint8(r>>8) != red.R || uint8(g>>8) != red.G || uint8(b>>8) != red.B {
+		t.Errorf("right column pixel = (%d,%d,%d), want 7d critical color %v", r>>8, g>>8, b>>8, red)
+	}
+	// Near the bottom of the left column: 5h bar in green.
+	r, g, b, _ = img.At(16, 58).RGBA()
+	green := color.RGBA{40, 167, 69, 255}
+	if uint8(r>>8) != green.R || uint8(g>>8) != green.G || uint8(b>>8) != green.B {
+		t.Errorf("left column pixel = (%d,%d,%d), want 5h normal color %v", r>>8, g>>8, b>>8, green)
+	}
+}
+
+func TestRenderIcon_BarDual_IconWindow7d(t *testing.T) {
+	v5 := Utilization(20)
+	v7 := Utilization(73)
+	state := QuotaState{FiveHour: &v5, SevenDay: &v7}
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+	opts.Indicator = "bar-dual"
+	opts.IconWindow = "7d"
+	opts.ShowText = false
+	img := renderIcon(state, th, opts)
+
+	// The left column keeps showing the 5h window: green near the bottom,
+	// empty at mid-height where a 73% bar would reach.
+	r, g, b, _ := img.At(16, 58).RGBA()
+	green := color.RGBA{40, 167, 69, 255}
+	if uint8(r>>8) != green.R || uint8(g>>8) != green.G || uint8(b>>8) != green.B {
+		t.Errorf("left column pixel = (%d,%d,%d), want 5h normal color %v", r>>8, g>>8, b>>8, green)
+	}
+	if _, _, _, a := img.At(16, 32).RGBA(); a != 0 {
+		t.Error("left column filled at mid-height, want the 20% 5h bar")
+	}
+}
+
+// countTransparent returns the number of fully transparent pixels in img.
+func countTransparent(img image.Image) int {
+	n := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a == 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestRenderIcon_TextOnly(t *testing.T) {
+	v := Utilization(42)
+	state := QuotaState{FiveHour: &v}
+	th := Thresholds{Warning: 60, Critical: 85}
+
+	pieOpts := testOpts()
+	textOpts := testOpts()
+	textOpts.Indicator = "text"
+
+	pie := countTransparent(renderIcon(state, th, pieOpts))
+	text := countTransparent(renderIcon(state, th, textOpts))
+	if text <= pie {
+		t.Errorf("text icon has %d transparent pixels, want more than pie (%d)", text, pie)
+	}
+}
+
+func TestRenderIcon_TextOnly_NilUtilization(t *testing.T) {
+	opts := testOpts()
+	opts.Indicator = "text"
+	img := renderIcon(QuotaState{}, Thresholds{Warning: 60, Critical: 85}, opts)
+	// "--" placeholder must still draw something.
+	if countTransparent(img) == 64*64 {
+		t.Error("text icon without data should draw a placeholder")
+	}
+}
+
+func TestResolveColorSource(t *testing.T) {
+	v := Utilization(40)
+	proj := Utilization(95)
+	withProj := QuotaState{FiveHour: &v, FiveHourProjected: &proj}
+	noProj := QuotaState{FiveHour: &v}
+
+	if got := resolveColorSource(withProj, "current", "5h"); got == nil || *got != 40 {
+		t.Errorf("resolveColorSource(current) = %v, want 40", got)
+	}
+	if got := resolveColorSource(withProj, "projected", "5h"); got == nil || *got != 95 {
+		t.Errorf("resolveColorSource(projected) = %v, want 95", got)
+	}
+	if got := resolveColorSource(noProj, "projected", "5h"); got == nil || *got != 40 {
+		t.Errorf("resolveColorSource(projected, nil projection) = %v, want 40 (fallback)", got)
+	}
+	if got := resolveColorSource(QuotaState{}, "projected", "5h"); got != nil {
+		t.Errorf("resolveColorSource(projected, empty) = %v, want nil", *got)
+	}
+}
+
+func TestRenderIcon_ColorSourceProjected(t *testing.T) {
+	v := Utilization(40)
+	proj := Utilization(95)
+	state := QuotaState{FiveHour: &v, FiveHourProjected: &proj}
+	th := Thresholds{Warning: 60, Critical: 85}
+
+	// Sample the outer ring of the pie indicator (top center).
+	ringColor := func(src string) color.RGBA {
+		opts := testOpts()
+		opts.ShowText = false
+		opts.ColorSource = src
+		img := renderIcon(state, th, opts)
+		r, g, b, a := img.At(32, 4).RGBA()
+		return color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	}
+
+	if got, want := ringColor("current"), (color.RGBA{40, 167, 69, 255}); got != want {
+		t.Errorf("current color source ring = %v, want green %v", got, want)
+	}
+	if got, want := ringColor("projected"), (color.RGBA{220, 53, 69, 255}); got != want {
+		t.Errorf("projected color source ring = %v, want red %v", got, want)
+	}
+}
+
+func TestValidColorSource(t *testing.T) {
+	for _, name := range []string{"current", "projected"} {
+		if !ValidColorSource(name) {
+			t.Errorf("ValidColorSource(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"", "future", "7d"} {
+		if ValidColorSource(name) {
+			t.Errorf("ValidColorSource(%q) = true, want false", name)
+		}
+	}
+}
+
 func TestMutedColor(t *testing.T) {
 	// Red {220, 53, 69} blended 50% toward gray {128,128,128} → {174, 90, 98}
 	c := color.RGBA{220, 53, 69, 255}
@@ -586,7 +911,7 @@ func TestMutedColor(t *testing.T) {
 
 func TestClampFrac(t *testing.T) {
 	tests := []struct {
-		pct  float64
+		pct  Utilization
 		want float64
 	}{
 		{0, 0},
@@ -603,3 +928,228 @@ func TestClampFrac(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderIcon_IconWindow7d(t *testing.T) {
+	five, seven := Utilization(20), Utilization(73)
+	state := QuotaState{FiveHour: &five, SevenDay: &seven}
+	th := Thresholds{Warning: 50, Critical: 70}
+
+	if got := resolveIconUtilization(state, "7d"); got == nil || *got != 73 {
+		t.Errorf("resolveIconUtilization(7d) = %v, want 73", got)
+	}
+	if got := resolveIconUtilization(state, "5h"); got == nil || *got != 20 {
+		t.Errorf("resolveIconUtilization(5h) = %v, want 20", got)
+	}
+
+	opts := testOpts()
+	opts.ShowText = false
+	opts.IconWindow = "7d"
+	img := renderIcon(state, th, opts)
+	// Right of center is inside the pie slice for anything above 25%.
+	want := colorForUtilization(&seven, th)
+	if got := color.RGBAModel.Convert(img.At(48, 32)).(color.RGBA); got != want {
+		t.Errorf("7d icon pixel = %v, want critical color %v", got, want)
+	}
+}
+
+func TestParseOptionalColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.RGBA
+		ok      bool
+		wantErr bool
+	}{
+		{"", color.RGBA{}, false, false},
+		{"#1e1e2e", color.RGBA{0x1e, 0x1e, 0x2e, 0xff}, true, false},
+		{"#FF000080", color.RGBA{0xff, 0, 0, 0x80}, true, false},
+		{"1e1e2e", color.RGBA{}, false, true},
+		{"#12345", color.RGBA{}, false, true},
+		{"#gggggg", color.RGBA{}, false, true},
+	}
+	for _, tt := range tests {
+		got, ok, err := parseOptionalColor(tt.in)
+		if got != tt.want || ok != tt.ok || (err != nil) != tt.wantErr {
+			t.Errorf("parseOptionalColor(%q) = %v, %v, %v; want %v, %v, err=%v",
+				tt.in, got, ok, err, tt.want, tt.ok, tt.wantErr)
+		}
+	}
+}
+
+func TestRenderIcon_Background(t *testing.T) {
+	v := Utilization(42)
+	state := QuotaState{FiveHour: &v}
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+
+	img := renderIcon(state, th, opts)
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("corner alpha = %d without background, want transparent", a)
+	}
+
+	opts.Background = color.RGBA{0x1e, 0x1e, 0x2e, 0xff}
+	img = renderIcon(state, th, opts)
+	for _, pt := range []image.Point{{0, 0}, {63, 0}, {0, 63}, {63, 63}} {
+		if got := color.RGBAModel.Convert(img.At(pt.X, pt.Y)).(color.RGBA); got != opts.Background {
+			t.Errorf("corner %v = %v, want background %v", pt, got, opts.Background)
+		}
+	}
+}
+
+func TestRenderIcon_Opacity(t *testing.T) {
+	v := Utilization(42)
+	state := QuotaState{FiveHour: &v}
+	th := Thresholds{Warning: 60, Critical: 85}
+	full := renderIcon(state, th, testOpts()).(*image.RGBA)
+
+	opts := testOpts()
+	opts.Opacity = 0.5
+	half := renderIcon(state, th, opts).(*image.RGBA)
+
+	opaque := 0
+	for i := 3; i < len(full.Pix); i += 4 {
+		switch full.Pix[i] {
+		case 255:
+			opaque++
+			if a := half.Pix[i]; a != 128 {
+				t.Fatalf("opaque pixel alpha at 0.5 opacity = %d, want 128", a)
+			}
+		case 0:
+			if a := half.Pix[i]; a != 0 {
+				t.Fatalf("transparent pixel alpha at 0.5 opacity = %d, want 0", a)
+			}
+		}
+	}
+	if opaque == 0 {
+		t.Fatal("rendered icon has no opaque pixels")
+	}
+}
+
+func TestRenderIcon_BarCornerRadius(t *testing.T) {
+	v := Utilization(100)
+	state := QuotaState{FiveHour: &v}
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+	opts.Indicator = "bar"
+	opts.ShowText = false
+	square := renderIcon(state, th, opts).(*image.RGBA)
+
+	opts.BarRadius = 4
+	rounded := renderIcon(state, th, opts).(*image.RGBA)
+
+	if rounded.Bounds() != square.Bounds() {
+		t.Errorf("bounds = %v, want %v", rounded.Bounds(), square.Bounds())
+	}
+	// The outer corner pixel is covered by the square border only.
+	if a := square.RGBAAt(0, 0).A; a == 0 {
+		t.Errorf("square corner alpha = 0, want opaque")
+	}
+	if a := rounded.RGBAAt(0, 0).A; a != 0 {
+		t.Errorf("rounded corner alpha = %d, want 0", a)
+	}
+}
+
+func TestRenderIcon_RingDual(t *testing.T) {
+	v5 := Utilization(10)
+	v7 := Utilization(90)
+	state := QuotaState{FiveHour: &v5, SevenDay: &v7}
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+	opts.Indicator = "ring-dual"
+	opts.ShowText = false
+	opts.RingGap = defaultRingGap
+	img := renderIcon(state, th, opts)
+
+	// Just clockwise of 12 o'clock: outer ring (radius 27) shows 5h in green,
+	// inner ring (radius 27-2-2) shows 7d in red.
+	green := color.RGBA{40, 167, 69, 255}
+	red := color.RGBA{220, 53, 69, 255}
+	for _, tc := range []struct {
+		name string
+		y    int
+		want color.RGBA
+	}{
+		{"outer 5h", 5, green},
+		{"inner 7d", 9, red},
+	} {
+		r, g, b, _ := img.At(32, tc.y).RGBA()
+		if uint8(r>>8) != tc.want.R || uint8(g>>8) != tc.want.G || uint8(b>>8) != tc.want.B {
+			t.Errorf("%s pixel = (%d,%d,%d), want %v", tc.name, r>>8, g>>8, b>>8, tc.want)
+		}
+	}
+
+	arcOpts := opts
+	arcOpts.Indicator = "arc"
+	ringData, _ := encodePNG(img)
+	arcData, _ := encodePNG(renderIcon(state, th, arcOpts))
+	if string(ringData) == string(arcData) {
+		t.Error("ring-dual should differ from arc")
+	}
+}
+
+func TestRenderIcon_RingDual_NilUtilization(t *testing.T) {
+	v := Utilization(42)
+	th := Thresholds{Warning: 60, Critical: 85}
+	opts := testOpts()
+	opts.Indicator = "ring-dual"
+	opts.RingGap = maxRingGap
+
+	for name, state := range map[string]QuotaState{
+		"nil 7d":   {FiveHour: &v},
+		"nil 5h":   {SevenDay: &v},
+		"nil both": {},
+	} {
+		img := renderIcon(state, th, opts)
+		if bounds := img.Bounds(); bounds.Dx() != 64 || bounds.Dy() != 64 {
+			t.Errorf("renderIcon ring-dual %s size = %dx%d, want 64x64", name, bounds.Dx(), bounds.Dy())
+		}
+	}
+}
+
+func TestTextY(t *testing.T) {
+	for _, tt := range []struct {
+		position string
+		want     float64
+	}{
+		{"top", 16}, {"center", 32}, {"bottom", 48}, {"", 32},
+	} {
+		if got := textY(64, tt.position); got != tt.want {
+			t.Errorf("textY(64, %q) = %v, want %v", tt.position, got, tt.want)
+		}
+	}
+}
+
+func TestRenderIcon_TextPosition(t *testing.T) {
+	// inkCenter returns the mean row of the non-transparent pixels.
+	inkCenter := func(img image.Image) float64 {
+		var sum, n float64
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if _, _, _, a := img.At(x, y).RGBA(); a > 0 {
+					sum += float64(y)
+					n++
+				}
+			}
+		}
+		if n == 0 {
+			t.Fatal("icon has no visible pixels")
+		}
+		return sum / n
+	}
+
+	u := Utilization(42)
+	th := Thresholds{Warning: 60, Critical: 85}
+	rows := make(map[string]float64)
+	for _, pos := range textPositions {
+		opts := testOpts()
+		opts.Indicator = "text"
+		opts.TextPosition = pos
+		rows[pos] = inkCenter(renderIcon(QuotaState{FiveHour: &u}, th, opts))
+	}
+	if !(rows["top"] < rows["center"] && rows["center"] < rows["bottom"]) {
+		t.Errorf("text rows top=%.1f center=%.1f bottom=%.1f, want increasing", rows["top"], rows["center"], rows["bottom"])
+	}
+	if d := rows["center"] - 32; d < -4 || d > 4 {
+		t.Errorf("center text row = %.1f, want about 32", rows["center"])
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// iconCacheStep is the utilization step between prewarmed icons.
+const iconCacheStep = 10
+
+// iconCacheKey identifies an icon whose look depends only on the
+// utilization shown and the error flags.
+type iconCacheKey struct {
+	utilization float64
+	noData      bool
+	err         bool
+	expired     bool
+}
+
+// iconCache holds encoded tray icons rendered ahead of time with fixed
+// thresholds and options. Only prewarmed icons are stored, so the cache
+// stays small.
+type iconCache struct {
+	thresholds Thresholds
+	opts       RenderOptions
+	icons      sync.Map // iconCacheKey → []byte from iconToBytes
+}
+
+// newIconCache returns an empty cache for icons rendered with thresholds and opts.
+func newIconCache(thresholds Thresholds, opts RenderOptions) *iconCache {
+	return &iconCache{thresholds: thresholds, opts: opts}
+}
+
+// key returns the cache key for state, or false when its icon also depends
+// on projections or the 7d window (bar-proj, bar-dual, ring-dual, projected color).
+func (c *iconCache) key(state QuotaState) (iconCacheKey, bool) {
+	switch {
+	case state.TokenExpired:
+		return iconCacheKey{expired: true}, true
+	case state.Error != "":
+		return iconCacheKey{err: true}, true
+	case slices.Contains([]string{"bar-proj", "bar-dual", "ring-dual"}, c.opts.Indicator) || c.opts.ColorSource == "projected":
+		return iconCacheKey{}, false
+	}
+	u := resolveIconUtilization(state, c.opts.IconWindow)
+	if u == nil {
+		return iconCacheKey{noData: true}, true
+	}
+	return iconCacheKey{utilization: float64(*u)}, true
+}
+
+// render returns the encoded icon for state, from the cache when present.
+func (c *iconCache) render(state QuotaState) ([]byte, error) {
+	if key, ok := c.key(state); ok {
+		if data, hit := c.icons.Load(key); hit {
+			return data.([]byte), nil
+		}
+	}
+	return iconToBytes(renderIcon(state, c.thresholds, c.opts))
+}
+
+// prewarmStates returns the states warm renders: utilization 0-100 in steps
+// of iconCacheStep in the icon window, then the error icon.
+func (c *iconCache) prewarmStates() []QuotaState {
+	var states []QuotaState
+	for pct := 0; pct <= 100; pct += iconCacheStep {
+		u := Utilization(pct)
+		if c.opts.IconWindow == "7d" {
+			states = append(states, QuotaState{SevenDay: &u})
+		} else {
+			states = append(states, QuotaState{FiveHour: &u})
+		}
+	}
+	return append(states, QuotaState{Error: "prewarm"})
+}
+
+// warm renders and stores the prewarm states. Rendering holds lock, which
+// must be the lock serializing updateUI: the font faces are shared and not
+// goroutine-safe.
+func (c *iconCache) warm(lock sync.Locker) {
+	for _, state := range c.prewarmStates() {
+		key, ok := c.key(state)
+		if !ok {
+			continue
+		}
+		lock.Lock()
+		data, err := iconToBytes(renderIcon(state, c.thresholds, c.opts))
+		lock.Unlock()
+		if err != nil {
+			slog.Warn("Icon prewarm failed", "error", err)
+			return
+		}
+		c.icons.Store(key, data)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func cachedIcons(c *iconCache) int {
+	n := 0
+	c.icons.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func TestIconCache_Warm(t *testing.T) {
+	th := Thresholds{Warning: 60, Critical: 85}
+	c := newIconCache(th, testOpts())
+	c.warm(&sync.Mutex{})
+
+	// 0-100 in steps of 10, plus the error icon.
+	if n := cachedIcons(c); n != 12 {
+		t.Errorf("cache holds %d icons, want 12", n)
+	}
+
+	for _, state := range []QuotaState{c.prewarmStates()[4], {Error: "HTTP 500"}} {
+		key, _ := c.key(state)
+		cached, ok := c.icons.Load(key)
+		if !ok {
+			t.Fatalf("no cache entry for %+v", key)
+		}
+		got, err := c.render(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fresh, err := iconToBytes(renderIcon(state, th, testOpts()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, cached.([]byte)) || !bytes.Equal(got, fresh) {
+			t.Errorf("render(%+v) differs from a fresh render", key)
+		}
+	}
+}
+
+func TestIconCache_Uncacheable(t *testing.T) {
+	opts := testOpts()
+	opts.Indicator = "bar-proj"
+	c := newIconCache(Thresholds{Warning: 60, Critical: 85}, opts)
+	c.warm(&sync.Mutex{})
+
+	// Projection-dependent icons are skipped; only the error icon is cached.
+	if n := cachedIcons(c); n != 1 {
+		t.Errorf("cache holds %d icons, want 1", n)
+	}
+}
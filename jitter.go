@@ -0,0 +1,16 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// jitteredInterval returns base plus a random duration in [0, jitter), so that
+// instances started together drift apart instead of polling in lockstep.
+// A non-positive jitter returns base unchanged.
+func jitteredInterval(base, jitter time.Duration, rng *rand.Rand) time.Duration {
+	if jitter <= 0 {
+		return base
+	}
+	return base + time.Duration(rng.Int64N(int64(jitter)))
+}
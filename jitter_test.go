@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+)
+
+func TestJitteredInterval_Distribution(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	base := 300 * time.Second
+	jitter := 10 * time.Second
+
+	var sum time.Duration
+	const n = 1000
+	for range n {
+		d := jitteredInterval(base, jitter, rng)
+		if d < base || d >= base+jitter {
+			t.Fatalf("jitteredInterval = %v, want in [%v, %v)", d, base, base+jitter)
+		}
+		sum += d
+	}
+	mean := sum / n
+	want := base + jitter/2
+	if diff := mean - want; diff < -time.Second || diff > time.Second {
+		t.Errorf("mean = %v, want ~%v", mean, want)
+	}
+}
+
+func TestJitteredInterval_NoJitter(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, jitter := range []time.Duration{0, -time.Second} {
+		if got := jitteredInterval(time.Minute, jitter, rng); got != time.Minute {
+			t.Errorf("jitteredInterval(1m, %v) = %v, want 1m", jitter, got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+)
+
+const (
+	logFileMaxSize    = 10 << 20 // rotate once the log file would exceed 10 MB
+	logFileMaxBackups = 3        // keep <path>.1 through <path>.3
+)
+
+// rotatingFile is an append-only log file that rotates to <path>.1, <path>.2, …
+// once it would grow past maxSize, keeping at most maxBackups old files.
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+// openLogFile opens path for append, creating it if necessary.
+func openLogFile(path string) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: logFileMaxSize, maxBackups: logFileMaxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.f = f
+	rf.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if it would push the file past maxSize. A
+// failed rotation keeps appending to the current file; Write only fails when
+// no file could be opened, and retries the open on the next call.
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil && rf.f == nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate shifts <path>.N to <path>.N+1, dropping the oldest, moves the current
+// file to <path>.1 and reopens an empty one. If the move fails, the current
+// file is reopened for append instead. Either way rf.f is nil only when the
+// reopen failed too. Must be called under mu.
+func (rf *rotatingFile) rotate() error {
+	// Closed first: Windows cannot rename an open file.
+	err := rf.f.Close()
+	rf.f = nil
+	if err != nil {
+		return errors.Join(err, rf.open())
+	}
+	os.Remove(fmt.Sprintf("%s.%d", rf.path, rf.maxBackups))
+	for i := rf.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+	}
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+		return errors.Join(err, rf.open())
+	}
+	return rf.open()
+}
+
+// Close closes the underlying file.
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
+
+// logFormats lists the structured log formats. The default, "", keeps the
+// plain "[claude-quota] message key=value" output of the log package.
+var logFormats = []string{"text", "json"}
+
+// ValidLogFormat reports whether s is a known log_format value.
+func ValidLogFormat(s string) bool {
+	return s == "" || slices.Contains(logFormats, s)
+}
+
+// newLogHandler returns the slog handler writing format to w, or nil for the
+// default format.
+func newLogHandler(w io.Writer, format string) slog.Handler {
+	switch format {
+	case "json":
+		return slog.NewJSONHandler(w, nil)
+	case "text":
+		return slog.NewTextHandler(w, nil)
+	default:
+		return nil
+	}
+}
+
+// setupLogging directs log output to w in the given format.
+func setupLogging(w io.Writer, format string) {
+	if h := newLogHandler(w, format); h != nil {
+		slog.SetDefault(slog.New(h))
+		return
+	}
+	log.SetOutput(w)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogFile_Tee(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude-quota.log")
+	rf, err := openLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	var stderr strings.Builder
+	logger := log.New(io.MultiWriter(&stderr, rf), "", 0)
+	logger.Print("first message")
+	logger.Print("second message")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "first message\nsecond message\n"
+	if string(data) != want {
+		t.Errorf("log file = %q, want %q", data, want)
+	}
+	if stderr.String() != want {
+		t.Errorf("stderr = %q, want %q", stderr.String(), want)
+	}
+}
+
+func TestLogFile_Appends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude-quota.log")
+	os.WriteFile(path, []byte("existing\n"), 0o600)
+
+	rf, err := openLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(rf, "appended")
+	rf.Close()
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "existing\nappended\n" {
+		t.Errorf("log file = %q", data)
+	}
+}
+
+func TestLogFile_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude-quota.log")
+	rf, err := openLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.maxSize = 100
+
+	// 10 bytes per line, 10 lines per file: 55 lines fill 5 files, of which
+	// only the current one and 3 backups survive.
+	for i := range 55 {
+		fmt.Fprintf(rf, "line %04d\n", i)
+	}
+
+	cur, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(cur), "line 0050\n") {
+		t.Errorf("current file starts with %q, want line 0050", cur)
+	}
+	for n, first := range map[int]string{1: "line 0040", 2: "line 0030", 3: "line 0020"} {
+		data, err := os.ReadFile(fmt.Sprintf("%s.%d", path, n))
+		if err != nil {
+			t.Fatalf("backup %d: %v", n, err)
+		}
+		if len(data) > 100 {
+			t.Errorf("backup %d is %d bytes, want <= 100", n, len(data))
+		}
+		if !strings.HasPrefix(string(data), first) {
+			t.Errorf("backup %d starts with %q, want %q", n, data, first)
+		}
+	}
+	if _, err := os.Stat(path + ".4"); !os.IsNotExist(err) {
+		t.Errorf("at most %d backups should be kept", logFileMaxBackups)
+	}
+}
+
+func TestLogFile_RotationFailureKeepsWriting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "claude-quota.log")
+	// A non-empty directory at <path>.1 can be neither removed nor replaced,
+	// so the rotation's rename fails.
+	if err := os.MkdirAll(filepath.Join(path+".1", "keep"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	rf, err := openLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	rf.maxSize = 10
+	rf.maxBackups = 1
+
+	for i := range 3 {
+		if _, err := fmt.Fprintf(rf, "line %04d\n", i); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if want := "line 0000\nline 0001\nline 0002\n"; string(data) != want {
+		t.Errorf("log file = %q, want %q", data, want)
+	}
+}
+
+// captureSlog routes the default slog logger to a JSON buffer for the test.
+func captureSlog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// slogRecords decodes one JSON log record per line.
+func slogRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestNewLogHandler(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"json", `"msg":"hello"`},
+		{"text", `msg=hello`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		slog.New(newLogHandler(&buf, tt.format)).Info("hello", "key", "value")
+		if !strings.Contains(buf.String(), tt.want) {
+			t.Errorf("%s output = %q, want it to contain %q", tt.format, buf.String(), tt.want)
+		}
+	}
+	if h := newLogHandler(io.Discard, ""); h != nil {
+		t.Errorf("newLogHandler(\"\") = %T, want nil", h)
+	}
+}
+
+func TestSlog_ErrorAttribute(t *testing.T) {
+	buf := captureSlog(t)
+
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	loadConfig()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(500)
+	}))
+	defer srv.Close()
+	newTestQuotaClient("tok", 0, srv.Client(), srv.URL).FetchOnce()
+
+	records := slogRecords(t, buf)
+	for _, msg := range []string{"Failed to parse config", "API error"} {
+		found := false
+		for _, r := range records {
+			if r["msg"] != msg {
+				continue
+			}
+			found = true
+			if r["level"] != "ERROR" {
+				t.Errorf("%q level = %v, want ERROR", msg, r["level"])
+			}
+			if _, ok := r["error"]; !ok {
+				t.Errorf("%q has no error attribute: %v", msg, r)
+			}
+		}
+		if !found {
+			t.Errorf("no %q record in %v", msg, records)
+		}
+	}
+}
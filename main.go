@@ -1,15 +1,21 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/net/http/httpproxy"
 )
 
 // Build-time variables injected via ldflags.
@@ -48,23 +54,65 @@ func main() {
 	showVersion := flag.Bool("version", false, "show version and exit")
 	doUpdate := flag.Bool("update", false, "check and update to latest release")
 	pollInterval := flag.Int("poll-interval", 0, "poll interval in seconds (env: CLAUDE_QUOTA_POLL_INTERVAL)")
+	pollJitter := flag.Int("poll-jitter", -1, "max random delay added to each poll, in seconds, 0 to disable (env: CLAUDE_QUOTA_POLL_JITTER)")
+	minProjection := flag.Float64("min-utilization-for-projection", -1, "utilization in % below which no projection is shown (env: CLAUDE_QUOTA_MIN_UTILIZATION_FOR_PROJECTION)")
+	timeout := flag.Int("timeout", 0, "HTTP timeout in seconds, 1-300 (env: CLAUDE_QUOTA_TIMEOUT)")
 	fontSize := flag.Float64("font-size", 0, "icon font size (env: CLAUDE_QUOTA_FONT_SIZE)")
 	fontName := flag.String("font-name", "", "icon font name: bold, regular, mono, monobold, bitmap (env: CLAUDE_QUOTA_FONT_NAME)")
 	haloSize := flag.Float64("halo-size", -1, "text halo/outline size in pixels, 0 to disable (env: CLAUDE_QUOTA_HALO_SIZE)")
 	iconSize := flag.Int("icon-size", 0, "icon size in pixels (env: CLAUDE_QUOTA_ICON_SIZE)")
 	warningThreshold := flag.Float64("warning-threshold", 0, "warning utilization threshold in % (env: CLAUDE_QUOTA_WARNING_THRESHOLD)")
 	criticalThreshold := flag.Float64("critical-threshold", 0, "critical utilization threshold in % (env: CLAUDE_QUOTA_CRITICAL_THRESHOLD)")
-	indicator := flag.String("indicator", "", "indicator type: pie, bar, arc, bar-proj (env: CLAUDE_QUOTA_INDICATOR)")
+	indicator := flag.String("indicator", "", "indicator type: pie, bar, arc, bar-proj, bar-dual, ring-dual, text (env: CLAUDE_QUOTA_INDICATOR)")
+	colorSource := flag.String("color-source", "", "icon color source: current, projected (env: CLAUDE_QUOTA_COLOR_SOURCE)")
+	iconBackground := flag.String("icon-background-color", "", "icon background as #rrggbb or #rrggbbaa (env: CLAUDE_QUOTA_ICON_BACKGROUND_COLOR)")
+	iconWindow := flag.String("icon-window", "", "quota window shown by the icon: 5h, 7d (env: CLAUDE_QUOTA_ICON_WINDOW)")
+	timeDisplay := flag.String("time-display", "", "reset time display: relative, absolute, both (env: CLAUDE_QUOTA_TIME_DISPLAY)")
 	showText := flag.Bool("show-text", true, "show percentage text on icon (env: CLAUDE_QUOTA_SHOW_TEXT)")
 	showAccount := flag.Bool("show-account", false, "show account info in menu (env: CLAUDE_QUOTA_SHOW_ACCOUNT)")
 	stats := flag.Bool("stats", false, "enable local stats collection (env: CLAUDE_QUOTA_STATS)")
+	apiURL := flag.String("api-url", "", "usage API endpoint (env: CLAUDE_QUOTA_API_URL)")
+	anthropicVersion := flag.String("anthropic-version", defaultAnthropicVersion, "anthropic-version API header, empty to omit (env: CLAUDE_QUOTA_ANTHROPIC_VERSION)")
+	logFile := flag.String("log-file", "", "also append logs to this file (env: CLAUDE_QUOTA_LOG_FILE)")
+	logFormat := flag.String("log-format", "", "structured log format: text, json (env: CLAUDE_QUOTA_LOG_FORMAT)")
+	healthcheckAddr := flag.String("healthcheck-addr", "", "serve a /health endpoint on this address, e.g. :8080")
+	verbose := flag.Bool("verbose", false, "show fetch counters in the tooltip")
+	noProjection := flag.Bool("no-projection", false, "hide projected utilization lines (overrides show_projection)")
 	claudeHome := flag.String("claude-home", "", "home directory for Claude credentials (env: CLAUDE_QUOTA_CLAUDE_HOME)")
+	configSchema := flag.Bool("config-schema", false, "print the config file JSON Schema and exit")
+	sampleCfg := flag.Bool("sample-config", false, "print a commented sample config file and exit")
+	genCompletion := flag.String("generate-completion", "", "print a shell completion script (bash, zsh, fish) and exit")
+	configFile := flag.String("config", "", "config file path, JSON or TOML by extension (default: "+configPath+")")
+	resetCfg := flag.Bool("reset-config", false, "reset the config file to defaults and exit")
+	cfgDiff := flag.Bool("config-diff", false, "print config file values that differ from the defaults and exit")
+	forgetCreds := flag.Bool("forget-credentials", false, "delete stored Claude credentials (Keychain and credentials file) and exit")
+	assumeYes := flag.Bool("yes", false, "don't ask for confirmation (with -reset-config or -forget-credentials)")
+	iconPreview := flag.Bool("icon-preview", false, "write the rendered icon as PNG to stdout and exit")
+	iconUtilization := flag.Float64("icon-utilization", -1, "utilization in % to render with -icon-preview (default: no data)")
+	summary := flag.Bool("summary", false, "fetch quota once, print a summary table and exit")
+	exportCSV := flag.String("export-csv", "", "write the -stats history to this CSV file and exit")
+	pushgatewayURL := flag.String("pushgateway-url", "", "with -summary, push the quota metrics to this Prometheus Pushgateway")
+	pushgatewayJob := flag.String("pushgateway-job", defaultPushgatewayJob, "Pushgateway job name for -pushgateway-url")
+	assertBelow := flag.Float64("assert-quota-below", -1, "fetch quota once and exit 2 if utilization is at or above this % (for CI)")
+	compareAccount := flag.String("compare-account", "", "fetch quota for the default and this credentials file, print both on one line and exit")
+	quotaWindow := flag.String("quota-window", "5h", "quota window checked by -assert-quota-below: 5h or 7d")
+	benchmarkIcons := flag.Int("benchmark-icon", 0, "render the icon N times (e.g. 100), print timings and exit")
+	dryRun := flag.Bool("dry-run", false, "validate config and credentials, print the resolved settings and exit")
 	flag.Usage = func() {
 		fmt.Print(versionStringLong())
-		fmt.Fprintf(os.Stderr, "\nUsage: %s [options]\n\nOptions:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nUsage: %s [status|update|version] [options]\n\nOptions:\n", os.Args[0])
 		flag.PrintDefaults()
 	}
-	flag.Parse()
+	flag.CommandLine.Parse(translateSubcommand(os.Args[1:]))
+
+	if *configFile != "" {
+		configPath = *configFile
+	}
+
+	if err := checkPushgatewayFlags(*pushgatewayURL, *summary); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
 
 	if *showVersion {
 		fmt.Print(versionStringLong())
@@ -76,29 +124,54 @@ func main() {
 		return
 	}
 
-	cfg := loadConfig()
+	if *genCompletion != "" {
+		if err := generateCompletion(os.Stdout, *genCompletion, flag.CommandLine); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Resolve claude-home: config < env < flag.
-	if cfg.ClaudeHome != "" {
-		credentialsPath = filepath.Join(cfg.ClaudeHome, ".claude", ".credentials.json")
+	if *sampleCfg {
+		os.Stdout.Write(generateSampleConfig())
+		return
 	}
-	if v := os.Getenv("CLAUDE_QUOTA_CLAUDE_HOME"); v != "" {
-		credentialsPath = filepath.Join(v, ".claude", ".credentials.json")
+
+	if *configSchema {
+		os.Stdout.Write(generateConfigSchema())
+		return
 	}
-	if *claudeHome != "" {
-		credentialsPath = filepath.Join(*claudeHome, ".claude", ".credentials.json")
+
+	if *resetCfg {
+		if err := resetConfig(os.Stdin, os.Stdout, *assumeYes); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	fmt.Println("WARNING: This tool uses Claude Code's OAuth client ID to access your")
-	fmt.Println("quota data via an undocumented API. This is not sanctioned by Anthropic")
-	fmt.Println("and may violate the Terms of Service. Use at your own risk.")
-	fmt.Println()
+	_, statErr := os.Stat(configPath)
+	firstRun := os.IsNotExist(statErr)
+	load := loadConfigDetails()
+	cfg := load.Config
 
-	credentialsPreCheck()
+	if *cfgDiff {
+		printConfigDiff(os.Stdout, cfg, load.Raw)
+		return
+	}
 
-	fmt.Println(versionString())
-	fmt.Printf("Credentials: %s\n", credentialsPath)
-	fmt.Printf("Config: %s\n", configPath)
+	// Resolve claude-home: config < env < flag. Only the config value needs
+	// expanding; the shell already expanded the others.
+	envClaudeHome := os.Getenv("CLAUDE_QUOTA_CLAUDE_HOME")
+	credentialsPath = resolveCredentialsPath(expandPath(cfg.ClaudeHome), envClaudeHome, *claudeHome)
+	// No explicit home: on Windows, fall back to WSL if the native file is missing.
+	if cfg.ClaudeHome == "" && envClaudeHome == "" && *claudeHome == "" {
+		wslCredentialsFallback()
+	}
+
+	if *forgetCreds {
+		os.Exit(forgetCredentials(os.Stdin, os.Stdout, *assumeYes))
+	}
 
 	// Only pass ShowText when the user explicitly set -show-text.
 	// flag.Bool defaults to true, so we can't distinguish "not set" from
@@ -106,7 +179,11 @@ func main() {
 	var showTextOverride *bool
 	var showAccountOverride *bool
 	var statsOverride *bool
+	var anthropicVersionOverride *string
 	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "anthropic-version" {
+			anthropicVersionOverride = anthropicVersion
+		}
 		if f.Name == "show-text" {
 			showTextOverride = showText
 		}
@@ -120,11 +197,22 @@ func main() {
 
 	applyOverrides(&cfg, overrides{
 		PollInterval:      *pollInterval,
+		PollJitter:        *pollJitter,
+		Timeout:           *timeout,
+		MinProjection:     *minProjection,
 		FontSize:          *fontSize,
 		FontName:          *fontName,
 		HaloSize:          *haloSize,
 		IconSize:          *iconSize,
 		Indicator:         *indicator,
+		ColorSource:       *colorSource,
+		IconWindow:        *iconWindow,
+		TimeDisplay:       *timeDisplay,
+		IconBackground:    *iconBackground,
+		APIURL:            *apiURL,
+		AnthropicVersion:  anthropicVersionOverride,
+		LogFile:           *logFile,
+		LogFormat:         *logFormat,
 		ShowText:          showTextOverride,
 		ShowAccount:       showAccountOverride,
 		Stats:             statsOverride,
@@ -132,55 +220,422 @@ func main() {
 		CriticalThreshold: *criticalThreshold,
 	})
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	cfg.Verbose = *verbose
+	if *noProjection {
+		cfg.ShowProjection = false
+	}
 
-	creds, err := NewOAuthCredentials()
+	logOut := io.Writer(os.Stderr)
+	if cfg.LogFile != "" {
+		lf, err := openLogFile(cfg.LogFile)
+		if err != nil {
+			slog.Error("Cannot open log file", "path", cfg.LogFile, "error", err)
+		} else {
+			defer lf.Close()
+			logOut = io.MultiWriter(os.Stderr, lf)
+		}
+	}
+	setupLogging(logOut, cfg.LogFormat)
+
+	if *dryRun {
+		os.Exit(runDryRun(os.Stdout, cfg, load.Raw))
+	}
+
+	if *iconPreview {
+		if err := writeIconPreview(os.Stdout, cfg, *iconUtilization); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *benchmarkIcons != 0 {
+		b, err := runIconBenchmark(cfg, *benchmarkIcons)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printIconBenchmark(os.Stdout, b)
+		return
+	}
+
+	if *exportCSV != "" {
+		os.Exit(runExportCSV(os.Stderr, *exportCSV))
+	}
+
+	if *summary {
+		os.Exit(runSummary(os.Stdout, cfg, *pushgatewayURL, *pushgatewayJob))
+	}
+
+	if *assertBelow >= 0 {
+		os.Exit(runAssertQuota(os.Stdout, cfg, *assertBelow, *quotaWindow))
+	}
+
+	if *compareAccount != "" {
+		os.Exit(runCompareAccounts(os.Stdout, cfg, expandPath(*compareAccount)))
+	}
+
+	saveMigratedConfig(load)
+
+	// First run from a terminal: offer telemetry, which stays off unless
+	// accepted.
+	if shouldAskTelemetryConsent(cfg, firstRun) && askTelemetryConsent(os.Stdin, os.Stdout, cfg.TelemetryURL) {
+		cfg.TelemetryEnabled = true
+		if err := saveTelemetryConsent(cfg.TelemetryURL); err != nil {
+			slog.Error("Failed to save config", "path", configPath, "error", err)
+		}
+	}
+
+	app, err := setupApp(os.Stdout, cfg, *healthcheckAddr)
 	if err != nil {
 		fmt.Printf("\nError: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Handle interrupt for clean shutdown (SIGINT on all platforms, SIGTERM on Unix).
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	notifyExtraSignals(sigCh)
+	go func() {
+		<-sigCh
+		slog.Info("Signal received, shutting down")
+		app.Shutdown()
+	}()
+
+	// SIGUSR1 (Unix only) forces an immediate fetch.
+	fetchCh := make(chan os.Signal, 1)
+	notifyFetchSignals(fetchCh)
+	go func() {
+		for range fetchCh {
+			slog.Info("Fetch requested by signal")
+			app.TriggerFetch()
+		}
+	}()
+
+	app.Run()
+
+	if app.restartRequested {
+		execSelf()
+	}
+}
+
+// subcommands maps subcommand words to the flag each one stands for.
+var subcommands = map[string]string{
+	"status":  "-summary",
+	"update":  "-update",
+	"version": "-version",
+}
+
+// translateSubcommand rewrites a leading subcommand word in args (os.Args[1:])
+// into its equivalent flag, so "claude-quota status" behaves like
+// "claude-quota -summary". Anything else is returned unchanged.
+func translateSubcommand(args []string) []string {
+	if len(args) == 0 {
+		return args
+	}
+	f, ok := subcommands[args[0]]
+	if !ok {
+		return args
+	}
+	return append([]string{f}, args[1:]...)
+}
+
+// runDryRun prints the resolved config and credential status without making
+// network requests or starting the systray. Validation problems are reported
+// for raw, the config as read, since cfg already has invalid values reset.
+// Returns the process exit code: 0 when config and credentials are usable,
+// 1 otherwise.
+func runDryRun(w io.Writer, cfg, raw Config) int {
+	fmt.Fprintln(w, versionString())
+	fmt.Fprintf(w, "Config: %s\n", configPath)
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		fmt.Fprintf(w, "Config error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	if !printConfigValidation(w, raw) {
+		return 1
+	}
+
+	fmt.Fprintf(w, "Credentials: %s\n", credentialsPath)
+	creds, err := NewOAuthCredentials()
+	if err != nil {
+		fmt.Fprintf(w, "Credentials error: %v\n", err)
+		return 1
+	}
+	info := creds.Inspect()
+	if info.IsExpired {
+		fmt.Fprintf(w, "Credentials error: %v\n", ErrTokenExpired)
+		return 1
+	}
+	if !info.ExpiresAt.IsZero() {
+		fmt.Fprintf(w, "Credentials: OK (token expires in %s, %s)\n",
+			formatTimeRemaining(&info.ExpiresAt, time.Now()), formatResetDate(&info.ExpiresAt))
+	} else {
+		fmt.Fprintln(w, "Credentials: OK (token expiry unknown)")
+	}
+	return 0
+}
+
+// startupWriter returns w, or io.Discard when CLAUDE_QUOTA_QUIET is set to
+// anything but "0", to silence the startup banner in scripts and containers.
+// Errors are still printed.
+func startupWriter(w io.Writer) io.Writer {
+	if quietMode() {
+		return io.Discard
+	}
+	return w
+}
+
+// quietMode reports whether CLAUDE_QUOTA_QUIET is set to anything but "0".
+func quietMode() bool {
+	v := os.Getenv("CLAUDE_QUOTA_QUIET")
+	return v != "" && v != "0"
+}
+
+// setupApp prints the startup banner to w, silenced by CLAUDE_QUOTA_QUIET,
+// and creates the App with its stats store and, when healthcheckAddr is set,
+// its health server.
+func setupApp(w io.Writer, cfg Config, healthcheckAddr string) (*App, error) {
+	banner := startupWriter(w)
+	printDisclaimer(banner)
+
+	credentialsPreCheck()
+
+	printStartupPaths(banner)
+
+	client := newHTTPClient(cfg)
+
+	creds, err := NewOAuthCredentials()
+	if err != nil {
+		return nil, err
+	}
+
 	var statsStore *StatsStore
 	if cfg.Stats {
 		var statsErr error
 		statsStore, statsErr = NewStatsStore()
 		if statsErr != nil {
-			log.Printf("Warning: stats collection disabled: %v", statsErr)
+			slog.Warn("Stats collection disabled", "error", statsErr)
 		} else {
-			fmt.Printf("Stats DB: %s\n", statsDBPath)
+			fmt.Fprintf(banner, "Stats DB: %s\n", statsDBPath)
 		}
 	} else {
-		fmt.Println("Stats: disabled")
+		fmt.Fprintln(banner, "Stats: disabled")
 	}
 
 	resolver := NewAccountResolver(client, statsStore)
 	app := NewApp(cfg, creds, client, statsStore, resolver)
 
-	// Handle interrupt for clean shutdown (SIGINT on all platforms, SIGTERM on Unix).
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt)
-	notifyExtraSignals(sigCh)
-	go func() {
-		<-sigCh
-		log.Println("Signal received, shutting down...")
-		app.Shutdown()
-	}()
+	if healthcheckAddr != "" {
+		startHealthServer(healthcheckAddr, app.QuotaState, app.LastFetchDuration, time.Duration(cfg.PollIntervalSeconds)*time.Second)
+		fmt.Fprintf(banner, "Healthcheck: http://%s/health\n", healthcheckAddr)
+	}
+	return app, nil
+}
 
-	app.Run()
+// printDisclaimer writes the terms-of-service warning shown at startup.
+func printDisclaimer(w io.Writer) {
+	fmt.Fprintln(w, "WARNING: This tool uses Claude Code's OAuth client ID to access your")
+	fmt.Fprintln(w, "quota data via an undocumented API. This is not sanctioned by Anthropic")
+	fmt.Fprintln(w, "and may violate the Terms of Service. Use at your own risk.")
+	fmt.Fprintln(w)
+}
 
-	if app.restartRequested {
-		execSelf()
+// printStartupPaths writes the version and the credentials and config paths.
+func printStartupPaths(w io.Writer) {
+	fmt.Fprintln(w, versionString())
+	fmt.Fprintf(w, "Credentials: %s\n", credentialsPath)
+	fmt.Fprintf(w, "Config: %s\n", configPath)
+}
+
+// runSummary fetches quota once and prints it with PrintSummary. When
+// pushURL is set, a successful fetch is also pushed with PushToGateway.
+// Returns the process exit code: 0 on success, 1 on any error.
+func runSummary(w io.Writer, cfg Config, pushURL, pushJob string) int {
+	creds, err := NewOAuthCredentials()
+	if err != nil {
+		fmt.Fprintf(w, "Credentials error: %v\n", err)
+		return 1
+	}
+	client := newHTTPClient(cfg)
+	qc := newConfiguredQuotaClient(cfg, creds, client)
+	ok := qc.Fetch()
+	PrintSummary(qc.State(), w)
+	if !ok {
+		return 1
+	}
+	if pushURL != "" {
+		// A failed push is logged but does not fail the summary.
+		if err := PushToGateway(client, pushURL, pushJob, qc.State(), qc.LastFetchDuration()); err != nil {
+			slog.Error("Pushgateway push failed", "url", pushURL, "error", err)
+		}
+	}
+	return 0
+}
+
+// assertQuotaExitCode is returned by runAssertQuota when the quota is at or
+// above the limit, distinct from 1 for fetch and credential errors.
+const assertQuotaExitCode = 2
+
+// runAssertQuota fetches quota once and checks window's utilization against
+// limit, e.g. "5h quota is 73% (limit 50%)". Returns the process exit code:
+// 0 below the limit, assertQuotaExitCode at or above it, 1 on any error.
+func runAssertQuota(w io.Writer, cfg Config, limit float64, window string) int {
+	if !ValidIconWindow(window) {
+		fmt.Fprintf(w, "Invalid quota window %q, want one of: %s\n", window, strings.Join(iconWindows, ", "))
+		return 1
+	}
+	creds, err := NewOAuthCredentials()
+	if err != nil {
+		fmt.Fprintf(w, "Credentials error: %v\n", err)
+		return 1
+	}
+	qc := newConfiguredQuotaClient(cfg, creds, newHTTPClient(cfg))
+	if !qc.Fetch() {
+		fmt.Fprintf(w, "Fetch error: %s\n", qc.State().Error)
+		return 1
+	}
+	u := resolveIconUtilization(qc.State(), window)
+	if u == nil {
+		fmt.Fprintf(w, "%s quota has no data\n", window)
+		return 1
 	}
+	fmt.Fprintf(w, "%s quota is %s (limit %g%%)\n", window, *u, limit)
+	if float64(*u) >= limit {
+		return assertQuotaExitCode
+	}
+	return 0
+}
+
+// runCompareAccounts fetches quota for the default credentials and for the
+// credentials file at otherPath, then prints both with compareAccounts.
+// Returns the process exit code: 0 on success, 1 on any error.
+func runCompareAccounts(w io.Writer, cfg Config, otherPath string) int {
+	first, err := NewOAuthCredentials()
+	if err != nil {
+		fmt.Fprintf(w, "Account 1 credentials error: %v\n", err)
+		return 1
+	}
+	second, err := NewOAuthCredentialsFromFile(otherPath)
+	if err != nil {
+		fmt.Fprintf(w, "Account 2 credentials error: %v\n", err)
+		return 1
+	}
+	client := newHTTPClient(cfg)
+	return compareAccounts(w, newConfiguredQuotaClient(cfg, first, client), newConfiguredQuotaClient(cfg, second, client))
+}
+
+// compareAccounts fetches with each client and prints one line, e.g.
+// "Account 1: 5h=42%, 7d=10% | Account 2: 5h=78%, 7d=55%". Returns 1 when
+// either fetch failed, 0 otherwise.
+func compareAccounts(w io.Writer, clients ...*QuotaClient) int {
+	code := 0
+	parts := make([]string, len(clients))
+	for i, qc := range clients {
+		if !qc.Fetch() {
+			code = 1
+			parts[i] = fmt.Sprintf("Account %d: error: %s", i+1, qc.State().Error)
+			continue
+		}
+		state := qc.State()
+		parts[i] = fmt.Sprintf("Account %d: 5h=%s, 7d=%s", i+1, utilizationOrDash(state.FiveHour), utilizationOrDash(state.SevenDay))
+	}
+	fmt.Fprintln(w, strings.Join(parts, " | "))
+	return code
+}
+
+// utilizationOrDash formats u, or "--" when unknown.
+func utilizationOrDash(u *Utilization) string {
+	if u == nil {
+		return "--"
+	}
+	return u.String()
+}
+
+// newConfiguredQuotaClient returns a QuotaClient set up from cfg.
+func newConfiguredQuotaClient(cfg Config, creds *OAuthCredentials, client *http.Client) *QuotaClient {
+	qc := NewQuotaClient(creds, client, configAPIURL(cfg), cfg.AnthropicVersion,
+		Utilization(cfg.MinUtilizationForProjection))
+	qc.SetStrictJSON(cfg.StrictJSON)
+	return qc
+}
+
+// newHTTPClient returns the HTTP client shared by the API clients,
+// with the timeout from cfg and the proxy from the environment.
+func newHTTPClient(cfg Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFromEnvironment()
+	return &http.Client{Timeout: configTimeout(cfg), Transport: transport}
+}
+
+// proxyFromEnvironment returns a Transport.Proxy function honoring
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY (or their lowercase forms), with
+// ALL_PROXY as the fallback for both schemes. socks5:// proxies are supported.
+// Unlike http.ProxyFromEnvironment, the environment is read on each call
+// rather than once per process.
+func proxyFromEnvironment() func(*http.Request) (*url.URL, error) {
+	cfg := httpproxy.FromEnvironment()
+	if all := getenvAny("ALL_PROXY", "all_proxy"); all != "" {
+		if cfg.HTTPSProxy == "" {
+			cfg.HTTPSProxy = all
+		}
+		if cfg.HTTPProxy == "" {
+			cfg.HTTPProxy = all
+		}
+	}
+	proxy := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxy(req.URL)
+	}
+}
+
+// getenvAny returns the value of the first non-empty environment variable in keys.
+func getenvAny(keys ...string) string {
+	for _, k := range keys {
+		if v := os.Getenv(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// writeIconPreview renders the icon for cfg and writes the raw PNG bytes to w.
+// A negative utilization renders the no-data icon.
+func writeIconPreview(w io.Writer, cfg Config, utilization float64) error {
+	var state QuotaState
+	if utilization >= 0 {
+		u := Utilization(utilization)
+		state.FiveHour = &u
+	}
+	data, err := encodePNG(renderIcon(state, cfg.Thresholds, RenderOptionsFromConfig(cfg)))
+	if err != nil {
+		return fmt.Errorf("encode icon: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
 }
 
 // overrides holds CLI flag values for config overrides.
 type overrides struct {
 	PollInterval      int
+	PollJitter        int
+	Timeout           int
+	MinProjection     float64 // -1 when not set
 	FontSize          float64
 	FontName          string
 	HaloSize          float64
 	IconSize          int
 	Indicator         string
+	ColorSource       string
+	IconWindow        string
+	TimeDisplay       string
+	IconBackground    string
+	APIURL            string
+	AnthropicVersion  *string // nil when -anthropic-version was not given
+	LogFile           string
+	LogFormat         string
 	ShowText          *bool
 	ShowAccount       *bool
 	Stats             *bool
@@ -193,7 +648,7 @@ type overrides struct {
 func applyIntOverride(target *int, envKey string, flagVal int, valid func(int) bool) {
 	if v := os.Getenv(envKey); v != "" {
 		if i, err := strconv.Atoi(v); err != nil || !valid(i) {
-			log.Printf("Ignoring invalid %s=%q", envKey, v)
+			slog.Warn("Ignoring invalid environment variable", "name", envKey, "value", v)
 		} else {
 			*target = i
 		}
@@ -208,7 +663,7 @@ func applyIntOverride(target *int, envKey string, flagVal int, valid func(int) b
 func applyFloatOverride(target *float64, envKey string, flagVal float64, flagIsSet bool, valid func(float64) bool) {
 	if v := os.Getenv(envKey); v != "" {
 		if f, err := strconv.ParseFloat(v, 64); err != nil || !valid(f) {
-			log.Printf("Ignoring invalid %s=%q", envKey, v)
+			slog.Warn("Ignoring invalid environment variable", "name", envKey, "value", v)
 		} else {
 			*target = f
 		}
@@ -223,14 +678,14 @@ func applyFloatOverride(target *float64, envKey string, flagVal float64, flagIsS
 func applyStringOverride(target *string, envKey, flagName, flagVal string, valid func(string) bool) {
 	if v := os.Getenv(envKey); v != "" {
 		if !valid(v) {
-			log.Printf("Ignoring invalid %s=%q", envKey, v)
+			slog.Warn("Ignoring invalid environment variable", "name", envKey, "value", v)
 		} else {
 			*target = v
 		}
 	}
 	if flagVal != "" {
 		if !valid(flagVal) {
-			log.Printf("Ignoring invalid -%s=%q", flagName, flagVal)
+			slog.Warn("Ignoring invalid flag", "name", "-"+flagName, "value", flagVal)
 		} else {
 			*target = flagVal
 		}
@@ -241,6 +696,11 @@ func applyStringOverride(target *string, envKey, flagName, flagVal string, valid
 func applyOverrides(cfg *Config, o overrides) {
 	applyIntOverride(&cfg.PollIntervalSeconds, "CLAUDE_QUOTA_POLL_INTERVAL", o.PollInterval,
 		func(i int) bool { return i > 0 })
+	applyIntOverride(&cfg.PollJitterSeconds, "CLAUDE_QUOTA_POLL_JITTER", o.PollJitter,
+		func(i int) bool { return i >= 0 })
+	applyIntOverride(&cfg.TimeoutSeconds, "CLAUDE_QUOTA_TIMEOUT", o.Timeout, validTimeoutSeconds)
+	applyFloatOverride(&cfg.MinUtilizationForProjection, "CLAUDE_QUOTA_MIN_UTILIZATION_FOR_PROJECTION",
+		o.MinProjection, o.MinProjection >= 0, validMinProjection)
 	applyFloatOverride(&cfg.FontSize, "CLAUDE_QUOTA_FONT_SIZE", o.FontSize, o.FontSize > 0,
 		func(f float64) bool { return f > 0 })
 	applyStringOverride(&cfg.FontName, "CLAUDE_QUOTA_FONT_NAME", "font-name", o.FontName, ValidFontName)
@@ -248,7 +708,24 @@ func applyOverrides(cfg *Config, o overrides) {
 		func(f float64) bool { return f >= 0 })
 	applyIntOverride(&cfg.IconSize, "CLAUDE_QUOTA_ICON_SIZE", o.IconSize,
 		func(i int) bool { return i > 0 })
+	alignIconSize(cfg)
 	applyStringOverride(&cfg.Indicator, "CLAUDE_QUOTA_INDICATOR", "indicator", o.Indicator, ValidIndicatorName)
+	applyStringOverride(&cfg.ColorSource, "CLAUDE_QUOTA_COLOR_SOURCE", "color-source", o.ColorSource, ValidColorSource)
+	applyStringOverride(&cfg.IconBackgroundColor, "CLAUDE_QUOTA_ICON_BACKGROUND_COLOR", "icon-background-color", o.IconBackground, ValidColor)
+	applyStringOverride(&cfg.IconWindow, "CLAUDE_QUOTA_ICON_WINDOW", "icon-window", o.IconWindow, ValidIconWindow)
+	applyStringOverride(&cfg.TimeDisplay, "CLAUDE_QUOTA_TIME_DISPLAY", "time-display", o.TimeDisplay, ValidTimeDisplay)
+	applyStringOverride(&cfg.APIURL, "CLAUDE_QUOTA_API_URL", "api-url", o.APIURL, ValidAPIURL)
+	// AnthropicVersion: the empty string is meaningful (omit the header), so
+	// an explicitly set env var or flag applies even when empty.
+	if v, ok := os.LookupEnv("CLAUDE_QUOTA_ANTHROPIC_VERSION"); ok {
+		cfg.AnthropicVersion = v
+	}
+	if o.AnthropicVersion != nil {
+		cfg.AnthropicVersion = *o.AnthropicVersion
+	}
+	applyStringOverride(&cfg.LogFile, "CLAUDE_QUOTA_LOG_FILE", "log-file", o.LogFile,
+		func(string) bool { return true })
+	applyStringOverride(&cfg.LogFormat, "CLAUDE_QUOTA_LOG_FORMAT", "log-format", o.LogFormat, ValidLogFormat)
 
 	// ShowText: unique tri-state parsing (true/1, false/0).
 	if v := os.Getenv("CLAUDE_QUOTA_SHOW_TEXT"); v != "" {
@@ -260,7 +737,7 @@ func applyOverrides(cfg *Config, o overrides) {
 			b := false
 			cfg.ShowText = &b
 		default:
-			log.Printf("Ignoring invalid CLAUDE_QUOTA_SHOW_TEXT=%q", v)
+			slog.Warn("Ignoring invalid environment variable", "name", "CLAUDE_QUOTA_SHOW_TEXT", "value", v)
 		}
 	}
 	if o.ShowText != nil {
@@ -275,7 +752,7 @@ func applyOverrides(cfg *Config, o overrides) {
 		case "false", "0":
 			cfg.ShowAccount = false
 		default:
-			log.Printf("Ignoring invalid CLAUDE_QUOTA_SHOW_ACCOUNT=%q", v)
+			slog.Warn("Ignoring invalid environment variable", "name", "CLAUDE_QUOTA_SHOW_ACCOUNT", "value", v)
 		}
 	}
 	if o.ShowAccount != nil {
@@ -290,7 +767,7 @@ func applyOverrides(cfg *Config, o overrides) {
 		case "false", "0":
 			cfg.Stats = false
 		default:
-			log.Printf("Ignoring invalid CLAUDE_QUOTA_STATS=%q", v)
+			slog.Warn("Ignoring invalid environment variable", "name", "CLAUDE_QUOTA_STATS", "value", v)
 		}
 	}
 	if o.Stats != nil {
@@ -300,7 +777,7 @@ func applyOverrides(cfg *Config, o overrides) {
 	// Thresholds: cross-field validation, kept inline.
 	if v := os.Getenv("CLAUDE_QUOTA_WARNING_THRESHOLD"); v != "" {
 		if f, err := strconv.ParseFloat(v, 64); err != nil || f <= 0 || f > 100 {
-			log.Printf("Ignoring invalid CLAUDE_QUOTA_WARNING_THRESHOLD=%q", v)
+			slog.Warn("Ignoring invalid environment variable", "name", "CLAUDE_QUOTA_WARNING_THRESHOLD", "value", v)
 		} else {
 			cfg.Thresholds.Warning = f
 		}
@@ -308,12 +785,12 @@ func applyOverrides(cfg *Config, o overrides) {
 	if o.WarningThreshold > 0 && o.WarningThreshold <= 100 {
 		cfg.Thresholds.Warning = o.WarningThreshold
 	} else if o.WarningThreshold > 100 {
-		log.Printf("Ignoring invalid -warning-threshold=%.0f (must be 1-100)", o.WarningThreshold)
+		slog.Warn("Ignoring invalid flag (must be 1-100)", "name", "-warning-threshold", "value", o.WarningThreshold)
 	}
 
 	if v := os.Getenv("CLAUDE_QUOTA_CRITICAL_THRESHOLD"); v != "" {
 		if f, err := strconv.ParseFloat(v, 64); err != nil || f <= 0 || f > 100 {
-			log.Printf("Ignoring invalid CLAUDE_QUOTA_CRITICAL_THRESHOLD=%q", v)
+			slog.Warn("Ignoring invalid environment variable", "name", "CLAUDE_QUOTA_CRITICAL_THRESHOLD", "value", v)
 		} else {
 			cfg.Thresholds.Critical = f
 		}
@@ -321,11 +798,11 @@ func applyOverrides(cfg *Config, o overrides) {
 	if o.CriticalThreshold > 0 && o.CriticalThreshold <= 100 {
 		cfg.Thresholds.Critical = o.CriticalThreshold
 	} else if o.CriticalThreshold > 100 {
-		log.Printf("Ignoring invalid -critical-threshold=%.0f (must be 1-100)", o.CriticalThreshold)
+		slog.Warn("Ignoring invalid flag (must be 1-100)", "name", "-critical-threshold", "value", o.CriticalThreshold)
 	}
 
 	if cfg.Thresholds.Warning >= cfg.Thresholds.Critical {
-		log.Printf("Warning threshold (%.0f) >= critical threshold (%.0f), swapping", cfg.Thresholds.Warning, cfg.Thresholds.Critical)
+		slog.Warn("Warning threshold >= critical threshold, swapping", "warning", cfg.Thresholds.Warning, "critical", cfg.Thresholds.Critical)
 		cfg.Thresholds.Warning, cfg.Thresholds.Critical = cfg.Thresholds.Critical, cfg.Thresholds.Warning
 	}
 }
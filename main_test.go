@@ -1,12 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 // noOverrides is the zero-value overrides struct that changes nothing.
-// HaloSize -1 means "not set" (0 is a valid value that disables halo).
-var noOverrides = overrides{HaloSize: -1}
+// HaloSize, PollJitter and MinProjection -1 mean "not set" (0 is a valid value).
+var noOverrides = overrides{HaloSize: -1, PollJitter: -1, MinProjection: -1}
 
 func TestApplyOverrides_Defaults(t *testing.T) {
 	cfg := defaultConfig()
@@ -267,6 +276,14 @@ func TestApplyOverrides_IndicatorFlagOverridesEnv(t *testing.T) {
 	}
 }
 
+func TestApplyOverrides_IndicatorBarDual(t *testing.T) {
+	cfg := defaultConfig()
+	applyOverrides(&cfg, overrides{HaloSize: -1, Indicator: "bar-dual"})
+	if cfg.Indicator != "bar-dual" {
+		t.Errorf("Indicator = %q, want %q", cfg.Indicator, "bar-dual")
+	}
+}
+
 func TestApplyOverrides_IndicatorInvalidEnvIgnored(t *testing.T) {
 	t.Setenv("CLAUDE_QUOTA_INDICATOR", "gauge")
 	cfg := defaultConfig()
@@ -284,6 +301,183 @@ func TestApplyOverrides_IndicatorInvalidFlagIgnored(t *testing.T) {
 	}
 }
 
+func TestApplyOverrides_ColorSource(t *testing.T) {
+	t.Setenv("CLAUDE_QUOTA_COLOR_SOURCE", "projected")
+	cfg := defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.ColorSource != "projected" {
+		t.Errorf("ColorSource = %q, want %q (env)", cfg.ColorSource, "projected")
+	}
+	applyOverrides(&cfg, overrides{HaloSize: -1, ColorSource: "current"})
+	if cfg.ColorSource != "current" {
+		t.Errorf("ColorSource = %q, want %q (flag should override env)", cfg.ColorSource, "current")
+	}
+	applyOverrides(&cfg, overrides{HaloSize: -1, ColorSource: "bogus"})
+	if cfg.ColorSource != "projected" {
+		t.Errorf("ColorSource = %q, want %q (invalid flag ignored, env applies)", cfg.ColorSource, "projected")
+	}
+}
+
+func TestApplyOverrides_APIURL(t *testing.T) {
+	t.Setenv("CLAUDE_QUOTA_API_URL", "http://localhost:9000/usage")
+	cfg := defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.APIURL != "http://localhost:9000/usage" {
+		t.Errorf("APIURL = %q, want env value", cfg.APIURL)
+	}
+	applyOverrides(&cfg, overrides{HaloSize: -1, APIURL: "https://proxy.example/usage"})
+	if cfg.APIURL != "https://proxy.example/usage" {
+		t.Errorf("APIURL = %q, want flag value", cfg.APIURL)
+	}
+	applyOverrides(&cfg, overrides{HaloSize: -1, APIURL: "not a url"})
+	if cfg.APIURL != "http://localhost:9000/usage" {
+		t.Errorf("APIURL = %q, want env value (invalid flag ignored)", cfg.APIURL)
+	}
+}
+
+func TestApplyOverrides_IconSizeRounded(t *testing.T) {
+	cfg := defaultConfig()
+	o := noOverrides
+	o.IconSize = 65
+	applyOverrides(&cfg, o)
+	if cfg.IconSize != 72 {
+		t.Errorf("IconSize = %d, want 72", cfg.IconSize)
+	}
+}
+
+func TestApplyOverrides_LogFile(t *testing.T) {
+	t.Setenv("CLAUDE_QUOTA_LOG_FILE", "/tmp/env.log")
+	cfg := defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.LogFile != "/tmp/env.log" {
+		t.Errorf("LogFile = %q, want env value", cfg.LogFile)
+	}
+	o := noOverrides
+	o.LogFile = "/tmp/flag.log"
+	applyOverrides(&cfg, o)
+	if cfg.LogFile != "/tmp/flag.log" {
+		t.Errorf("LogFile = %q, want flag value", cfg.LogFile)
+	}
+}
+
+func TestApplyOverrides_LogFormat(t *testing.T) {
+	t.Setenv("CLAUDE_QUOTA_LOG_FORMAT", "text")
+	cfg := defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.LogFormat != "text" {
+		t.Errorf("LogFormat = %q, want env value", cfg.LogFormat)
+	}
+	o := noOverrides
+	o.LogFormat = "json"
+	applyOverrides(&cfg, o)
+	if cfg.LogFormat != "json" {
+		t.Errorf("LogFormat = %q, want flag value", cfg.LogFormat)
+	}
+	o.LogFormat = "xml"
+	applyOverrides(&cfg, o)
+	if cfg.LogFormat != "text" {
+		t.Errorf("LogFormat = %q, invalid flag should fall back to env value", cfg.LogFormat)
+	}
+}
+
+func TestApplyOverrides_AnthropicVersion(t *testing.T) {
+	cfg := defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.AnthropicVersion != defaultAnthropicVersion {
+		t.Errorf("AnthropicVersion = %q, want default", cfg.AnthropicVersion)
+	}
+
+	t.Setenv("CLAUDE_QUOTA_ANTHROPIC_VERSION", "")
+	applyOverrides(&cfg, noOverrides)
+	if cfg.AnthropicVersion != "" {
+		t.Errorf("AnthropicVersion = %q, want empty from env", cfg.AnthropicVersion)
+	}
+
+	o := noOverrides
+	v := "2024-01-01"
+	o.AnthropicVersion = &v
+	applyOverrides(&cfg, o)
+	if cfg.AnthropicVersion != v {
+		t.Errorf("AnthropicVersion = %q, want flag value", cfg.AnthropicVersion)
+	}
+}
+
+func TestApplyOverrides_PollJitter(t *testing.T) {
+	t.Setenv("CLAUDE_QUOTA_POLL_JITTER", "30")
+	cfg := defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.PollJitterSeconds != 30 {
+		t.Errorf("PollJitterSeconds = %d, want 30 (env)", cfg.PollJitterSeconds)
+	}
+	applyOverrides(&cfg, overrides{HaloSize: -1, PollJitter: 0})
+	if cfg.PollJitterSeconds != 0 {
+		t.Errorf("PollJitterSeconds = %d, want 0 (flag disables jitter)", cfg.PollJitterSeconds)
+	}
+	t.Setenv("CLAUDE_QUOTA_POLL_JITTER", "-5")
+	cfg = defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.PollJitterSeconds != 10 {
+		t.Errorf("PollJitterSeconds = %d, want default 10 (invalid env ignored)", cfg.PollJitterSeconds)
+	}
+}
+
+func TestApplyOverrides_Timeout(t *testing.T) {
+	t.Setenv("CLAUDE_QUOTA_TIMEOUT", "60")
+	cfg := defaultConfig()
+	applyOverrides(&cfg, noOverrides)
+	if cfg.TimeoutSeconds != 60 {
+		t.Errorf("TimeoutSeconds = %d, want 60 (env)", cfg.TimeoutSeconds)
+	}
+	applyOverrides(&cfg, overrides{HaloSize: -1, PollJitter: -1, Timeout: 5})
+	if cfg.TimeoutSeconds != 5 {
+		t.Errorf("TimeoutSeconds = %d, want 5 (flag)", cfg.TimeoutSeconds)
+	}
+	applyOverrides(&cfg, overrides{HaloSize: -1, PollJitter: -1, Timeout: 301})
+	if cfg.TimeoutSeconds != 60 {
+		t.Errorf("TimeoutSeconds = %d, want 60 (out-of-range flag ignored)", cfg.TimeoutSeconds)
+	}
+}
+
+func TestNewHTTPClient_Proxy(t *testing.T) {
+	for _, envKey := range []string{"HTTPS_PROXY", "ALL_PROXY"} {
+		t.Run(envKey, func(t *testing.T) {
+			var gotMethod, gotHost string
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod, gotHost = r.Method, r.Host
+				w.WriteHeader(http.StatusBadGateway)
+			}))
+			defer proxy.Close()
+
+			for _, k := range []string{"HTTPS_PROXY", "https_proxy", "ALL_PROXY", "all_proxy", "NO_PROXY", "no_proxy"} {
+				t.Setenv(k, "")
+			}
+			t.Setenv(envKey, proxy.URL)
+
+			// localhost is never proxied, so target a name that is only
+			// reachable through the proxy.
+			qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000,
+				newHTTPClient(defaultConfig()), "https://quota.example.test/usage")
+			if qc.FetchOnce() {
+				t.Fatal("FetchOnce() succeeded through a proxy that refuses CONNECT")
+			}
+			if gotMethod != http.MethodConnect || gotHost != "quota.example.test:443" {
+				t.Errorf("proxy got %s %s, want CONNECT quota.example.test:443", gotMethod, gotHost)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClient_Timeout(t *testing.T) {
+	cfg := defaultConfig()
+	if got := newHTTPClient(cfg).Timeout; got != FetchTimeout {
+		t.Errorf("default Timeout = %v, want %v", got, FetchTimeout)
+	}
+	cfg.TimeoutSeconds = 5
+	if got := newHTTPClient(cfg).Timeout; got != 5*time.Second {
+		t.Errorf("Timeout = %v, want 5s", got)
+	}
+}
+
 func boolPtr(b bool) *bool { return &b }
 
 func TestApplyOverrides_ShowTextFlag(t *testing.T) {
@@ -444,3 +638,313 @@ func TestConfigShowText_False(t *testing.T) {
 		t.Errorf("configShowText(false) = %v, want false", configShowText(cfg))
 	}
 }
+
+func TestRunDryRun_Valid(t *testing.T) {
+	origConfig := configPath
+	origCreds := credentialsPath
+	defer func() {
+		configPath = origConfig
+		credentialsPath = origCreds
+	}()
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configPath, []byte(`{"font_name": "mono", "indicator": "arc"}`), 0600)
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()+3_600_000)
+	t.Setenv("CLAUDE_QUOTA_POLL_INTERVAL", "120")
+
+	load := loadConfigDetails()
+	cfg := load.Config
+	applyOverrides(&cfg, noOverrides)
+
+	var buf bytes.Buffer
+	if code := runDryRun(&buf, cfg, load.Raw); code != 0 {
+		t.Fatalf("runDryRun() = %d, want 0; output:\n%s", code, buf.String())
+	}
+	out := buf.String()
+	for _, want := range []string{
+		configPath,
+		credentialsPath,
+		`"poll_interval_seconds": 120`,
+		`"font_name": "mono"`,
+		`"indicator": "arc"`,
+		"Credentials: OK",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dry-run output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunDryRun_InvalidConfig(t *testing.T) {
+	origConfig := configPath
+	origCreds := credentialsPath
+	defer func() {
+		configPath = origConfig
+		credentialsPath = origCreds
+	}()
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(configPath, []byte(`{"ring_gap": -1, "thresholds": {"warning": 90, "critical": 50}}`), 0600)
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()+3_600_000)
+
+	load := loadConfigDetails()
+	var buf bytes.Buffer
+	if code := runDryRun(&buf, load.Config, load.Raw); code != 1 {
+		t.Errorf("runDryRun() = %d, want 1 for an invalid config", code)
+	}
+	for _, want := range []string{
+		"Config error: ring_gap: must be 0-8, got -1",
+		"Config warning: thresholds.warning (90) >= thresholds.critical (50), they are swapped",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("dry-run output missing %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "Credentials:") {
+		t.Errorf("dry-run should stop before credentials:\n%s", buf.String())
+	}
+}
+
+func TestRunDryRun_ExpiredToken(t *testing.T) {
+	origConfig := configPath
+	origCreds := credentialsPath
+	defer func() {
+		configPath = origConfig
+		credentialsPath = origCreds
+	}()
+
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()-1000)
+
+	var buf bytes.Buffer
+	if code := runDryRun(&buf, defaultConfig(), defaultConfig()); code != 1 {
+		t.Errorf("runDryRun() = %d, want 1 for expired token", code)
+	}
+	if !strings.Contains(buf.String(), "expired") {
+		t.Errorf("dry-run output should mention expiry:\n%s", buf.String())
+	}
+}
+
+func TestRunDryRun_MissingCredentials(t *testing.T) {
+	origCreds := credentialsPath
+	defer func() { credentialsPath = origCreds }()
+
+	credentialsPath = filepath.Join(t.TempDir(), "nonexistent.json")
+
+	var buf bytes.Buffer
+	if code := runDryRun(&buf, defaultConfig(), defaultConfig()); code != 1 {
+		t.Errorf("runDryRun() = %d, want 1 for missing credentials", code)
+	}
+}
+
+func TestWriteIconPreview(t *testing.T) {
+	for _, util := range []float64{42, -1} {
+		var out bytes.Buffer
+		if err := writeIconPreview(&out, defaultConfig(), util); err != nil {
+			t.Fatalf("writeIconPreview(%v) error: %v", util, err)
+		}
+		if !bytes.HasPrefix(out.Bytes(), []byte("\x89PNG")) {
+			t.Errorf("writeIconPreview(%v) output does not start with PNG magic: %q", util, out.Bytes()[:min(8, out.Len())])
+		}
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"five_hour": {"utilization": 50.0}, "seven_day": {"utilization": 10.0}}`))
+	}))
+	defer srv.Close()
+
+	origCreds := credentialsPath
+	defer func() { credentialsPath = origCreds }()
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()+3_600_000)
+
+	cfg := defaultConfig()
+	cfg.APIURL = srv.URL
+
+	var buf bytes.Buffer
+	if code := runSummary(&buf, cfg, "", ""); code != 0 {
+		t.Fatalf("runSummary() = %d, want 0; output:\n%s", code, buf.String())
+	}
+	if !strings.Contains(buf.String(), "50%") || !strings.Contains(buf.String(), "10%") {
+		t.Errorf("summary missing utilization values:\n%s", buf.String())
+	}
+}
+
+func TestSetupApp_Quiet(t *testing.T) {
+	origCreds := credentialsPath
+	defer func() { credentialsPath = origCreds }()
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()+3_600_000)
+
+	for _, tc := range []struct {
+		env       string
+		wantQuiet bool
+	}{
+		{"", false},
+		{"0", false},
+		{"1", true},
+		{"true", true},
+	} {
+		t.Run("CLAUDE_QUOTA_QUIET="+tc.env, func(t *testing.T) {
+			t.Setenv("CLAUDE_QUOTA_QUIET", tc.env)
+			var buf bytes.Buffer
+			if _, err := setupApp(&buf, defaultConfig(), "127.0.0.1:0"); err != nil {
+				t.Fatalf("setupApp() error: %v", err)
+			}
+
+			out := buf.String()
+			if tc.wantQuiet && out != "" {
+				t.Errorf("quiet startup printed:\n%s", out)
+			}
+			for _, line := range []string{"WARNING:", "Terms of Service", "Credentials: " + credentialsPath, "Config: " + configPath,
+				"Stats: disabled", "Healthcheck: http://127.0.0.1:0/health"} {
+				if got := strings.Contains(out, line); got == tc.wantQuiet {
+					t.Errorf("output contains %q = %v, want %v; output:\n%s", line, got, !tc.wantQuiet, out)
+				}
+			}
+		})
+	}
+}
+
+func TestCompareAccounts(t *testing.T) {
+	newServer := func(body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(body))
+		}))
+	}
+	srv1 := newServer(`{"five_hour": {"utilization": 42.0}, "seven_day": {"utilization": 10.0}}`)
+	defer srv1.Close()
+	srv2 := newServer(`{"five_hour": {"utilization": 78.0}, "seven_day": {"utilization": 55.0}}`)
+	defer srv2.Close()
+
+	expires := time.Now().UnixMilli() + 3_600_000
+	creds1, err := NewOAuthCredentialsFromFile(writeTestCredentials(t, "token-1", expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds2, err := NewOAuthCredentialsFromFile(writeTestCredentials(t, "token-2", expires))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	code := compareAccounts(&buf,
+		NewQuotaClient(creds1, srv1.Client(), srv1.URL, defaultAnthropicVersion, defaultMinProjectionUtilization),
+		NewQuotaClient(creds2, srv2.Client(), srv2.URL, defaultAnthropicVersion, defaultMinProjectionUtilization))
+	if code != 0 {
+		t.Errorf("compareAccounts() = %d, want 0", code)
+	}
+	want := "Account 1: 5h=42%, 7d=10% | Account 2: 5h=78%, 7d=55%\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCompareAccounts_FetchError(t *testing.T) {
+	noFetchRetryDelay(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	creds := &OAuthCredentials{accessToken: "tok"}
+	var buf bytes.Buffer
+	code := compareAccounts(&buf, NewQuotaClient(creds, srv.Client(), srv.URL, "", 0))
+	if code != 1 || !strings.HasPrefix(buf.String(), "Account 1: error: ") {
+		t.Errorf("compareAccounts() = %d, %q; want 1 and an error line", code, buf.String())
+	}
+}
+
+func TestRunAssertQuota(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"five_hour": {"utilization": 73.0}, "seven_day": {"utilization": 20.0}}`))
+	}))
+	defer srv.Close()
+
+	origCreds := credentialsPath
+	defer func() { credentialsPath = origCreds }()
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()+3_600_000)
+
+	cfg := defaultConfig()
+	cfg.APIURL = srv.URL
+
+	tests := []struct {
+		limit    float64
+		window   string
+		wantCode int
+		wantOut  string
+	}{
+		{80, "5h", 0, "5h quota is 73% (limit 80%)\n"},
+		{50, "5h", 2, "5h quota is 73% (limit 50%)\n"},
+		{73, "5h", 2, "5h quota is 73% (limit 73%)\n"},
+		{50, "7d", 0, "7d quota is 20% (limit 50%)\n"},
+		{50, "1h", 1, "Invalid quota window \"1h\", want one of: 5h, 7d\n"},
+	}
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		if code := runAssertQuota(&buf, cfg, tc.limit, tc.window); code != tc.wantCode {
+			t.Errorf("runAssertQuota(%g, %s) = %d, want %d", tc.limit, tc.window, code, tc.wantCode)
+		}
+		if buf.String() != tc.wantOut {
+			t.Errorf("runAssertQuota(%g, %s) output = %q, want %q", tc.limit, tc.window, buf.String(), tc.wantOut)
+		}
+	}
+}
+
+func TestRunAssertQuota_FetchError(t *testing.T) {
+	noFetchRetryDelay(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	origCreds := credentialsPath
+	defer func() { credentialsPath = origCreds }()
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()+3_600_000)
+
+	cfg := defaultConfig()
+	cfg.APIURL = srv.URL
+
+	var buf bytes.Buffer
+	if code := runAssertQuota(&buf, cfg, 50, "5h"); code != 1 {
+		t.Errorf("runAssertQuota() = %d, want 1; output: %q", code, buf.String())
+	}
+}
+
+func TestTranslateSubcommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"status"}, []string{"-summary"}},
+		{[]string{"status", "-api-url", "http://x"}, []string{"-summary", "-api-url", "http://x"}},
+		{[]string{"update"}, []string{"-update"}},
+		{[]string{"version"}, []string{"-version"}},
+		{[]string{"-poll-interval", "60"}, []string{"-poll-interval", "60"}},
+		{[]string{"-stats", "status"}, []string{"-stats", "status"}},
+	}
+	for _, tc := range tests {
+		if got := translateSubcommand(tc.args); !slices.Equal(got, tc.want) {
+			t.Errorf("translateSubcommand(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestTranslateSubcommand_SetsFlag(t *testing.T) {
+	for sub, name := range map[string]string{"status": "summary", "update": "update", "version": "version"} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		summary := fs.Bool("summary", false, "")
+		update := fs.Bool("update", false, "")
+		version := fs.Bool("version", false, "")
+		if err := fs.Parse(translateSubcommand([]string{sub})); err != nil {
+			t.Fatalf("%s: parse error: %v", sub, err)
+		}
+		got := map[string]bool{"summary": *summary, "update": *update, "version": *version}
+		for n, set := range got {
+			if set != (n == name) {
+				t.Errorf("%s: -%s = %v, want %v", sub, n, set, n == name)
+			}
+		}
+	}
+}
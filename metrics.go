@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPushgatewayJob is the Pushgateway job name used when -pushgateway-job is unset.
+const defaultPushgatewayJob = "claude_quota"
+
+// errPushgatewayWithoutSummary is returned by checkPushgatewayFlags when
+// -pushgateway-url is set without -summary, the only mode that pushes.
+var errPushgatewayWithoutSummary = errors.New("-pushgateway-url requires -summary")
+
+// checkPushgatewayFlags rejects a -pushgateway-url that would be ignored.
+func checkPushgatewayFlags(pushURL string, summary bool) error {
+	if pushURL != "" && !summary {
+		return errPushgatewayWithoutSummary
+	}
+	return nil
+}
+
+// formatPrometheusMetrics renders state in the Prometheus text exposition
+// format: utilization, projection and reset time per window, labelled with
+// the API bucket name, plus the duration of the fetch that produced state.
+// Windows without data, and a zero fetchDuration, are left out.
+func formatPrometheusMetrics(state QuotaState, fetchDuration time.Duration) string {
+	type window struct {
+		bucket    string
+		util      *Utilization
+		projected *Utilization
+		resets    *time.Time
+	}
+	windows := []window{
+		{"five_hour", state.FiveHour, state.FiveHourProjected, state.FiveHourResets},
+		{"seven_day", state.SevenDay, state.SevenDayProjected, state.SevenDayResets},
+		{"seven_day_sonnet", state.SevenDaySonnet, nil, state.SevenDaySonnetResets},
+	}
+
+	var b strings.Builder
+	gauge := func(name, help string, value func(w window) (float64, bool)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, w := range windows {
+			if v, ok := value(w); ok {
+				fmt.Fprintf(&b, "%s{bucket=%q} %s\n", name, w.bucket, strconv.FormatFloat(v, 'g', -1, 64))
+			}
+		}
+	}
+	gauge("claude_quota_utilization_percent", "Current quota utilization in percent.", func(w window) (float64, bool) {
+		if w.util == nil {
+			return 0, false
+		}
+		return float64(*w.util), true
+	})
+	gauge("claude_quota_projected_utilization_percent", "Utilization projected at the window reset, in percent.", func(w window) (float64, bool) {
+		if w.projected == nil {
+			return 0, false
+		}
+		return float64(*w.projected), true
+	})
+	gauge("claude_quota_resets_at_seconds", "Unix time at which the quota window resets.", func(w window) (float64, bool) {
+		if w.resets == nil {
+			return 0, false
+		}
+		return float64(w.resets.Unix()), true
+	})
+	const durationName = "claude_quota_fetch_duration_seconds"
+	fmt.Fprintf(&b, "# HELP %s Duration of the quota API request in seconds.\n# TYPE %s gauge\n", durationName, durationName)
+	if fetchDuration > 0 {
+		fmt.Fprintf(&b, "%s %s\n", durationName, strconv.FormatFloat(fetchDuration.Seconds(), 'g', -1, 64))
+	}
+	return b.String()
+}
+
+// PushToGateway replaces the metrics of job on the Prometheus Pushgateway at
+// gatewayURL with those of state and fetchDuration, via
+// PUT <gatewayURL>/metrics/job/<job>. client should come from newHTTPClient
+// so the push honors the proxy and timeout_seconds.
+func PushToGateway(client *http.Client, gatewayURL, job string, state QuotaState, fetchDuration time.Duration) error {
+	target := strings.TrimRight(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequest(http.MethodPut, target, bytes.NewBufferString(formatPrometheusMetrics(state, fetchDuration)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	req.Header.Set("User-Agent", "claude-quota/"+Version)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("pushgateway returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatPrometheusMetrics(t *testing.T) {
+	five, proj, seven := Utilization(42.5), Utilization(110), Utilization(10)
+	resets := time.Unix(1_800_000_000, 0)
+	state := QuotaState{FiveHour: &five, FiveHourProjected: &proj, FiveHourResets: &resets, SevenDay: &seven}
+
+	want := `# HELP claude_quota_utilization_percent Current quota utilization in percent.
+# TYPE claude_quota_utilization_percent gauge
+claude_quota_utilization_percent{bucket="five_hour"} 42.5
+claude_quota_utilization_percent{bucket="seven_day"} 10
+# HELP claude_quota_projected_utilization_percent Utilization projected at the window reset, in percent.
+# TYPE claude_quota_projected_utilization_percent gauge
+claude_quota_projected_utilization_percent{bucket="five_hour"} 110
+# HELP claude_quota_resets_at_seconds Unix time at which the quota window resets.
+# TYPE claude_quota_resets_at_seconds gauge
+claude_quota_resets_at_seconds{bucket="five_hour"} 1.8e+09
+# HELP claude_quota_fetch_duration_seconds Duration of the quota API request in seconds.
+# TYPE claude_quota_fetch_duration_seconds gauge
+claude_quota_fetch_duration_seconds 0.25
+`
+	if got := formatPrometheusMetrics(state, 250*time.Millisecond); got != want {
+		t.Errorf("formatPrometheusMetrics() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPushToGateway(t *testing.T) {
+	var method, path, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, contentType = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	five := Utilization(42)
+	if err := PushToGateway(srv.Client(), srv.URL+"/", "my job", QuotaState{FiveHour: &five}, time.Second); err != nil {
+		t.Fatalf("PushToGateway() error: %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/my%20job" {
+		t.Errorf("request = %s %s, want PUT /metrics/job/my%%20job", method, path)
+	}
+	if !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", contentType)
+	}
+	if !strings.Contains(body, `claude_quota_utilization_percent{bucket="five_hour"} 42`+"\n") {
+		t.Errorf("body missing the 5h utilization sample:\n%s", body)
+	}
+	if !strings.Contains(body, "claude_quota_fetch_duration_seconds 1\n") {
+		t.Errorf("body missing the fetch duration sample:\n%s", body)
+	}
+}
+
+func TestPushToGateway_UsesProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	t.Setenv("NO_PROXY", "")
+
+	if err := PushToGateway(newHTTPClient(defaultConfig()), "http://pushgateway.example.test:9091", "job", QuotaState{}, 0); err != nil {
+		t.Fatalf("PushToGateway() error: %v", err)
+	}
+	if want := "http://pushgateway.example.test:9091/metrics/job/job"; proxied != want {
+		t.Errorf("proxied request = %q, want %q", proxied, want)
+	}
+}
+
+func TestCheckPushgatewayFlags(t *testing.T) {
+	if err := checkPushgatewayFlags("http://pushgateway:9091", false); err != errPushgatewayWithoutSummary {
+		t.Errorf("url without -summary: error = %v, want %v", err, errPushgatewayWithoutSummary)
+	}
+	for _, tc := range []struct {
+		url     string
+		summary bool
+	}{{"http://pushgateway:9091", true}, {"", false}, {"", true}} {
+		if err := checkPushgatewayFlags(tc.url, tc.summary); err != nil {
+			t.Errorf("checkPushgatewayFlags(%q, %v) = %v, want nil", tc.url, tc.summary, err)
+		}
+	}
+}
+
+func TestPushToGateway_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	if err := PushToGateway(srv.Client(), srv.URL, "job", QuotaState{}, 0); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("PushToGateway() error = %v, want HTTP 400", err)
+	}
+}
+
+func TestRunSummary_PushFailureKeepsOutput(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"five_hour": {"utilization": 50.0}}`))
+	}))
+	defer srv.Close()
+	// A closed server: the push fails with a connection error.
+	gateway := httptest.NewServer(http.NotFoundHandler())
+	gateway.Close()
+
+	origCreds := credentialsPath
+	defer func() { credentialsPath = origCreds }()
+	credentialsPath = writeTestCredentials(t, "test-token", time.Now().UnixMilli()+3_600_000)
+
+	cfg := defaultConfig()
+	cfg.APIURL = srv.URL
+	logs := captureSlog(t)
+
+	var buf bytes.Buffer
+	if code := runSummary(&buf, cfg, gateway.URL, "job"); code != 0 {
+		t.Fatalf("runSummary() = %d, want 0; output:\n%s", code, buf.String())
+	}
+	if !strings.Contains(buf.String(), "50%") {
+		t.Errorf("summary missing utilization:\n%s", buf.String())
+	}
+	if !strings.Contains(logs.String(), "Pushgateway push failed") {
+		t.Errorf("push failure not logged:\n%s", logs)
+	}
+}
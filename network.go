@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// isOfflineError reports whether err, returned by an HTTP request, means the
+// API host or proxy could not be reached at all: a DNS lookup that timed out
+// or failed temporarily, or a failed dial. An unknown host (NXDOMAIN) is
+// answered by a working resolver, and a refused connection proves a route
+// exists, so neither is offline.
+func isOfflineError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return !dnsErr.IsNotFound
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
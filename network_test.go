@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetch_NoNetwork(t *testing.T) {
+	// Offline, name lookups time out before any connection is tried.
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(context.Context, string, string) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Net: "tcp",
+				Err: &net.DNSError{Err: "i/o timeout", Name: "quota.example.test", IsTimeout: true}}
+		},
+	}}
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, client, "http://quota.example.test/usage")
+	if qc.FetchOnce() {
+		t.Fatal("FetchOnce() should fail when the host cannot be resolved")
+	}
+	state := qc.State()
+	if state.Error != errMsgNoNetwork {
+		t.Errorf("Error = %q, want %q", state.Error, errMsgNoNetwork)
+	}
+	if state.ErrorType != ErrTypeNetwork {
+		t.Errorf("ErrorType = %q, want %q", state.ErrorType, ErrTypeNetwork)
+	}
+}
+
+func TestFetch_UnknownHostIsNotOffline(t *testing.T) {
+	// .invalid never resolves: a mistyped api_url, not a lost network.
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{}, "http://quota.invalid/usage")
+	if qc.FetchOnce() {
+		t.Fatal("FetchOnce() should fail for an unknown host")
+	}
+	if state := qc.State(); state.Error == errMsgNoNetwork || state.ErrorType != ErrTypeNetwork {
+		t.Errorf("state = %q (%s), want a network error other than %q", state.Error, state.ErrorType, errMsgNoNetwork)
+	}
+}
+
+func TestFetch_ConnectionRefusedIsNotOffline(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{}, url)
+	if qc.FetchOnce() {
+		t.Fatal("FetchOnce() should fail against a closed server")
+	}
+	if state := qc.State(); state.Error == errMsgNoNetwork || state.ErrorType != ErrTypeNetwork {
+		t.Errorf("state = %q (%s), want a network error other than %q", state.Error, state.ErrorType, errMsgNoNetwork)
+	}
+}
+
+func TestIsOfflineError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}, true},
+		{"dns temporary", &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}, true},
+		{"unknown host", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "x.invalid", IsNotFound: true}}, false},
+		{"dial timeout", &net.OpError{Op: "dial", Err: errors.New("i/o timeout")}, true},
+		{"read", &net.OpError{Op: "read", Err: errors.New("reset")}, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := isOfflineError(tt.err); got != tt.want {
+			t.Errorf("%s: isOfflineError() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,22 +1,44 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var usageURL = "https://api.anthropic.com/api/oauth/usage"
+// defaultUsageURL is the Anthropic OAuth usage endpoint, overridable via api_url.
+const defaultUsageURL = "https://api.anthropic.com/api/oauth/usage"
 
 // userAgent is the User-Agent header sent to the Anthropic API.
 // Shared between quota and profile API clients.
 const userAgent = "claude-code/2.0.31"
 
+// defaultMinProjectionUtilization is the utilization (%) below which no
+// projection is computed, overridable via min_utilization_for_projection.
+const defaultMinProjectionUtilization = 5.0
+
+// defaultAnthropicVersion is the anthropic-version header value, overridable
+// via anthropic_version. An empty value omits the header.
+const defaultAnthropicVersion = "2023-06-01"
+
+// FetchTimeout is the default bound on a single quota fetch and the HTTP
+// client timeout, overridable via timeout_seconds.
+const FetchTimeout = 30 * time.Second
+
+// maxTimeoutSeconds caps the configurable timeout.
+const maxTimeoutSeconds = 300
+
 // Error type constants for classifying fetch failures.
 const (
 	ErrTypeCredential = "credential"
@@ -25,23 +47,85 @@ const (
 	ErrTypeParse      = "parse"
 )
 
+// Error messages for HTTP statuses with a known cause.
+const (
+	errMsgTokenInvalid       = "Token invalid \u2014 run 'claude login'"
+	errMsgTokenInvalidDetail = "Token invalid (%s) \u2014 run 'claude login'" // %s: the server's error_description
+	errMsgScopeMissing       = "Scope missing user:profile"
+	errMsgRateLimited        = "Rate limited \u2014 quota API"
+	errMsgNoNetwork          = "No network"
+)
+
 // QuotaState holds the current quota snapshot.
 type QuotaState struct {
-	FiveHour             *float64
-	FiveHourResets       *time.Time
-	FiveHourProjected    *float64   // projected 5h utilization at window reset
-	FiveHourSaturation   *time.Time // projected time when 5h quota hits 100%
-	SevenDay             *float64
-	SevenDayResets       *time.Time
-	SevenDayProjected    *float64   // projected 7d utilization at window reset
-	SevenDaySaturation   *time.Time // projected time when 7d quota hits 100%
-	SevenDaySonnet       *float64
-	SevenDaySonnetResets *time.Time
-	LastUpdate           *time.Time
-	Error                string
-	ErrorType            string // credential, http, network, parse
-	HTTPStatus           int    // HTTP status code when ErrorType is "http"
-	TokenExpired         bool
+	FiveHour              *Utilization
+	FiveHourResets        *time.Time
+	FiveHourProjected     *Utilization // projected 5h utilization at window reset
+	FiveHourProjectedLow  *Utilization // lower bound of the 5h projection, nil when no interval
+	FiveHourProjectedHigh *Utilization // upper bound of the 5h projection, nil when no interval
+	FiveHourSaturation    *time.Time   // projected time when 5h quota hits 100%
+	SevenDay              *Utilization
+	SevenDayResets        *time.Time
+	SevenDayProjected     *Utilization // projected 7d utilization at window reset
+	SevenDaySaturation    *time.Time   // projected time when 7d quota hits 100%
+	SevenDaySonnet        *Utilization
+	SevenDaySonnetResets  *time.Time
+	// Absolute token limit and usage per window, nil unless the API reports them.
+	FiveHourCap              *int64
+	FiveHourTokensUsed       *int64
+	SevenDayCap              *int64
+	SevenDayTokensUsed       *int64
+	SevenDaySonnetCap        *int64
+	SevenDaySonnetTokensUsed *int64
+	LastUpdate               *time.Time
+	TokenExpiresAt           *time.Time // access token expiry, nil when unknown
+	Error                    string
+	ErrorType                string // credential, http, network, parse
+	HTTPStatus               int    // HTTP status code when ErrorType is "http"
+	TokenExpired             bool
+}
+
+// Equal reports whether s and o hold the same values, comparing pointer
+// fields by the values they point to.
+func (s QuotaState) Equal(o QuotaState) bool {
+	return reflect.DeepEqual(s, o)
+}
+
+// sameIcon reports whether s and o render the same icon. Unlike Equal it
+// ignores bookkeeping such as LastUpdate, which changes on every fetch:
+// renderIcon only reads the utilizations, projections, Error and TokenExpired.
+func (s QuotaState) sameIcon(o QuotaState) bool {
+	eq := func(a, b *Utilization) bool {
+		return a == b || (a != nil && b != nil && *a == *b)
+	}
+	return eq(s.FiveHour, o.FiveHour) && eq(s.SevenDay, o.SevenDay) && eq(s.SevenDaySonnet, o.SevenDaySonnet) &&
+		eq(s.FiveHourProjected, o.FiveHourProjected) && eq(s.SevenDayProjected, o.SevenDayProjected) &&
+		s.Error == o.Error && s.TokenExpired == o.TokenExpired
+}
+
+// IsEmpty reports whether the state holds neither utilization data nor an error,
+// e.g. before the first fetch.
+func (s QuotaState) IsEmpty() bool {
+	return s.FiveHour == nil && s.SevenDay == nil && s.SevenDaySonnet == nil && s.Error == ""
+}
+
+// HasData is the complement of IsEmpty.
+func (s QuotaState) HasData() bool {
+	return !s.IsEmpty()
+}
+
+// IsSaturated reports whether the projected 5h saturation time has passed.
+func (s QuotaState) IsSaturated() bool {
+	return s.FiveHourSaturation != nil && time.Now().After(*s.FiveHourSaturation)
+}
+
+// StateAge returns the time elapsed since LastUpdate, i.e. how stale the
+// state is. Zero when never updated.
+func (s QuotaState) StateAge() time.Duration {
+	if s.LastUpdate == nil {
+		return 0
+	}
+	return time.Since(*s.LastUpdate)
 }
 
 // usageResponse matches the JSON returned by the usage API.
@@ -51,27 +135,98 @@ type usageResponse struct {
 	SevenDaySonnet *usageBucket `json:"seven_day_sonnet"`
 }
 
+// usageResponseFields lists usageResponse's JSON names.
+var usageResponseFields = []string{"five_hour", "seven_day", "seven_day_sonnet"}
+
+// decodeUsage parses a usage response in two passes: the top-level keys
+// first, to detect buckets this version does not know, then the buckets.
+// In strict mode unknown fields, nested ones included, are an error;
+// otherwise unknown top-level fields are logged once per distinct set.
+func (qc *QuotaClient) decodeUsage(body []byte) (usageResponse, error) {
+	var data usageResponse
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return data, err
+	}
+	var unknown []string
+	for key := range raw {
+		if !slices.Contains(usageResponseFields, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+
+	qc.mu.Lock()
+	strict := qc.strict
+	warn := len(unknown) > 0 && !strict && strings.Join(unknown, ",") != qc.unknown
+	qc.unknown = strings.Join(unknown, ",")
+	qc.mu.Unlock()
+
+	if strict {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&data)
+		return data, err
+	}
+	if warn {
+		slog.Warn("Unknown fields in usage response, an update may be needed to show them", "fields", unknown)
+	}
+	err := json.Unmarshal(body, &data)
+	return data, err
+}
+
 type usageBucket struct {
 	Utilization *float64 `json:"utilization"`
 	ResetsAt    *string  `json:"resets_at"`
+	Cap         *int64   `json:"cap"`         // absolute limit, not sent by the API yet
+	TokensUsed  *int64   `json:"tokens_used"` // absolute usage, not sent by the API yet
+}
+
+// QuotaStats counts fetch attempts since the client was created.
+type QuotaStats struct {
+	FetchCount      int64 // all fetches, successful or not
+	FetchErrorCount int64 // fetches that failed
 }
 
 // QuotaClient fetches and stores quota state.
 type QuotaClient struct {
-	mu     sync.RWMutex
-	state  QuotaState
-	creds  *OAuthCredentials
-	client *http.Client
+	mu       sync.RWMutex
+	state    QuotaState
+	creds    *OAuthCredentials
+	client   *http.Client
+	url      string      // usage endpoint
+	version  string      // anthropic-version header, omitted when empty
+	minProj  Utilization // utilization below which no projection is computed
+	lastETag string      // ETag of the last successful response, sent as If-None-Match
+	strict   bool        // reject responses with unknown fields
+	unknown  string      // unknown top-level fields last warned about, to warn once per set
+
+	fetchCount        atomic.Int64
+	fetchErrorCount   atomic.Int64
+	lastFetchDuration atomic.Int64 // time.Duration of the last HTTP round trip, successful or not
 }
 
-// NewQuotaClient creates a new quota client.
-func NewQuotaClient(creds *OAuthCredentials, client *http.Client) *QuotaClient {
+// NewQuotaClient creates a new quota client fetching from url, sending
+// version as the anthropic-version header unless it is empty. Projections
+// are skipped while utilization is below minProjection.
+func NewQuotaClient(creds *OAuthCredentials, client *http.Client, url, version string, minProjection Utilization) *QuotaClient {
 	return &QuotaClient{
-		creds:  creds,
-		client: client,
+		creds:   creds,
+		client:  client,
+		url:     url,
+		version: version,
+		minProj: minProjection,
 	}
 }
 
+// SetStrictJSON makes fetches fail on unknown fields in the API response
+// instead of only warning about unknown top-level fields.
+func (qc *QuotaClient) SetStrictJSON(strict bool) {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	qc.strict = strict
+}
+
 // State returns a consistent snapshot of the current quota state.
 func (qc *QuotaClient) State() QuotaState {
 	qc.mu.RLock()
@@ -79,24 +234,93 @@ func (qc *QuotaClient) State() QuotaState {
 	return qc.state
 }
 
-// Fetch fetches quota from the Anthropic OAuth usage API. Returns true on success.
+// fetchRetries is how many times Fetch retries a transient failure.
+const fetchRetries = 2
+
+// fetchRetryDelay is the wait before Fetch's first retry, doubled before
+// each further one. A variable so tests can shorten it.
+var fetchRetryDelay = time.Second
+
+// Fetch fetches quota from the Anthropic OAuth usage API, retrying network
+// errors and HTTP 5xx responses with back-off. Returns true on success.
 func (qc *QuotaClient) Fetch() bool {
+	delay := fetchRetryDelay
+	for attempt := 1; ; attempt++ {
+		if qc.FetchOnce() {
+			return true
+		}
+		if attempt > fetchRetries || !retryableFetchError(qc.State()) {
+			return false
+		}
+		slog.Info("Retrying fetch", "attempt", attempt+1, "delay", delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// FetchOnce is like Fetch but makes exactly one request, with no retry.
+func (qc *QuotaClient) FetchOnce() bool {
+	return qc.FetchWithContext(context.Background())
+}
+
+// retryableFetchError reports whether the failure in state may go away on
+// its own: a network error while online, or a server-side HTTP error.
+// Credential, auth, rate limit and parse errors would fail again.
+func retryableFetchError(state QuotaState) bool {
+	switch state.ErrorType {
+	case ErrTypeNetwork:
+		return state.Error != errMsgNoNetwork
+	case ErrTypeHTTP:
+		return state.HTTPStatus >= 500
+	}
+	return false
+}
+
+// Stats returns a snapshot of the fetch counters.
+func (qc *QuotaClient) Stats() QuotaStats {
+	return QuotaStats{
+		FetchCount:      qc.fetchCount.Load(),
+		FetchErrorCount: qc.fetchErrorCount.Load(),
+	}
+}
+
+// LastFetchDuration returns how long the HTTP round trip of the last fetch
+// took, including failed requests, or 0 before the first request.
+func (qc *QuotaClient) LastFetchDuration() time.Duration {
+	return time.Duration(qc.lastFetchDuration.Load())
+}
+
+// FetchWithContext is like FetchOnce but aborts the HTTP request when ctx is
+// done. The poll loop uses it: its next poll is the retry.
+func (qc *QuotaClient) FetchWithContext(ctx context.Context) bool {
+	ok := qc.fetch(ctx)
+	qc.fetchCount.Add(1)
+	if !ok {
+		qc.fetchErrorCount.Add(1)
+	}
+	return ok
+}
+
+// fetch performs a single fetch and updates state. Returns true on success.
+func (qc *QuotaClient) fetch(ctx context.Context) bool {
 	token, err := qc.creds.GetAccessToken()
 	if err != nil {
-		log.Printf("Credential error: %v", err)
+		slog.Error("Credential error", "error", err)
 		qc.mu.Lock()
 		qc.state = QuotaState{
 			Error:        truncate(err.Error(), 50),
 			ErrorType:    ErrTypeCredential,
 			TokenExpired: errors.Is(err, ErrTokenExpired),
+			LastUpdate:   qc.state.LastUpdate,
 		}
+		qc.lastETag = ""
 		qc.mu.Unlock()
 		return false
 	}
 
-	req, err := http.NewRequest("GET", usageURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", qc.url, nil)
 	if err != nil {
-		log.Printf("Request error: %v", err)
+		slog.Error("Request error", "error", err)
 		qc.setErrorTyped(truncate(err.Error(), 50), ErrTypeNetwork, 0)
 		return false
 	}
@@ -104,35 +328,74 @@ func (qc *QuotaClient) Fetch() bool {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("anthropic-beta", "oauth-2025-04-20")
+	if qc.version != "" {
+		req.Header.Set("anthropic-version", qc.version)
+	}
 	req.Header.Set("Accept", "application/json")
+	qc.mu.RLock()
+	if qc.lastETag != "" {
+		req.Header.Set("If-None-Match", qc.lastETag)
+	}
+	qc.mu.RUnlock()
 
+	// Time the request and response body only, not credential loading.
+	start := time.Now()
+	defer func() { qc.lastFetchDuration.Store(int64(time.Since(start))) }()
 	resp, err := qc.client.Do(req)
 	if err != nil {
-		log.Printf("Fetch failed: %v", err)
+		// A DNS or dial failure means no route to the API or proxy; report
+		// it as offline rather than as an API failure.
+		if isOfflineError(err) {
+			slog.Info("Network unavailable", "error", err)
+			qc.setErrorTyped(errMsgNoNetwork, ErrTypeNetwork, 0)
+			return false
+		}
+		slog.Error("Fetch failed", "error", err)
 		qc.setErrorTyped(truncate(err.Error(), 50), ErrTypeNetwork, 0)
 		return false
 	}
 	defer resp.Body.Close()
 
+	// Not modified: keep the previous values, only refresh timestamps.
+	if resp.StatusCode == http.StatusNotModified {
+		now := time.Now().UTC()
+		qc.mu.Lock()
+		qc.state.LastUpdate = &now
+		qc.state.TokenExpiresAt = qc.creds.ExpiresAt()
+		qc.mu.Unlock()
+		return true
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		var msg string
 		switch resp.StatusCode {
 		case 401:
-			msg = "Token invalid \u2014 run 'claude login'"
+			msg = errMsgTokenInvalid
+			if desc := parseWWWAuthenticate(resp.Header.Get("WWW-Authenticate"))["error_description"]; desc != "" {
+				msg = fmt.Sprintf(errMsgTokenInvalidDetail, truncate(desc, 60))
+			}
 		case 403:
-			msg = "Scope missing user:profile"
+			msg = errMsgScopeMissing
+		case 429:
+			msg = errMsgRateLimited
 		default:
 			msg = fmt.Sprintf("HTTP %d", resp.StatusCode)
 		}
-		log.Printf("API error: %s", msg)
+		slog.Error("API error", "status", resp.StatusCode, "error", msg)
 		qc.setErrorTyped(msg, ErrTypeHTTP, resp.StatusCode)
 		return false
 	}
 
-	var data usageResponse
 	// Limit body to 1MB to prevent memory exhaustion from misbehaving server.
-	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&data); err != nil {
-		log.Printf("JSON parse failed: %v", err)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		slog.Error("Reading response failed", "error", err)
+		qc.setErrorTyped(truncate(err.Error(), 50), ErrTypeNetwork, 0)
+		return false
+	}
+	data, err := qc.decodeUsage(body)
+	if err != nil {
+		slog.Error("JSON parse failed", "error", err)
 		qc.setErrorTyped(truncate(err.Error(), 50), ErrTypeParse, 0)
 		return false
 	}
@@ -141,15 +404,27 @@ func (qc *QuotaClient) Fetch() bool {
 	parseBucket(data.FiveHour, &newState.FiveHour, &newState.FiveHourResets)
 	parseBucket(data.SevenDay, &newState.SevenDay, &newState.SevenDayResets)
 	parseBucket(data.SevenDaySonnet, &newState.SevenDaySonnet, &newState.SevenDaySonnetResets)
+	parseBucketTokens(data.FiveHour, &newState.FiveHourCap, &newState.FiveHourTokensUsed)
+	parseBucketTokens(data.SevenDay, &newState.SevenDayCap, &newState.SevenDayTokensUsed)
+	parseBucketTokens(data.SevenDaySonnet, &newState.SevenDaySonnetCap, &newState.SevenDaySonnetTokensUsed)
 
 	now := time.Now().UTC()
 	newState.LastUpdate = &now
+	newState.TokenExpiresAt = qc.creds.ExpiresAt()
 
 	// Compute 5h projection: extrapolate average consumption rate to end of window.
-	if newState.FiveHour != nil && newState.FiveHourResets != nil {
-		newState.FiveHourProjected = computeProjection(
+	// Below minProj the extrapolation is too noisy to be useful.
+	if newState.FiveHour != nil && newState.FiveHourResets != nil && *newState.FiveHour >= qc.minProj {
+		projected, lower, upper, ok := computeProjectionInterval(
 			*newState.FiveHour, *newState.FiveHourResets, now, fiveHourWindow,
 		)
+		if ok {
+			newState.FiveHourProjected = &projected
+			if lower != upper {
+				newState.FiveHourProjectedLow = &lower
+				newState.FiveHourProjectedHigh = &upper
+			}
+		}
 	}
 
 	// Compute saturation time when projected > 100%.
@@ -160,7 +435,7 @@ func (qc *QuotaClient) Fetch() bool {
 	}
 
 	// Compute 7d projection: same approach as 5h above.
-	if newState.SevenDay != nil && newState.SevenDayResets != nil {
+	if newState.SevenDay != nil && newState.SevenDayResets != nil && *newState.SevenDay >= qc.minProj {
 		newState.SevenDayProjected = computeProjection(
 			*newState.SevenDay, *newState.SevenDayResets, now, sevenDayWindow,
 		)
@@ -174,14 +449,18 @@ func (qc *QuotaClient) Fetch() bool {
 
 	qc.mu.Lock()
 	qc.state = newState
+	qc.lastETag = resp.Header.Get("ETag")
 	qc.mu.Unlock()
 	return true
 }
 
-// setErrorTyped resets state to an error-only snapshot with classification.
+// setErrorTyped resets state to an error-only snapshot with classification,
+// keeping LastUpdate so the time of the last successful fetch stays visible.
+// The stored ETag is dropped so the next request fetches a full response.
 func (qc *QuotaClient) setErrorTyped(msg, errType string, httpStatus int) {
 	qc.mu.Lock()
-	qc.state = QuotaState{Error: msg, ErrorType: errType, HTTPStatus: httpStatus}
+	qc.state = QuotaState{Error: msg, ErrorType: errType, HTTPStatus: httpStatus, LastUpdate: qc.state.LastUpdate}
+	qc.lastETag = ""
 	qc.mu.Unlock()
 }
 
@@ -191,24 +470,60 @@ func (qc *QuotaClient) setError(msg string) {
 }
 
 // parseBucket extracts utilization and reset time from an API bucket.
-func parseBucket(bucket *usageBucket, util **float64, resets **time.Time) {
+func parseBucket(bucket *usageBucket, util **Utilization, resets **time.Time) {
 	if bucket == nil {
 		return
 	}
 	if bucket.Utilization != nil {
-		v := *bucket.Utilization
+		v := Utilization(*bucket.Utilization)
 		*util = &v
 	}
 	if bucket.ResetsAt != nil {
-		t, err := time.Parse(time.RFC3339, *bucket.ResetsAt)
+		t, err := parseFlexTime(*bucket.ResetsAt)
 		if err != nil {
-			log.Printf("Failed to parse reset time %q: %v", *bucket.ResetsAt, err)
+			slog.Warn("Failed to parse reset time", "resets_at", *bucket.ResetsAt, "error", err)
 			return
 		}
 		*resets = &t
 	}
 }
 
+// parseBucketTokens copies the absolute cap and token usage from an API
+// bucket, when present.
+func parseBucketTokens(bucket *usageBucket, limit, used **int64) {
+	if bucket == nil {
+		return
+	}
+	*limit = bucket.Cap
+	*used = bucket.TokensUsed
+}
+
+// resetTimeLayouts are the reset time formats accepted from the API, in the
+// order they are tried.
+var resetTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC850,
+}
+
+// parseFlexTime parses s with each of resetTimeLayouts in turn, returning the
+// first success or the RFC3339 error when none match.
+func parseFlexTime(s string) (time.Time, error) {
+	var firstErr error
+	for _, layout := range resetTimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return time.Time{}, firstErr
+}
+
 // fiveHourWindow is the assumed duration of the 5-hour quota window.
 // This value is not derivable from the API (which only returns resets_at).
 // If Anthropic changes the window duration, this constant must be updated.
@@ -224,7 +539,7 @@ const sevenDayWindow = 7 * 24 * time.Hour
 //
 // Formula: projected = current * windowDuration / timeElapsed
 // where timeElapsed = windowDuration - timeUntilReset.
-func computeProjection(current float64, resetsAt time.Time, now time.Time, windowDuration time.Duration) *float64 {
+func computeProjection(current Utilization, resetsAt time.Time, now time.Time, windowDuration time.Duration) *Utilization {
 	if current <= 0 || !resetsAt.After(now) || windowDuration <= 0 {
 		return nil
 	}
@@ -233,14 +548,34 @@ func computeProjection(current float64, resetsAt time.Time, now time.Time, windo
 	if timeElapsed <= 0 {
 		return nil
 	}
-	projected := current * windowDuration.Seconds() / timeElapsed.Seconds()
+	projected := current * Utilization(windowDuration.Seconds()/timeElapsed.Seconds())
 	return &projected
 }
 
+// computeProjectionInterval is computeProjection with an uncertainty interval
+// that narrows as the window elapses: ±20% of the projection while less than a
+// quarter of the window has passed, ±10% up to half, and none after that
+// (lower == upper == projected). ok is false when there is no projection.
+func computeProjectionInterval(current Utilization, resetsAt, now time.Time, windowDuration time.Duration) (projected, lower, upper Utilization, ok bool) {
+	p := computeProjection(current, resetsAt, now, windowDuration)
+	if p == nil {
+		return 0, 0, 0, false
+	}
+	elapsed := 1 - resetsAt.Sub(now).Seconds()/windowDuration.Seconds()
+	var margin Utilization
+	switch {
+	case elapsed < 0.25:
+		margin = 0.2
+	case elapsed < 0.5:
+		margin = 0.1
+	}
+	return *p, *p * (1 - margin), *p * (1 + margin), true
+}
+
 // computeSaturationTime estimates when utilization will reach 100%, based on
 // the average consumption rate over the elapsed portion of the window. Returns
 // nil when saturation won't occur before reset or inputs are invalid.
-func computeSaturationTime(current float64, resetsAt time.Time, now time.Time, windowDuration time.Duration) *time.Time {
+func computeSaturationTime(current Utilization, resetsAt time.Time, now time.Time, windowDuration time.Duration) *time.Time {
 	if current <= 0 || current >= 100 || !resetsAt.After(now) || windowDuration <= 0 {
 		return nil
 	}
@@ -250,7 +585,7 @@ func computeSaturationTime(current float64, resetsAt time.Time, now time.Time, w
 		return nil
 	}
 	// rate = current / timeElapsed; timeToSaturation = (100 - current) / rate
-	timeToSaturation := time.Duration(float64(timeElapsed) * (100 - current) / current)
+	timeToSaturation := time.Duration(float64(timeElapsed) * float64((100-current)/current))
 	saturation := now.Add(timeToSaturation)
 	if !saturation.Before(resetsAt) {
 		return nil
@@ -258,6 +593,53 @@ func computeSaturationTime(current float64, resetsAt time.Time, now time.Time, w
 	return &saturation
 }
 
+// parseWWWAuthenticate returns the auth-params of a WWW-Authenticate header,
+// keyed by lowercased name, e.g. `Bearer error="invalid_token",
+// error_description="Token has expired"` → {"error": "invalid_token",
+// "error_description": "Token has expired"}. Quoted values may contain
+// commas and backslash escapes. A single challenge is assumed.
+func parseWWWAuthenticate(header string) map[string]string {
+	params := map[string]string{}
+	s := strings.TrimSpace(header)
+	// Skip the auth scheme, e.g. "Bearer".
+	if sp := strings.IndexByte(s, ' '); sp >= 0 && !strings.Contains(s[:sp], "=") {
+		s = s[sp+1:]
+	} else if !strings.Contains(s, "=") {
+		return params
+	}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return params
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " \t")
+
+		var val strings.Builder
+		if strings.HasPrefix(s, `"`) {
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				val.WriteByte(s[i])
+			}
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			val.WriteString(strings.TrimSpace(s[:end]))
+			s = s[end:]
+		}
+		if key != "" {
+			params[key] = val.String()
+		}
+	}
+}
+
 func truncate(s string, n int) string {
 	runes := []rune(s)
 	if len(runes) <= n {
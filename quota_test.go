@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -43,7 +47,7 @@ func TestTruncate_MultiByteRune(t *testing.T) {
 }
 
 func TestParseBucket_Nil(t *testing.T) {
-	var util *float64
+	var util *Utilization
 	var resets *time.Time
 	parseBucket(nil, &util, &resets)
 	if util != nil {
@@ -57,7 +61,7 @@ func TestParseBucket_Nil(t *testing.T) {
 func TestParseBucket_UtilizationOnly(t *testing.T) {
 	v := 42.5
 	bucket := &usageBucket{Utilization: &v}
-	var util *float64
+	var util *Utilization
 	var resets *time.Time
 	parseBucket(bucket, &util, &resets)
 	if util == nil || *util != 42.5 {
@@ -72,7 +76,7 @@ func TestParseBucket_Full(t *testing.T) {
 	v := 73.0
 	r := "2026-02-06T14:30:00Z"
 	bucket := &usageBucket{Utilization: &v, ResetsAt: &r}
-	var util *float64
+	var util *Utilization
 	var resets *time.Time
 	parseBucket(bucket, &util, &resets)
 	if util == nil || *util != 73.0 {
@@ -91,7 +95,7 @@ func TestParseBucket_WithTimezone(t *testing.T) {
 	v := 10.0
 	r := "2026-02-06T14:30:00+02:00"
 	bucket := &usageBucket{Utilization: &v, ResetsAt: &r}
-	var util *float64
+	var util *Utilization
 	var resets *time.Time
 	parseBucket(bucket, &util, &resets)
 	if resets == nil {
@@ -107,7 +111,7 @@ func TestParseBucket_WithTimezone(t *testing.T) {
 func TestParseBucket_NilUtilization(t *testing.T) {
 	r := "2026-02-06T14:30:00Z"
 	bucket := &usageBucket{ResetsAt: &r}
-	var util *float64
+	var util *Utilization
 	var resets *time.Time
 	parseBucket(bucket, &util, &resets)
 	if util != nil {
@@ -121,7 +125,7 @@ func TestParseBucket_NilUtilization(t *testing.T) {
 func TestParseBucket_InvalidTime(t *testing.T) {
 	r := "not-a-date"
 	bucket := &usageBucket{ResetsAt: &r}
-	var util *float64
+	var util *Utilization
 	var resets *time.Time
 	parseBucket(bucket, &util, &resets)
 	if resets != nil {
@@ -129,28 +133,123 @@ func TestParseBucket_InvalidTime(t *testing.T) {
 	}
 }
 
-func newTestQuotaClient(token string, expiresAt int64, client *http.Client) *QuotaClient {
+func TestFetch_CapAndTokensUsed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{
+			"five_hour": {"utilization": 40.0, "cap": 500000, "tokens_used": 200000},
+			"seven_day": {"utilization": 10.0}
+		}`))
+	}))
+	defer srv.Close()
+
+	for _, strict := range []bool{false, true} {
+		qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+		qc.SetStrictJSON(strict)
+		if !qc.FetchOnce() {
+			t.Fatalf("strict=%v: FetchOnce() failed: %q", strict, qc.State().Error)
+		}
+		state := qc.State()
+		if state.FiveHourCap == nil || *state.FiveHourCap != 500000 {
+			t.Errorf("strict=%v: FiveHourCap = %v, want 500000", strict, state.FiveHourCap)
+		}
+		if state.FiveHourTokensUsed == nil || *state.FiveHourTokensUsed != 200000 {
+			t.Errorf("strict=%v: FiveHourTokensUsed = %v, want 200000", strict, state.FiveHourTokensUsed)
+		}
+		if state.SevenDayCap != nil || state.SevenDayTokensUsed != nil || state.SevenDaySonnetCap != nil {
+			t.Errorf("strict=%v: absent cap fields should stay nil", strict)
+		}
+	}
+}
+
+func TestParseBucketTokens_Nil(t *testing.T) {
+	var limit, used *int64
+	parseBucketTokens(nil, &limit, &used)
+	if limit != nil || used != nil {
+		t.Error("parseBucketTokens(nil) set a field")
+	}
+}
+
+func TestParseFlexTime(t *testing.T) {
+	want := time.Date(2026, 2, 6, 14, 30, 0, 0, time.UTC)
+	for _, s := range []string{
+		"2026-02-06T14:30:00Z",            // RFC3339
+		"2026-02-06T15:30:00+01:00",       // RFC3339 with offset
+		"2026-02-06T14:30:00.000000Z",     // RFC3339Nano
+		"Fri, 06 Feb 2026 14:30:00 UTC",   // RFC1123
+		"Fri, 06 Feb 2026 14:30:00 +0000", // RFC1123Z
+		"Friday, 06-Feb-26 14:30:00 UTC",  // RFC850
+	} {
+		got, err := parseFlexTime(s)
+		if err != nil {
+			t.Errorf("parseFlexTime(%q) error: %v", s, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("parseFlexTime(%q) = %v, want %v", s, got.UTC(), want)
+		}
+	}
+}
+
+func TestParseFlexTime_Invalid(t *testing.T) {
+	for _, s := range []string{"", "not-a-time", "2026-02-06"} {
+		if _, err := parseFlexTime(s); err == nil {
+			t.Errorf("parseFlexTime(%q) should fail", s)
+		}
+	}
+}
+
+func newTestQuotaClient(token string, expiresAt int64, client *http.Client, url string) *QuotaClient {
 	creds := &OAuthCredentials{
 		accessToken: token,
 		expiresAt:   expiresAt,
 	}
-	return NewQuotaClient(creds, client)
+	return NewQuotaClient(creds, client, url, defaultAnthropicVersion, defaultMinProjectionUtilization)
 }
 
 func TestNewQuotaClient(t *testing.T) {
 	creds := &OAuthCredentials{accessToken: "tok"}
 	client := &http.Client{}
-	qc := NewQuotaClient(creds, client)
+	qc := NewQuotaClient(creds, client, "http://localhost:8080/usage", "2023-06-01", 5)
 	if qc.creds != creds {
 		t.Error("creds not set")
 	}
+	if qc.url != "http://localhost:8080/usage" {
+		t.Errorf("url = %q, want %q", qc.url, "http://localhost:8080/usage")
+	}
 	if qc.client != client {
 		t.Error("client not set")
 	}
+	if qc.version != "2023-06-01" {
+		t.Errorf("version = %q, want %q", qc.version, "2023-06-01")
+	}
+}
+
+func TestFetch_AnthropicVersionHeader(t *testing.T) {
+	for _, version := range []string{"2023-06-01", "2099-01-01", ""} {
+		var got []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Values("anthropic-version")
+			w.Write([]byte(`{}`))
+		}))
+		creds := &OAuthCredentials{accessToken: "tok", expiresAt: time.Now().UnixMilli() + 300_000}
+		qc := NewQuotaClient(creds, srv.Client(), srv.URL, version, 0)
+		if !qc.Fetch() {
+			t.Errorf("version %q: Fetch() returned false", version)
+		}
+		srv.Close()
+
+		if version == "" {
+			if len(got) != 0 {
+				t.Errorf("empty version should omit the header, got %q", got)
+			}
+		} else if len(got) != 1 || got[0] != version {
+			t.Errorf("anthropic-version = %q, want %q", got, version)
+		}
+	}
 }
 
 func TestQuotaClient_State(t *testing.T) {
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{})
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{}, "")
 	qc.state.Error = "test error"
 	state := qc.State()
 	if state.Error != "test error" {
@@ -159,8 +258,8 @@ func TestQuotaClient_State(t *testing.T) {
 }
 
 func TestQuotaClient_SetError(t *testing.T) {
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{})
-	v := 42.0
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{}, "")
+	v := Utilization(42)
 	qc.state.FiveHour = &v
 
 	qc.setError("something broke")
@@ -188,11 +287,7 @@ func TestFetch_Success(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("test-token", time.Now().UnixMilli()+300_000, srv.Client())
+	qc := newTestQuotaClient("test-token", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	if !qc.Fetch() {
 		t.Fatal("Fetch() returned false")
 	}
@@ -210,6 +305,9 @@ func TestFetch_Success(t *testing.T) {
 	if state.LastUpdate == nil {
 		t.Error("LastUpdate should be set")
 	}
+	if state.TokenExpiresAt == nil {
+		t.Error("TokenExpiresAt should be set from credentials expiry")
+	}
 	// Reset time is in the past (2026-02-06), so projection should be nil.
 	if state.FiveHourProjected != nil {
 		t.Errorf("FiveHourProjected should be nil for past reset, got %f", *state.FiveHourProjected)
@@ -220,6 +318,17 @@ func TestFetch_Success(t *testing.T) {
 	}
 }
 
+func TestQuotaState_StateAge(t *testing.T) {
+	if age := (QuotaState{}).StateAge(); age != 0 {
+		t.Errorf("StateAge() without LastUpdate = %v, want 0", age)
+	}
+	past := time.Now().Add(-2 * time.Minute)
+	age := QuotaState{LastUpdate: &past}.StateAge()
+	if age < 2*time.Minute || age > 3*time.Minute {
+		t.Errorf("StateAge() = %v, want ~2m", age)
+	}
+}
+
 func TestFetch_ComputesProjection(t *testing.T) {
 	// Use a reset time 1h in the future so the projection pipeline runs.
 	resetsAt := time.Now().UTC().Add(1 * time.Hour).Format(time.RFC3339)
@@ -232,11 +341,7 @@ func TestFetch_ComputesProjection(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	if !qc.Fetch() {
 		t.Fatal("Fetch() returned false")
 	}
@@ -251,6 +356,31 @@ func TestFetch_ComputesProjection(t *testing.T) {
 	}
 }
 
+func TestFetch_MinUtilizationForProjection(t *testing.T) {
+	// 1h into the 5h window, so any projection would be computed.
+	resetsAt := time.Now().UTC().Add(4 * time.Hour).Format(time.RFC3339)
+	for _, tt := range []struct {
+		util    string
+		wantNil bool
+	}{
+		{"3.0", true},
+		{"6.0", false},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`{"five_hour": {"utilization": ` + tt.util + `, "resets_at": "` + resetsAt + `"}}`))
+		}))
+		qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+		if !qc.Fetch() {
+			t.Fatalf("utilization %s: Fetch() returned false", tt.util)
+		}
+		srv.Close()
+
+		if got := qc.State().FiveHourProjected; (got == nil) != tt.wantNil {
+			t.Errorf("utilization %s: FiveHourProjected = %v, want nil=%v", tt.util, got, tt.wantNil)
+		}
+	}
+}
+
 func TestFetch_ComputesSaturation(t *testing.T) {
 	// 80% consumed with 4h remaining → projected = 80*5/1 = 400% (>100)
 	// → saturation = now + (100-80)/80 * 1h = now + 15min
@@ -264,11 +394,7 @@ func TestFetch_ComputesSaturation(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	if !qc.Fetch() {
 		t.Fatal("Fetch() returned false")
 	}
@@ -290,23 +416,58 @@ func TestFetch_ComputesSaturation(t *testing.T) {
 	}
 }
 
-func TestFetch_HTTP401(t *testing.T) {
+func TestFetch_HTTP401_WWWAuthenticate(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="Token has expired"`)
 		w.WriteHeader(401)
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+	if qc.Fetch() {
+		t.Error("Fetch() should return false on 401")
+	}
+	want := "Token invalid (Token has expired) \u2014 run 'claude login'"
+	if got := qc.State().Error; got != want {
+		t.Errorf("Error = %q, want %q", got, want)
+	}
+}
 
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
+func TestParseWWWAuthenticate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   map[string]string
+	}{
+		{"", map[string]string{}},
+		{"Bearer", map[string]string{}},
+		{`Bearer error="invalid_token", error_description="Token has expired"`,
+			map[string]string{"error": "invalid_token", "error_description": "Token has expired"}},
+		{`Bearer realm="api", Error_Description="a, \"quoted\" b"`,
+			map[string]string{"realm": "api", "error_description": `a, "quoted" b`}},
+		{`Bearer error=invalid_token,scope=user:profile`,
+			map[string]string{"error": "invalid_token", "scope": "user:profile"}},
+		{`Bearer error="unterminated`, map[string]string{"error": "unterminated"}},
+	}
+	for _, tc := range tests {
+		if got := parseWWWAuthenticate(tc.header); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parseWWWAuthenticate(%q) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestFetch_HTTP401(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(401)
+	}))
+	defer srv.Close()
+
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	if qc.Fetch() {
 		t.Error("Fetch() should return false on 401")
 	}
 	state := qc.State()
-	if state.Error == "" {
-		t.Error("Error should be set on 401")
+	if state.Error != errMsgTokenInvalid {
+		t.Errorf("Error = %q, want %q", state.Error, errMsgTokenInvalid)
 	}
 	if state.ErrorType != ErrTypeHTTP {
 		t.Errorf("ErrorType = %q, want %q", state.ErrorType, ErrTypeHTTP)
@@ -322,15 +483,11 @@ func TestFetch_HTTP403(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	qc.Fetch()
 	state := qc.State()
-	if state.Error != "Scope missing user:profile" {
-		t.Errorf("Error = %q, want scope error", state.Error)
+	if state.Error != errMsgScopeMissing {
+		t.Errorf("Error = %q, want %q", state.Error, errMsgScopeMissing)
 	}
 	if state.ErrorType != ErrTypeHTTP {
 		t.Errorf("ErrorType = %q, want %q", state.ErrorType, ErrTypeHTTP)
@@ -340,6 +497,59 @@ func TestFetch_HTTP403(t *testing.T) {
 	}
 }
 
+func TestFetch_HTTP429(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(429)
+	}))
+	defer srv.Close()
+
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+	if qc.Fetch() {
+		t.Error("Fetch() should return false on 429")
+	}
+	state := qc.State()
+	if state.Error != errMsgRateLimited {
+		t.Errorf("Error = %q, want %q", state.Error, errMsgRateLimited)
+	}
+	if state.ErrorType != ErrTypeHTTP {
+		t.Errorf("ErrorType = %q, want %q", state.ErrorType, ErrTypeHTTP)
+	}
+	if state.HTTPStatus != 429 {
+		t.Errorf("HTTPStatus = %d, want 429", state.HTTPStatus)
+	}
+}
+
+func TestLastFetchDuration(t *testing.T) {
+	for _, status := range []int{200, 500} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(status)
+			w.Write([]byte(`{}`))
+		}))
+		qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+		if d := qc.LastFetchDuration(); d != 0 {
+			t.Errorf("LastFetchDuration() before fetch = %v, want 0", d)
+		}
+		if ok := qc.FetchOnce(); ok != (status == 200) {
+			t.Errorf("HTTP %d: FetchOnce() = %v", status, ok)
+		}
+		srv.Close()
+
+		if d := qc.LastFetchDuration(); d < 100*time.Millisecond {
+			t.Errorf("HTTP %d: LastFetchDuration() = %v, want >= 100ms", status, d)
+		}
+	}
+
+	// A credential failure never reaches the API, so nothing is timed.
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()-1000, http.DefaultClient, "http://127.0.0.1:0")
+	if qc.FetchOnce() {
+		t.Fatal("FetchOnce() with an expired token should fail")
+	}
+	if d := qc.LastFetchDuration(); d != 0 {
+		t.Errorf("LastFetchDuration() after credential error = %v, want 0", d)
+	}
+}
+
 func TestFetch_InvalidJSON(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(200)
@@ -347,11 +557,7 @@ func TestFetch_InvalidJSON(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	if qc.Fetch() {
 		t.Error("Fetch() should return false on invalid JSON")
 	}
@@ -369,7 +575,7 @@ func TestFetch_TokenExpired(t *testing.T) {
 	defer func() { credentialsPath = origCreds }()
 	credentialsPath = "/nonexistent/credentials.json"
 
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()-1000, &http.Client{})
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()-1000, &http.Client{}, "")
 	if qc.Fetch() {
 		t.Error("Fetch() should return false on expired token")
 	}
@@ -385,22 +591,44 @@ func TestFetch_TokenExpired(t *testing.T) {
 	}
 }
 
+func TestQuotaClient_SetErrorPreservesLastUpdate(t *testing.T) {
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{}, "")
+	last := time.Now().UTC().Add(-10 * time.Second)
+	qc.state.LastUpdate = &last
+
+	qc.setError("something broke")
+
+	state := qc.State()
+	if state.LastUpdate == nil || !state.LastUpdate.Equal(last) {
+		t.Errorf("LastUpdate = %v, want %v preserved", state.LastUpdate, last)
+	}
+}
+
+func TestFetch_CredentialErrorPreservesLastUpdate(t *testing.T) {
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()-1000, &http.Client{}, "")
+	last := time.Now().UTC().Add(-10 * time.Second)
+	qc.state.LastUpdate = &last
+
+	qc.Fetch()
+
+	state := qc.State()
+	if state.LastUpdate == nil || !state.LastUpdate.Equal(last) {
+		t.Errorf("LastUpdate = %v, want %v preserved", state.LastUpdate, last)
+	}
+}
+
 func TestFetch_ResetsStaleState(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(500)
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
-	v := 42.0
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+	v := Utilization(42)
 	qc.state.FiveHour = &v
 	qc.state.TokenExpired = true
 
-	qc.Fetch()
+	qc.FetchOnce()
 
 	state := qc.State()
 	if state.FiveHour != nil {
@@ -417,6 +645,152 @@ func TestFetch_ResetsStaleState(t *testing.T) {
 	}
 }
 
+func TestFetch_NotModified(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			if inm := r.Header.Get("If-None-Match"); inm != "" {
+				t.Errorf("first request If-None-Match = %q, want empty", inm)
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(200)
+			w.Write([]byte(`{"five_hour": {"utilization": 42.5}, "seven_day": {"utilization": 73.0}}`))
+			return
+		}
+		if inm := r.Header.Get("If-None-Match"); inm != `"v1"` {
+			t.Errorf("If-None-Match = %q, want %q", inm, `"v1"`)
+		}
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer srv.Close()
+
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+	if !qc.Fetch() {
+		t.Fatal("first Fetch() returned false")
+	}
+	first := qc.State()
+
+	// Backdate LastUpdate so the refresh is observable.
+	old := first.LastUpdate.Add(-time.Minute)
+	qc.state.LastUpdate = &old
+
+	if !qc.Fetch() {
+		t.Fatal("second Fetch() returned false on 304")
+	}
+	state := qc.State()
+	if state.LastUpdate == nil || !state.LastUpdate.After(old) {
+		t.Errorf("LastUpdate = %v, want refreshed after %v", state.LastUpdate, old)
+	}
+	if state.FiveHour == nil || *state.FiveHour != 42.5 {
+		t.Errorf("FiveHour = %v, want 42.5", state.FiveHour)
+	}
+	if state.SevenDay == nil || *state.SevenDay != 73.0 {
+		t.Errorf("SevenDay = %v, want 73.0", state.SevenDay)
+	}
+	if state.Error != "" {
+		t.Errorf("Error = %q, want empty", state.Error)
+	}
+}
+
+func TestFetch_ErrorClearsETag(t *testing.T) {
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, &http.Client{}, "")
+	qc.lastETag = `"v1"`
+	qc.setError("boom")
+	if qc.lastETag != "" {
+		t.Errorf("lastETag = %q, want empty after error", qc.lastETag)
+	}
+}
+
+func TestFetch_Stats(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		// Every third request fails.
+		if requests%3 == 0 {
+			w.WriteHeader(500)
+			return
+		}
+		w.Write([]byte(`{"five_hour": {"utilization": 10.0}}`))
+	}))
+	defer srv.Close()
+
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+	for range 7 {
+		qc.FetchOnce()
+	}
+
+	stats := qc.Stats()
+	if stats.FetchCount != 7 {
+		t.Errorf("FetchCount = %d, want 7", stats.FetchCount)
+	}
+	if stats.FetchErrorCount != 2 {
+		t.Errorf("FetchErrorCount = %d, want 2", stats.FetchErrorCount)
+	}
+}
+
+// noFetchRetryDelay makes Fetch retry immediately for the test.
+func noFetchRetryDelay(t *testing.T) {
+	orig := fetchRetryDelay
+	fetchRetryDelay = 0
+	t.Cleanup(func() { fetchRetryDelay = orig })
+}
+
+func TestFetch_Retry(t *testing.T) {
+	noFetchRetryDelay(t)
+
+	tests := []struct {
+		name     string
+		statuses []int // per request; the last one repeats
+		wantOK   bool
+		wantReqs int
+	}{
+		{"success after 5xx", []int{500, 502, 200}, true, 3},
+		{"5xx exhausts retries", []int{503}, false, fetchRetries + 1},
+		{"401 not retried", []int{401}, false, 1},
+		{"429 not retried", []int{429}, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				status := tt.statuses[min(requests, len(tt.statuses)-1)]
+				requests++
+				w.WriteHeader(status)
+				if status == 200 {
+					w.Write([]byte(`{"five_hour": {"utilization": 10.0}}`))
+				}
+			}))
+			defer srv.Close()
+
+			qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+			if ok := qc.Fetch(); ok != tt.wantOK {
+				t.Errorf("Fetch() = %v, want %v", ok, tt.wantOK)
+			}
+			if requests != tt.wantReqs {
+				t.Errorf("requests = %d, want %d", requests, tt.wantReqs)
+			}
+		})
+	}
+}
+
+func TestFetchOnce_NoRetry(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(500)
+	}))
+	defer srv.Close()
+
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+	if qc.FetchOnce() {
+		t.Error("FetchOnce() = true on HTTP 500")
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+}
+
 func TestComputeProjection_Normal(t *testing.T) {
 	// 33% consumed, 23 min remaining in 5h window
 	// elapsed = 5h - 23m = 4h37m = 277 min
@@ -433,6 +807,40 @@ func TestComputeProjection_Normal(t *testing.T) {
 	}
 }
 
+func TestComputeProjectionInterval(t *testing.T) {
+	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		margin  float64
+	}{
+		{"first quarter", 1 * time.Hour, 0.2},
+		{"second quarter", 2 * time.Hour, 0.1},
+		{"second half", 4 * time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetsAt := now.Add(fiveHourWindow - tt.elapsed)
+			projected, lower, upper, ok := computeProjectionInterval(10, resetsAt, now, fiveHourWindow)
+			if !ok {
+				t.Fatal("expected a projection")
+			}
+			want := *computeProjection(10, resetsAt, now, fiveHourWindow)
+			if projected != want {
+				t.Errorf("projected = %v, want %v", projected, want)
+			}
+			if math.Abs(float64(lower-want*Utilization(1-tt.margin))) > 1e-9 ||
+				math.Abs(float64(upper-want*Utilization(1+tt.margin))) > 1e-9 {
+				t.Errorf("interval = [%v, %v], want ±%v of %v", lower, upper, tt.margin, want)
+			}
+		})
+	}
+
+	if _, _, _, ok := computeProjectionInterval(0, now.Add(time.Hour), now, fiveHourWindow); ok {
+		t.Error("zero utilization should have no projection")
+	}
+}
+
 func TestComputeProjection_HighUsageUncapped(t *testing.T) {
 	// 80% consumed with 4h remaining in 5h window → projected way over 100
 	now := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
@@ -619,11 +1027,7 @@ func TestFetch_ComputesSevenDayProjection(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	if !qc.Fetch() {
 		t.Fatal("Fetch() returned false")
 	}
@@ -650,11 +1054,7 @@ func TestFetch_ComputesSevenDaySaturation(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	origURL := usageURL
-	defer func() { usageURL = origURL }()
-	usageURL = srv.URL
-
-	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client())
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
 	if !qc.Fetch() {
 		t.Fatal("Fetch() returned false")
 	}
@@ -674,3 +1074,154 @@ func TestFetch_ComputesSevenDaySaturation(t *testing.T) {
 		t.Errorf("SevenDaySaturation in %v, want ~6h", untilSat)
 	}
 }
+
+func TestFetchWithContext_Cancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(10 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	qc := newTestQuotaClient("test-token", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if qc.FetchWithContext(ctx) {
+		t.Fatal("FetchWithContext() returned true after cancel")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("FetchWithContext took %v after cancel, want prompt return", elapsed)
+	}
+	if state := qc.State(); state.ErrorType != ErrTypeNetwork {
+		t.Errorf("ErrorType = %q, want %q", state.ErrorType, ErrTypeNetwork)
+	}
+}
+
+func TestQuotaState_IsEmpty(t *testing.T) {
+	v := Utilization(0)
+	tests := []struct {
+		name  string
+		state QuotaState
+		want  bool
+	}{
+		{"zero value", QuotaState{}, true},
+		{"only LastUpdate", QuotaState{LastUpdate: &time.Time{}}, true},
+		{"five hour zero", QuotaState{FiveHour: &v}, false},
+		{"seven day", QuotaState{SevenDay: &v}, false},
+		{"sonnet", QuotaState{SevenDaySonnet: &v}, false},
+		{"error", QuotaState{Error: "boom"}, false},
+	}
+	for _, tc := range tests {
+		if got := tc.state.IsEmpty(); got != tc.want {
+			t.Errorf("%s: IsEmpty() = %v, want %v", tc.name, got, tc.want)
+		}
+		if got := tc.state.HasData(); got == tc.want {
+			t.Errorf("%s: HasData() = %v, want %v", tc.name, got, !tc.want)
+		}
+	}
+}
+
+func TestQuotaState_IsSaturated(t *testing.T) {
+	past := time.Now().Add(-time.Second)
+	future := time.Now().Add(time.Minute)
+	if (QuotaState{}).IsSaturated() {
+		t.Error("IsSaturated() = true with nil saturation")
+	}
+	if (QuotaState{FiveHourSaturation: &future}).IsSaturated() {
+		t.Error("IsSaturated() = true with future saturation")
+	}
+	if !(QuotaState{FiveHourSaturation: &past}).IsSaturated() {
+		t.Error("IsSaturated() = false with past saturation")
+	}
+}
+
+func TestQuotaState_Equal(t *testing.T) {
+	u1, u2, u3 := Utilization(42), Utilization(42), Utilization(43)
+	t1, t2 := testNow, testNow
+	a := QuotaState{FiveHour: &u1, FiveHourResets: &t1, Error: "boom", HTTPStatus: 500}
+	b := QuotaState{FiveHour: &u2, FiveHourResets: &t2, Error: "boom", HTTPStatus: 500}
+	if !a.Equal(b) {
+		t.Error("Equal() = false for distinct pointers to equal values")
+	}
+	b.FiveHour = &u3
+	if a.Equal(b) {
+		t.Error("Equal() = true for different FiveHour values")
+	}
+
+	// Setting any single field must break equality with the zero value.
+	var base QuotaState
+	typ := reflect.TypeOf(base)
+	for i := range typ.NumField() {
+		changed := base
+		f := reflect.ValueOf(&changed).Elem().Field(i)
+		switch f.Kind() {
+		case reflect.Pointer:
+			f.Set(reflect.New(f.Type().Elem()))
+		case reflect.String:
+			f.SetString("x")
+		case reflect.Int:
+			f.SetInt(1)
+		case reflect.Bool:
+			f.SetBool(true)
+		default:
+			t.Fatalf("field %s: unhandled kind %s", typ.Field(i).Name, f.Kind())
+		}
+		if base.Equal(changed) {
+			t.Errorf("Equal() = true with %s changed", typ.Field(i).Name)
+		}
+	}
+}
+
+func TestFetch_UnknownFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"five_hour": {"utilization": 42.0}, "one_hour": {"utilization": 9.0}}`))
+	}))
+	defer srv.Close()
+
+	t.Run("tolerant", func(t *testing.T) {
+		buf := captureSlog(t)
+		qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+		for range 2 {
+			if !qc.Fetch() {
+				t.Fatalf("Fetch() = false: %q", qc.State().Error)
+			}
+		}
+		if u := qc.State().FiveHour; u == nil || *u != 42 {
+			t.Errorf("FiveHour = %v, want 42", u)
+		}
+		if n := strings.Count(buf.String(), "Unknown fields in usage response"); n != 1 {
+			t.Errorf("unknown fields warning logged %d times, want once:\n%s", n, buf.String())
+		}
+		if !strings.Contains(buf.String(), "one_hour") {
+			t.Errorf("warning does not name the field:\n%s", buf.String())
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+		qc.SetStrictJSON(true)
+		if qc.Fetch() {
+			t.Fatal("Fetch() = true, want failure on unknown field")
+		}
+		if s := qc.State(); s.ErrorType != ErrTypeParse || !strings.Contains(s.Error, "one_hour") {
+			t.Errorf("error = %q (%s), want parse error naming one_hour", s.Error, s.ErrorType)
+		}
+	})
+}
+
+func TestFetch_StrictJSONKnownFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"five_hour": {"utilization": 42.0, "resets_at": null}, "seven_day": null}`))
+	}))
+	defer srv.Close()
+
+	qc := newTestQuotaClient("tok", time.Now().UnixMilli()+300_000, srv.Client(), srv.URL)
+	qc.SetStrictJSON(true)
+	if !qc.Fetch() {
+		t.Fatalf("Fetch() = false: %q", qc.State().Error)
+	}
+}
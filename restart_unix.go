@@ -3,15 +3,16 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"syscall"
 )
 
 // execSelf replaces the current process with a new instance using the same arguments.
 func execSelf() {
-	log.Printf("Restarting %s %v", executablePath, os.Args[1:])
+	slog.Info("Restarting", "path", executablePath, "args", os.Args[1:])
 	if err := syscall.Exec(executablePath, os.Args, os.Environ()); err != nil {
-		log.Fatalf("Restart failed: %v", err)
+		slog.Error("Restart failed", "error", err)
+		os.Exit(1)
 	}
 }
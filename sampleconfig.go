@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// sampleConfigComments describes each config field in the sample config's
+// "_comments" object, keyed by JSON name.
+var sampleConfigComments = map[string]string{
+	"schema_version":                 "Config file format version. Leave as is; older files are migrated on load.",
+	"claude_home":                    "Home directory holding .claude/.credentials.json, $VAR references expanded. Empty uses your home directory.",
+	"api_url":                        "Usage API endpoint, e.g. a local proxy. Empty uses the Anthropic API.",
+	"anthropic_version":              "anthropic-version header sent with API requests. Empty omits the header.",
+	"poll_interval_seconds":          "Seconds between quota fetches.",
+	"poll_jitter_seconds":            "Maximum random delay added to each poll, 0 to disable.",
+	"timeout_seconds":                "HTTP timeout in seconds, 1-300.",
+	"min_utilization_for_projection": "Utilization in % below which no projection is shown.",
+	"font_size":                      "Icon text size.",
+	"font_name":                      "Icon font: bold, regular, mono, monobold or bitmap.",
+	"halo_size":                      "Text outline size in pixels, 0 to disable.",
+	"icon_size":                      "Icon size in pixels, rounded up to a multiple of 8.",
+	"padding":                        "Margin between the icon edge and the indicator, 0-16.",
+	"indicator_size_ratio":           "Scale of the pie and arc indicators, 0.1-1.",
+	"icon_opacity":                   "Alpha multiplier for the whole icon, 0-1.",
+	"bar_corner_radius":              "Corner radius of the bar indicator in pixels at 64px, 0 for square corners.",
+	"text_position":                  "Vertical position of the icon text: center, top or bottom.",
+	"ring_gap":                       "Gap between the ring-dual rings in pixels at 64px, 0-8.",
+	"prewarm_icon_cache":             "Render the icons for 0-100% in steps of 10 and the error icon at startup.",
+	"blink_when_saturated":           "Blink the icon once the projected 5h saturation time has passed.",
+	"indicator":                      "Indicator style: pie, bar, arc, bar-proj, bar-dual, ring-dual or text.",
+	"color_source":                   "Value driving the icon color: current or projected.",
+	"icon_window":                    "Quota window shown by the icon: 5h or 7d.",
+	"icon_background_color":          "Icon background as #rrggbb or #rrggbbaa. Empty keeps it transparent.",
+	"time_display":                   "Reset time display: relative, absolute or both.",
+	"max_saturation_display_minutes": "Hide the 5h saturation line until saturation is at most this many minutes away, 0 to always show it.",
+	"model_aliases":                  "Window labels by API bucket (five_hour, seven_day, seven_day_sonnet), e.g. {\"seven_day_sonnet\": \"Sonnet\"}. null keeps 5h, 7d and Sonnet 7d.",
+	"show_text":                      "Show the percentage text on the icon.",
+	"show_account":                   "Show the account email and organization in the menu.",
+	"show_projection":                "Show the projected utilization lines in the menu and tooltip.",
+	"stats":                          "Record quota snapshots in a local SQLite database.",
+	"telemetry_enabled":              "Send anonymous usage statistics (version, OS, arch, indicator, poll interval) after each fetch.",
+	"telemetry_url":                  "Endpoint receiving telemetry reports. Empty disables telemetry.",
+	"strict_json":                    "Fail fetches on unknown fields in the API response instead of logging a warning.",
+	"menu_order":                     "Tray menu items in display order, null for the default order.",
+	"log_file":                       "Also append logs to this file. Empty logs to stderr only.",
+	"log_format":                     "Structured log format: text or json. Empty keeps plain log lines.",
+	"title_format":                   "Go template for text next to the icon, e.g. \"{{.FiveHour}}%\". Empty shows no text.",
+	"tooltip_format":                 "Go template for the tooltip, e.g. \"5h {{FiveHourBar 10}} {{.FiveHour}}%\". Empty keeps the built-in tooltip.",
+	"thresholds":                     "Utilization in % at which the icon turns yellow (warning) and red (critical).",
+}
+
+// configJSONFields returns the JSON names of Config's fields in declaration
+// order, with their indexes; fields tagged "-" are skipped.
+func configJSONFields() (names []string, indexes []int) {
+	typ := reflect.TypeOf(Config{})
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		names = append(names, name)
+		indexes = append(indexes, i)
+	}
+	return names, indexes
+}
+
+// generateSampleConfig returns the sample config: every field at its
+// defaultConfig() value in declaration order, omitempty ignored, after a
+// "_comments" object describing each one. sample_config.json is its output;
+// regenerate it with go generate.
+func generateSampleConfig() []byte {
+	names, indexes := configJSONFields()
+	defaults := reflect.ValueOf(defaultConfig())
+
+	var b bytes.Buffer
+	b.WriteString("{\n  \"_comments\": {\n")
+	for i, name := range names {
+		key, _ := json.Marshal(name)
+		comment, _ := json.Marshal(sampleConfigComments[name])
+		b.WriteString("    " + string(key) + ": " + string(comment))
+		if i < len(names)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("  },\n")
+	for i, name := range names {
+		key, _ := json.Marshal(name)
+		value, err := json.MarshalIndent(defaults.Field(indexes[i]).Interface(), "  ", "  ")
+		if err != nil {
+			panic("sample config: " + err.Error()) // Config holds only plain JSON types
+		}
+		b.WriteString("  " + string(key) + ": " + string(value))
+		if i < len(names)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
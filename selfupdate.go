@@ -4,7 +4,8 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"runtime"
@@ -56,7 +57,7 @@ func applyUpdate(version string) error {
 		return fmt.Errorf("permission denied (download manually: %s)", downloadURL)
 	}
 
-	log.Printf("Downloading %s...", downloadURL)
+	slog.Info("Downloading update", "url", downloadURL)
 	// No client timeout — downloads can be large and slow on constrained links.
 	dlResp, err := http.Get(downloadURL)
 	if err != nil {
@@ -73,20 +74,78 @@ func applyUpdate(version string) error {
 		return fmt.Errorf("decompression failed: %w", err)
 	}
 
-	if err := selfupdate.Apply(r, opts); err != nil {
+	if err := selfUpdateWithRollback(r, opts); err != nil {
 		return fmt.Errorf("apply failed: %w", err)
 	}
 
-	log.Printf("Updated to %s successfully.", version)
+	slog.Info("Updated successfully", "version", version)
 	return nil
 }
 
+// selfupdateApply is selfupdate.Apply, a package-level var so tests can stub it.
+var selfupdateApply = selfupdate.Apply
+
+// selfUpdateWithRollback applies an update after saving a copy of the target
+// binary as <name>.bak. If the apply fails the copy is moved back in place;
+// on success it is removed. A copy is used rather than a rename because
+// selfupdate.Apply needs the original file present to swap it out.
+func selfUpdateWithRollback(r io.Reader, opts selfupdate.Options) error {
+	target := opts.TargetPath
+	if target == "" {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		target = exe
+	}
+	backup := target + ".bak"
+	if err := copyFile(target, backup); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+
+	if err := selfupdateApply(r, opts); err != nil {
+		if rerr := os.Rename(backup, target); rerr != nil {
+			return fmt.Errorf("%w (restore from %s failed: %v)", err, backup, rerr)
+		}
+		return err
+	}
+
+	if err := os.Remove(backup); err != nil {
+		slog.Warn("Could not remove backup", "path", backup, "error", err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst, keeping the source file mode.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
 func selfUpdate() {
 	fmt.Printf("Current version: %s-%s\n", Version, CommitHash)
 
 	latestRelease, err := fetchLatestVersion()
 	if err != nil {
-		log.Fatalf("Failed to check for updates: %v", err)
+		slog.Error("Failed to check for updates", "error", err)
+		os.Exit(1)
 	}
 	fmt.Printf("Latest release: %s\n", latestRelease)
 
@@ -106,6 +165,7 @@ func selfUpdate() {
 	}
 
 	if err := applyUpdate(latestRelease); err != nil {
-		log.Fatalf("Update failed: %v", err)
+		slog.Error("Update failed", "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minio/selfupdate"
+)
+
+func writeTestBinary(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude-quota")
+	if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSelfUpdateWithRollback_RestoresOnFailure(t *testing.T) {
+	target := writeTestBinary(t, "old binary")
+	orig := selfupdateApply
+	defer func() { selfupdateApply = orig }()
+	selfupdateApply = func(_ io.Reader, opts selfupdate.Options) error {
+		// Simulate a half-applied update that clobbered the target.
+		os.WriteFile(opts.TargetPath, []byte("corrupt"), 0o755)
+		return errors.New("boom")
+	}
+
+	err := selfUpdateWithRollback(strings.NewReader("new binary"), selfupdate.Options{TargetPath: target})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("err = %v, want apply error", err)
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != "old binary" {
+		t.Errorf("target = %q, want restored %q", data, "old binary")
+	}
+	if _, err := os.Stat(target + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("backup should be gone after restore, stat err = %v", err)
+	}
+}
+
+func TestSelfUpdateWithRollback_RemovesBackupOnSuccess(t *testing.T) {
+	target := writeTestBinary(t, "old binary")
+
+	err := selfUpdateWithRollback(strings.NewReader("new binary"), selfupdate.Options{TargetPath: target})
+	if err != nil {
+		t.Fatalf("selfUpdateWithRollback: %v", err)
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != "new binary" {
+		t.Errorf("target = %q, want %q", data, "new binary")
+	}
+	if _, err := os.Stat(target + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("backup should be removed on success, stat err = %v", err)
+	}
+}
@@ -11,3 +11,8 @@ import (
 func notifyExtraSignals(ch chan<- os.Signal) {
 	signal.Notify(ch, syscall.SIGTERM)
 }
+
+// notifyFetchSignals relays SIGUSR1, which requests an immediate fetch.
+func notifyFetchSignals(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
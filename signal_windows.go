@@ -7,3 +7,7 @@ import "os"
 func notifyExtraSignals(_ chan<- os.Signal) {
 	// No extra signals on Windows; os.Interrupt covers Ctrl+C.
 }
+
+func notifyFetchSignals(_ chan<- os.Signal) {
+	// No SIGUSR1 on Windows; use the Refresh menu item instead.
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -158,7 +158,7 @@ func (s *StatsStore) RecordFetch(state QuotaState, accountID string) {
 		timeToUnix(state.SevenDaySonnetResets),
 	)
 	if err != nil {
-		log.Printf("Failed to record fetch stats: %v", err)
+		slog.Error("Failed to record fetch stats", "error", err)
 	}
 }
 
@@ -196,7 +196,7 @@ func (s *StatsStore) UpsertAccount(refreshTokenHash string, info AccountInfo, su
 		subType, rateLimitTier, refreshTokenHash, now, now,
 	)
 	if err != nil {
-		log.Printf("Failed to upsert account: %v", err)
+		slog.Error("Failed to upsert account", "error", err)
 	}
 }
 
@@ -216,7 +216,7 @@ func (s *StatsStore) RecordError(accountID, errType string, httpStatus int, mess
 		time.Now().Unix(), accountIDVal, errType, httpStatusVal, message,
 	)
 	if err != nil {
-		log.Printf("Failed to record fetch error: %v", err)
+		slog.Error("Failed to record fetch error", "error", err)
 	}
 }
 
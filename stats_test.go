@@ -44,11 +44,11 @@ func TestRecordFetch_Full(t *testing.T) {
 	resets7d := now.Add(5 * 24 * time.Hour)
 	sat5h := now.Add(2 * time.Hour)
 	sat7d := now.Add(4 * 24 * time.Hour)
-	fiveH := 42.5
-	fiveHProj := 85.0
-	sevenD := 73.0
-	sevenDProj := 90.0
-	sonnet := 10.0
+	fiveH := Utilization(42.5)
+	fiveHProj := Utilization(85)
+	sevenD := Utilization(73)
+	sevenDProj := Utilization(90)
+	sonnet := Utilization(10)
 
 	state := QuotaState{
 		FiveHour:             &fiveH,
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// TelemetryPayload is the anonymous report sent after each successful fetch.
+// It describes the installation only; quota values and account details are
+// never included.
+type TelemetryPayload struct {
+	Version      string `json:"version"`
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	Indicator    string `json:"indicator"`
+	PollInterval int    `json:"poll_interval"`
+}
+
+// TelemetryClient posts TelemetryPayload reports to telemetry_url.
+// A nil *TelemetryClient is valid and sends nothing.
+type TelemetryClient struct {
+	url     string
+	client  *http.Client
+	payload TelemetryPayload
+}
+
+// NewTelemetryClient returns a client reporting cfg's settings, or nil
+// unless telemetry_enabled is set and telemetry_url is not empty.
+func NewTelemetryClient(cfg Config, client *http.Client) *TelemetryClient {
+	if !cfg.TelemetryEnabled || cfg.TelemetryURL == "" {
+		return nil
+	}
+	return &TelemetryClient{
+		url:    cfg.TelemetryURL,
+		client: client,
+		payload: TelemetryPayload{
+			Version:      Version,
+			OS:           runtime.GOOS,
+			Arch:         runtime.GOARCH,
+			Indicator:    cfg.Indicator,
+			PollInterval: cfg.PollIntervalSeconds,
+		},
+	}
+}
+
+// Send posts the report. It is a no-op on a nil client.
+func (t *TelemetryClient) Send(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	body, err := json.Marshal(t.payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "claude-quota/"+Version)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("telemetry endpoint returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// shouldAskTelemetryConsent reports whether the first run may ask for
+// telemetry consent: only when there is a telemetry_url to send to, the
+// banner is not silenced, and the process runs in the foreground of an
+// interactive terminal. Desktop launches and background jobs are never asked.
+func shouldAskTelemetryConsent(cfg Config, firstRun bool) bool {
+	if !firstRun || cfg.TelemetryURL == "" || isTOMLConfig(configPath) || quietMode() {
+		return false
+	}
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout) && isForeground(os.Stdin)
+}
+
+// saveTelemetryConsent writes the first-run config file with telemetry
+// enabled to url. The URL is saved too: on a first run it can only come from
+// CLAUDE_QUOTA_OVERRIDE_JSON, and the file must work without it.
+func saveTelemetryConsent(url string) error {
+	cfg := defaultConfig()
+	cfg.TelemetryEnabled = true
+	cfg.TelemetryURL = url
+	return saveConfig(cfg)
+}
+
+// askTelemetryConsent asks whether to enable telemetry to url and reports
+// the answer. Anything but yes declines.
+func askTelemetryConsent(in io.Reader, out io.Writer, url string) bool {
+	fmt.Fprintln(out, "claude-quota can send anonymous usage statistics (version, OS,")
+	fmt.Fprintln(out, "architecture, indicator and poll interval; never quota values) to")
+	fmt.Fprintf(out, "%s.\n", url)
+	fmt.Fprint(out, "Enable telemetry? [y/N]: ")
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a
+// pipe, file or /dev/null, as when launched from a desktop session.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTelemetryClient_Payload(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	cfg := defaultConfig()
+	cfg.TelemetryEnabled = true
+	cfg.TelemetryURL = srv.URL
+	cfg.Indicator = "arc"
+	if err := NewTelemetryClient(cfg, srv.Client()).Send(context.Background()); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("payload is not JSON: %v (%s)", err, body)
+	}
+	want := map[string]any{
+		"version":       Version,
+		"os":            runtime.GOOS,
+		"arch":          runtime.GOARCH,
+		"indicator":     "arc",
+		"poll_interval": float64(cfg.PollIntervalSeconds),
+	}
+	if len(got) != len(want) {
+		t.Errorf("payload = %v, want exactly %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("payload[%q] = %v, want %v", k, got[k], v)
+		}
+	}
+	for _, field := range []string{"utilization", "five_hour", "seven_day", "token", "email"} {
+		if strings.Contains(string(body), field) {
+			t.Errorf("payload contains %q: %s", field, body)
+		}
+	}
+}
+
+func TestTelemetryClient_Disabled(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	enabledNoURL := defaultConfig()
+	enabledNoURL.TelemetryEnabled = true
+	urlNotEnabled := defaultConfig()
+	urlNotEnabled.TelemetryURL = srv.URL
+
+	for name, cfg := range map[string]Config{"default": defaultConfig(), "no url": enabledNoURL, "not enabled": urlNotEnabled} {
+		tc := NewTelemetryClient(cfg, srv.Client())
+		if tc != nil {
+			t.Errorf("%s: NewTelemetryClient() = %v, want nil", name, tc)
+		}
+		if err := tc.Send(context.Background()); err != nil {
+			t.Errorf("%s: Send() on nil client = %v, want nil", name, err)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("telemetry endpoint got %d requests, want 0", n)
+	}
+}
+
+func TestTelemetryClient_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := defaultConfig()
+	cfg.TelemetryEnabled = true
+	cfg.TelemetryURL = srv.URL
+	if err := NewTelemetryClient(cfg, srv.Client()).Send(context.Background()); err == nil {
+		t.Error("Send() error = nil on HTTP 503")
+	}
+}
+
+func TestAskTelemetryConsent(t *testing.T) {
+	for input, want := range map[string]bool{"y\n": true, "YES\n": true, "n\n": false, "\n": false, "": false} {
+		var out bytes.Buffer
+		if got := askTelemetryConsent(strings.NewReader(input), &out, "https://telemetry.example.test"); got != want {
+			t.Errorf("askTelemetryConsent(%q) = %v, want %v", input, got, want)
+		}
+		if !strings.Contains(out.String(), "[y/N]") || !strings.Contains(out.String(), "https://telemetry.example.test") {
+			t.Errorf("prompt missing [y/N] or the URL: %q", out.String())
+		}
+	}
+}
+
+func TestSaveTelemetryConsent(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+
+	if err := saveTelemetryConsent("https://telemetry.example.test"); err != nil {
+		t.Fatalf("saveTelemetryConsent() error: %v", err)
+	}
+	load := loadConfigDetails()
+	if !load.File.TelemetryEnabled || load.File.TelemetryURL != "https://telemetry.example.test" {
+		t.Errorf("saved telemetry_enabled = %v, telemetry_url = %q; want true and the URL",
+			load.File.TelemetryEnabled, load.File.TelemetryURL)
+	}
+	if warnings, err := load.File.Validate(); len(warnings) != 0 || err != nil {
+		t.Errorf("saved config Validate() = %q, %v; want no warnings", warnings, err)
+	}
+}
+
+func TestShouldAskTelemetryConsent(t *testing.T) {
+	orig := configPath
+	defer func() { configPath = orig }()
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	withURL := defaultConfig()
+	withURL.TelemetryURL = "https://telemetry.example.test"
+
+	// go test never runs with a terminal on stdin, so every case is false;
+	// each one is also rejected before the terminal checks.
+	for name, tc := range map[string]struct {
+		cfg      Config
+		firstRun bool
+		quiet    string
+	}{
+		"no telemetry_url": {defaultConfig(), true, ""},
+		"not first run":    {withURL, false, ""},
+		"quiet":            {withURL, true, "1"},
+	} {
+		t.Setenv("CLAUDE_QUOTA_QUIET", tc.quiet)
+		if shouldAskTelemetryConsent(tc.cfg, tc.firstRun) {
+			t.Errorf("%s: shouldAskTelemetryConsent() = true, want false", name)
+		}
+	}
+}
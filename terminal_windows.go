@@ -0,0 +1,10 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// isForeground always reports true: Windows consoles have no background jobs.
+func isForeground(_ *os.File) bool {
+	return true
+}
@@ -1,10 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"log"
+	"image"
+	"io"
+	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"fyne.io/systray"
@@ -28,10 +36,26 @@ type App struct {
 	stats            *StatsStore
 	resolver         *AccountResolver
 	account          AccountInfo
-	quit             chan struct{} // closed on shutdown
-	restartRequested bool          // set before shutdown to trigger re-exec
-	fetchMu          sync.Mutex    // serializes refreshAccount+Fetch+record across goroutines
-	uiMu             sync.Mutex    // serializes updateUI calls
+	quit             chan struct{}      // closed on shutdown
+	triggerFetch     chan struct{}      // wakes pollLoop for an immediate fetch, buffered 1
+	restartRequested bool               // set before shutdown to trigger re-exec
+	fetchMu          sync.Mutex         // serializes refreshAccount+Fetch+record across goroutines
+	uiMu             sync.Mutex         // serializes updateUI calls
+	clock            func() time.Time   // time source for menu and tooltip text
+	resets           ResetDetector      // guarded by fetchMu
+	icons            *iconCache         // encoded icons, prewarmed with prewarm_icon_cache
+	blinker          *Blinker           // blinks the icon with blink_when_saturated
+	telemetry        *TelemetryClient   // nil unless telemetry is enabled
+	titleTemplate    *template.Template // parsed title_format, nil when unset or invalid
+	tooltipTemplate  *template.Template // parsed tooltip_format, nil when unset or invalid
+
+	// Icon debounce state, guarded by uiMu.
+	prevState     *QuotaState // state the current icon was rendered from, nil before the first
+	lastUIUpdate  time.Time   // when the icon was last sent to systray
+	lastIconError bool        // whether that icon showed an error state
+	pendingIcon   []byte      // latest icon held back by the debounce
+	lastIcon      []byte      // icon rendered from prevState
+	iconTimer     *time.Timer // flushes pendingIcon once the debounce expires
 
 	// Update state.
 	updateMu      sync.Mutex
@@ -52,21 +76,49 @@ type App struct {
 	mStats              *systray.MenuItem
 	mRefresh            *systray.MenuItem
 	mCheckUpdate        *systray.MenuItem
+	mAbout              *systray.MenuItem
+	mAboutItems         []*systray.MenuItem // submenu lines, one per aboutLines entry
 	mQuit               *systray.MenuItem
 }
 
 // NewApp creates an App from the given config and credentials.
 func NewApp(cfg Config, creds *OAuthCredentials, client *http.Client, stats *StatsStore, resolver *AccountResolver) *App {
 	return &App{
-		config:   cfg,
-		creds:    creds,
-		quota:    NewQuotaClient(creds, client),
-		stats:    stats,
-		resolver: resolver,
-		quit:     make(chan struct{}),
+		config:       cfg,
+		creds:        creds,
+		quota:        newConfiguredQuotaClient(cfg, creds, client),
+		stats:        stats,
+		resolver:     resolver,
+		quit:         make(chan struct{}),
+		triggerFetch: make(chan struct{}, 1),
+		clock:        time.Now,
+		icons:        newIconCache(cfg.Thresholds, RenderOptionsFromConfig(cfg)),
+		blinker:      NewBlinker(func(b []byte) { setTrayIcon(b) }, blankIcon(cfg.IconSize)),
+		telemetry:    NewTelemetryClient(cfg, client),
+
+		titleTemplate:   parseTitleFormat(cfg.TitleFormat),
+		tooltipTemplate: parseTooltipFormat(cfg.TooltipFormat, cfg.Thresholds),
 	}
 }
 
+// QuotaState returns a snapshot of the current quota state.
+func (a *App) QuotaState() QuotaState {
+	return a.quota.State()
+}
+
+// LastFetchDuration returns how long the last quota fetch took.
+func (a *App) LastFetchDuration() time.Duration {
+	return a.quota.LastFetchDuration()
+}
+
+// FetchOnce performs a single quota fetch outside the poll loop, without
+// touching the account, stats or UI. Returns true on success.
+func (a *App) FetchOnce() bool {
+	a.fetchMu.Lock()
+	defer a.fetchMu.Unlock()
+	return a.quota.FetchOnce()
+}
+
 // Run starts the systray. Blocks until the tray exits.
 func (a *App) Run() {
 	systray.Run(a.onReady, a.onExit)
@@ -80,6 +132,7 @@ func (a *App) Shutdown() {
 	default:
 		close(a.quit)
 	}
+	a.blinker.Stop()
 	systray.Quit()
 }
 
@@ -88,45 +141,11 @@ func (a *App) onReady() {
 	systray.SetTitle("")
 	systray.SetTooltip("Claude Quota")
 
-	// Create menu items.
-	if a.config.ShowAccount {
-		a.mAccountEmail = systray.AddMenuItem("", "Account email")
-		a.mAccountEmail.Disable()
-		a.mAccountEmail.Hide()
-		a.mAccountOrg = systray.AddMenuItem("", "Organization name")
-		a.mAccountOrg.Disable()
-		a.mAccountOrg.Hide()
-	}
-	a.mFiveHour = systray.AddMenuItem("5h: --", "5-hour quota")
-	a.mFiveHour.Disable()
-	a.mProjection = systray.AddMenuItem("", "Projected utilization at reset")
-	a.mProjection.Disable()
-	a.mProjection.Hide()
-	a.mSaturation = systray.AddMenuItem("", "Projected saturation time")
-	a.mSaturation.Disable()
-	a.mSaturation.Hide()
-	a.mSevenDay = systray.AddMenuItem("7d: --", "7-day quota")
-	a.mSevenDay.Disable()
-	a.mSevenDayProjection = systray.AddMenuItem("", "Projected 7d utilization at reset")
-	a.mSevenDayProjection.Disable()
-	a.mSevenDayProjection.Hide()
-	a.mSevenDaySaturation = systray.AddMenuItem("", "Projected 7d saturation time")
-	a.mSevenDaySaturation.Disable()
-	a.mSevenDaySaturation.Hide()
-	a.mSevenDaySonnet = systray.AddMenuItem("Sonnet 7d: --", "7-day Sonnet quota")
-	a.mSevenDaySonnet.Disable()
-
-	systray.AddSeparator()
-
-	a.mUpdated = systray.AddMenuItem("Updated: --", "Last update time")
-	a.mUpdated.Disable()
-	if a.stats != nil {
-		a.mStats = systray.AddMenuItem(fmt.Sprintf("Stats: %s", statsDBPath), "Stats database location")
-		a.mStats.Disable()
+	a.buildMenu()
+
+	if a.config.PrewarmIconCache {
+		go a.icons.warm(&a.uiMu)
 	}
-	a.mRefresh = systray.AddMenuItem("Refresh", "Refresh quota now")
-	a.mCheckUpdate = systray.AddMenuItem(fmt.Sprintf("Check for Updates (current %s)", Version), "Check for a newer version")
-	a.mQuit = systray.AddMenuItem("Quit", "Quit the application")
 
 	// Initial fetch + icon update.
 	a.fetchCycle()
@@ -150,18 +169,130 @@ func (a *App) onExit() {
 	}
 }
 
+// addMenuItem and addSeparator create tray menu entries; replaceable in tests.
+var (
+	addMenuItem  = systray.AddMenuItem
+	addSeparator = systray.AddSeparator
+)
+
+// addInfoItem adds a disabled, informational menu item, optionally hidden
+// until updateUI has something to show.
+func addInfoItem(title, tooltip string, hidden bool) *systray.MenuItem {
+	m := addMenuItem(title, tooltip)
+	m.Disable()
+	if hidden {
+		m.Hide()
+	}
+	return m
+}
+
+// buildMenu creates the tray menu items in the order given by menu_order.
+// Items left out of the order are never created and stay nil.
+func (a *App) buildMenu() {
+	if a.config.ShowAccount {
+		a.mAccountEmail = addInfoItem("", "Account email", true)
+		a.mAccountOrg = addInfoItem("", "Organization name", true)
+	}
+	for _, name := range resolveMenuOrder(a.config.MenuOrder) {
+		switch name {
+		case "5h":
+			a.mFiveHour = addInfoItem(bucketLabel(a.config.ModelAliases, "five_hour")+": --", "5-hour quota", false)
+		case "5h-projection":
+			a.mProjection = addInfoItem("", "Projected utilization at reset", true)
+		case "5h-saturation":
+			a.mSaturation = addInfoItem("", "Projected saturation time", true)
+		case "7d":
+			a.mSevenDay = addInfoItem(bucketLabel(a.config.ModelAliases, "seven_day")+": --", "7-day quota", false)
+		case "7d-projection":
+			a.mSevenDayProjection = addInfoItem("", "Projected 7d utilization at reset", true)
+		case "7d-saturation":
+			a.mSevenDaySaturation = addInfoItem("", "Projected 7d saturation time", true)
+		case "7d-sonnet":
+			a.mSevenDaySonnet = addInfoItem(bucketLabel(a.config.ModelAliases, "seven_day_sonnet")+": --", "7-day Sonnet quota", false)
+		case "separator":
+			addSeparator()
+		case "updated":
+			a.mUpdated = addInfoItem("Updated: --", "Last update time", false)
+			if a.stats != nil {
+				a.mStats = addInfoItem(fmt.Sprintf("Stats: %s", statsDBPath), "Stats database location", false)
+			}
+		case "refresh":
+			a.mRefresh = addMenuItem("Refresh", "Refresh quota now")
+			a.mCheckUpdate = addMenuItem(fmt.Sprintf("Check for Updates (current %s)", Version), "Check for a newer version")
+		case "about":
+			a.mAbout = addMenuItem("About", "Version and file locations")
+			for _, line := range aboutLines() {
+				m := a.mAbout.AddSubMenuItem(line, "")
+				m.Disable()
+				a.mAboutItems = append(a.mAboutItems, m)
+			}
+		case "quit":
+			a.mQuit = addMenuItem("Quit", "Quit the application")
+		}
+	}
+}
+
+// aboutLines returns the About submenu lines: version, config file path and
+// credentials file path.
+func aboutLines() []string {
+	return []string{
+		versionString(),
+		"Config: " + configPath,
+		"Credentials: " + credentialsPath,
+	}
+}
+
+// updateAbout refreshes the About submenu, as the paths may change at runtime.
+func (a *App) updateAbout() {
+	for i, line := range aboutLines() {
+		if i < len(a.mAboutItems) {
+			a.mAboutItems[i].SetTitle(line)
+		}
+	}
+}
+
+// clickedCh returns m's click channel, or nil (never ready) when m was not
+// created.
+func clickedCh(m *systray.MenuItem) <-chan struct{} {
+	if m == nil {
+		return nil
+	}
+	return m.ClickedCh
+}
+
+// setMenuTitle sets m's title; m may be nil when left out of menu_order.
+func setMenuTitle(m *systray.MenuItem, title string) {
+	if m != nil {
+		m.SetTitle(title)
+	}
+}
+
+// setMenuLine shows m with line as its title, or hides it when line is empty.
+// m may be nil when left out of menu_order.
+func setMenuLine(m *systray.MenuItem, line string) {
+	if m == nil {
+		return
+	}
+	if line == "" {
+		m.Hide()
+		return
+	}
+	m.SetTitle(line)
+	m.Show()
+}
+
 // eventLoop handles menu item clicks.
 func (a *App) eventLoop() {
 	for {
 		select {
 		case <-a.quit:
 			return
-		case <-a.mRefresh.ClickedCh:
+		case <-clickedCh(a.mRefresh):
 			a.fetchCycle()
 			a.updateUI()
-		case <-a.mCheckUpdate.ClickedCh:
+		case <-clickedCh(a.mCheckUpdate):
 			a.handleUpdateClick()
-		case <-a.mQuit.ClickedCh:
+		case <-clickedCh(a.mQuit):
 			a.Shutdown()
 			return
 		}
@@ -171,55 +302,155 @@ func (a *App) eventLoop() {
 // pollLoop periodically fetches quota and updates the UI.
 func (a *App) pollLoop() {
 	interval := time.Duration(a.config.PollIntervalSeconds) * time.Second
+	jitter := time.Duration(a.config.PollJitterSeconds) * time.Second
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
 
 	// Wait first — initial fetch already happened in onReady.
-	select {
-	case <-a.quit:
+	if !a.waitForPoll(jitteredInterval(interval, jitter, rng)) {
 		return
-	case <-time.After(interval):
 	}
 
 	for {
 		a.fetchCycle()
 		a.updateUI()
 
-		select {
-		case <-a.quit:
+		if !a.waitForPoll(jitteredInterval(interval, jitter, rng)) {
 			return
-		case <-time.After(interval):
 		}
 	}
 }
 
-// updatedTicker refreshes the "Updated: Xs ago" menu item every 10 seconds.
+// waitForPoll blocks until the next poll is due: after d, or earlier when
+// TriggerFetch is called. Returns false on shutdown.
+func (a *App) waitForPoll(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-a.quit:
+		return false
+	case <-a.triggerFetch:
+		return true
+	case <-timer.C:
+		return true
+	}
+}
+
+// TriggerFetch makes pollLoop fetch now and restart its interval. Requests
+// made while one is already pending are merged.
+func (a *App) TriggerFetch() {
+	select {
+	case a.triggerFetch <- struct{}{}:
+	default:
+	}
+}
+
+// updatedTickInterval is how often updatedTicker refreshes the "Updated" item.
+const updatedTickInterval = time.Second
+
+// updatedTicker refreshes the "Updated: Xs ago" menu item every
+// updatedTickInterval.
 func (a *App) updatedTicker() {
-	ticker := time.NewTicker(1 * time.Second)
+	ticker := time.NewTicker(updatedTickInterval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-a.quit:
 			return
 		case <-ticker.C:
-			state := a.quota.State()
-			a.mUpdated.SetTitle(formatUpdatedAgo(state.LastUpdate))
+			a.updateUpdatedLabel()
 		}
 	}
 }
 
+// updateUpdatedLabel sets the "Updated" menu item title. It holds uiMu so
+// the systray call never overlaps the ones made by updateUI.
+func (a *App) updateUpdatedLabel() {
+	a.uiMu.Lock()
+	defer a.uiMu.Unlock()
+	setMenuTitle(a.mUpdated, updatedTitle(a.quota.State(), a.clock()))
+}
+
 // fetchCycle runs the full refresh-account + fetch-quota + record cycle.
 // Serialized via fetchMu so pollLoop and eventLoop don't race on a.account.
 func (a *App) fetchCycle() {
 	a.fetchMu.Lock()
 	defer a.fetchMu.Unlock()
 
+	ctx, cancel := a.fetchContext()
+	defer cancel()
+
 	a.refreshAccount()
-	if a.quota.Fetch() {
+	if a.quota.FetchWithContext(ctx) {
 		a.recordStats()
+		a.sendTelemetry()
+		for _, window := range a.resets.Detect(a.quota.State(), a.clock()) {
+			slog.Info("Quota reset", "window", window)
+		}
 	} else {
 		a.recordError()
 	}
 }
 
+// sendTelemetry posts the telemetry report in the background, if enabled.
+// Failures are only logged at debug level: telemetry never affects the widget.
+func (a *App) sendTelemetry() {
+	if a.telemetry == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), FetchTimeout)
+		defer cancel()
+		if err := a.telemetry.Send(ctx); err != nil {
+			slog.Debug("Telemetry report failed", "error", err)
+		}
+	}()
+}
+
+// ResetDetector spots quota window resets by comparing each successful state
+// with the previous one: a window has reset when its previous reset time has
+// passed and utilization dropped.
+type ResetDetector struct {
+	prevState *QuotaState
+}
+
+// Detect records state and returns the labels ("5h", "7d") of windows that
+// reset since the previous call.
+func (d *ResetDetector) Detect(state QuotaState, now time.Time) []string {
+	prev := d.prevState
+	d.prevState = &state
+	if prev == nil {
+		return nil
+	}
+	var windows []string
+	if windowReset(prev.FiveHour, prev.FiveHourResets, state.FiveHour, now) {
+		windows = append(windows, "5h")
+	}
+	if windowReset(prev.SevenDay, prev.SevenDayResets, state.SevenDay, now) {
+		windows = append(windows, "7d")
+	}
+	return windows
+}
+
+// windowReset reports whether prevResets has passed and utilization dropped.
+func windowReset(prevUtil *Utilization, prevResets *time.Time, cur *Utilization, now time.Time) bool {
+	return prevResets != nil && now.After(*prevResets) &&
+		prevUtil != nil && cur != nil && *cur < *prevUtil
+}
+
+// fetchContext returns a context canceled on shutdown or after the configured
+// timeout, so a hung request doesn't delay exit.
+func (a *App) fetchContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), configTimeout(a.config))
+	go func() {
+		select {
+		case <-a.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
 // refreshAccount resolves account identity, re-reading credentials if they changed.
 // Must be called under fetchMu.
 func (a *App) refreshAccount() {
@@ -231,7 +462,7 @@ func (a *App) refreshAccount() {
 	}
 	snap, err := a.creds.ReloadAndSnapshot()
 	if err != nil {
-		log.Printf("Credential reload failed: %v", err)
+		slog.Error("Credential reload failed", "error", err)
 		// Clear stale identity so fetches aren't attributed to wrong account.
 		a.account = AccountInfo{}
 		return
@@ -276,82 +507,232 @@ func (a *App) updateUI() {
 	a.uiMu.Lock()
 	defer a.uiMu.Unlock()
 	state := a.quota.State()
+	now := a.clock()
 
-	// Update icon.
-	img := renderIcon(state, a.config.Thresholds, RenderOptions{
-		FontSize:  a.config.FontSize,
-		IconSize:  a.config.IconSize,
-		FontName:  a.config.FontName,
-		HaloSize:  a.config.HaloSize,
-		Indicator: a.config.Indicator,
-		ShowText:  configShowText(a.config),
-	})
-	iconData, err := iconToBytes(img)
-	if err != nil {
-		log.Printf("Icon encode error: %v", err)
-	} else {
-		systray.SetIcon(iconData)
+	// Stop blinking before a new icon is pushed, so Stop's restore of the
+	// solid frame cannot overwrite it.
+	blink := a.config.BlinkWhenSaturated && state.Error == "" && state.IsSaturated()
+	if !blink {
+		a.blinker.Stop()
+	}
+
+	// Update icon, unless the state it was rendered from draws the same one.
+	if a.prevState == nil || !state.sameIcon(*a.prevState) {
+		iconData, err := a.icons.render(state)
+		if err != nil {
+			slog.Error("Icon encode error", "error", err)
+		} else {
+			a.pushIcon(iconData, state.Error != "")
+			a.prevState = &state
+			a.lastIcon = iconData
+		}
+	}
+	if blink && a.lastIcon != nil {
+		a.blinker.Start(a.lastIcon, blinkInterval)
 	}
 
 	// Update tooltip.
-	systray.SetTooltip(buildTooltip(state))
+	opts := tooltipOptionsFromConfig(a.config)
+	tooltip := executeTooltipTemplate(a.tooltipTemplate, state, a.config.Thresholds, a.clock())
+	if tooltip == "" {
+		tooltip = buildTooltip(state, opts, a.clock)
+	}
+	if a.config.Verbose {
+		tooltip += "\n" + formatFetchStats(a.quota.Stats())
+	}
+	systray.SetTooltip(tooltip)
+	systray.SetTitle(formatTitle(a.titleTemplate, state))
 
 	// Update menu items.
-	if a.mAccountEmail != nil {
-		if account.EmailAddress != "" {
-			a.mAccountEmail.SetTitle("Acct: " + account.EmailAddress)
-			a.mAccountEmail.Show()
-		} else {
-			a.mAccountEmail.Hide()
-		}
+	email, org := "", ""
+	if account.EmailAddress != "" {
+		email = "Acct: " + account.EmailAddress
 	}
-	if a.mAccountOrg != nil {
-		if account.OrganizationName != "" {
-			a.mAccountOrg.SetTitle("Org: " + account.OrganizationName)
-			a.mAccountOrg.Show()
-		} else {
-			a.mAccountOrg.Hide()
-		}
+	if account.OrganizationName != "" {
+		org = "Org: " + account.OrganizationName
 	}
-	a.mFiveHour.SetTitle(formatQuotaLine("5h", state.FiveHour, state.FiveHourResets))
+	setMenuLine(a.mAccountEmail, email)
+	setMenuLine(a.mAccountOrg, org)
+	a.updateAbout()
+
+	// Projection and saturation lines only apply while the window has data.
+	setMenuTitle(a.mFiveHour, formatQuotaLine(bucketLabel(opts.aliases, "five_hour"), state.FiveHour, state.FiveHourResets, now, opts.timeDisplay))
+	var projLine, satLine string
 	if state.FiveHour != nil {
-		if projLine := formatProjectionLine(state.FiveHourProjected); projLine != "" {
-			a.mProjection.SetTitle(projLine)
-			a.mProjection.Show()
-		} else {
-			a.mProjection.Hide()
+		if !opts.hideProjection {
+			projLine = formatProjectionLine(state.FiveHourProjected, state.FiveHourProjectedLow, state.FiveHourProjectedHigh)
 		}
-		if satLine := formatSaturationLine(state.FiveHourSaturation); satLine != "" {
-			a.mSaturation.SetTitle(satLine)
-			a.mSaturation.Show()
-		} else {
-			a.mSaturation.Hide()
+		if showSaturation(state.FiveHourSaturation, now, opts.maxSaturation) {
+			satLine = formatSaturationLine(state.FiveHourSaturation, now)
 		}
-	} else {
-		a.mProjection.Hide()
-		a.mSaturation.Hide()
 	}
-	a.mSevenDay.SetTitle(formatQuotaLine("7d", state.SevenDay, state.SevenDayResets))
+	setMenuLine(a.mProjection, projLine)
+	setMenuLine(a.mSaturation, satLine)
+
+	setMenuTitle(a.mSevenDay, formatQuotaLine(bucketLabel(opts.aliases, "seven_day"), state.SevenDay, state.SevenDayResets, now, opts.timeDisplay))
+	projLine, satLine = "", ""
 	if state.SevenDay != nil {
-		if projLine := formatProjectionLine(state.SevenDayProjected); projLine != "" {
-			a.mSevenDayProjection.SetTitle(projLine)
-			a.mSevenDayProjection.Show()
-		} else {
-			a.mSevenDayProjection.Hide()
+		if !opts.hideProjection {
+			projLine = formatProjectionLine(state.SevenDayProjected, nil, nil)
 		}
-		if satLine := formatSaturationLine(state.SevenDaySaturation); satLine != "" {
-			a.mSevenDaySaturation.SetTitle(satLine)
-			a.mSevenDaySaturation.Show()
-		} else {
-			a.mSevenDaySaturation.Hide()
+		satLine = formatSaturationLine(state.SevenDaySaturation, now)
+	}
+	setMenuLine(a.mSevenDayProjection, projLine)
+	setMenuLine(a.mSevenDaySaturation, satLine)
+
+	setMenuTitle(a.mSevenDaySonnet, formatQuotaLine(bucketLabel(opts.aliases, "seven_day_sonnet"), state.SevenDaySonnet, state.SevenDaySonnetResets, now, opts.timeDisplay))
+
+	setMenuTitle(a.mUpdated, updatedTitle(state, now))
+}
+
+// iconDebounce is the minimum interval between systray icon updates.
+// Rapid back-to-back updates cause visible flicker on Windows and macOS.
+const iconDebounce = time.Second
+
+// setTrayIcon sends icon bytes to the systray; replaceable in tests.
+var setTrayIcon = systray.SetIcon
+
+// pushIcon sends iconData to the systray, at most once per iconDebounce.
+// An error/non-error transition bypasses the debounce. A held-back icon is
+// not dropped: the latest one is sent when the debounce expires.
+// Caller must hold uiMu.
+func (a *App) pushIcon(iconData []byte, hasError bool) {
+	now := a.clock()
+	elapsed := now.Sub(a.lastUIUpdate)
+	if a.lastUIUpdate.IsZero() || elapsed >= iconDebounce || hasError != a.lastIconError {
+		if a.iconTimer != nil {
+			a.iconTimer.Stop()
+			a.iconTimer = nil
 		}
-	} else {
-		a.mSevenDayProjection.Hide()
-		a.mSevenDaySaturation.Hide()
+		a.pendingIcon = nil
+		a.lastUIUpdate = now
+		a.lastIconError = hasError
+		setTrayIcon(iconData)
+		return
+	}
+	a.pendingIcon = iconData
+	if a.iconTimer == nil {
+		a.iconTimer = time.AfterFunc(iconDebounce-elapsed, a.flushIcon)
 	}
-	a.mSevenDaySonnet.SetTitle(formatQuotaLine("Sonnet 7d", state.SevenDaySonnet, state.SevenDaySonnetResets))
+}
 
-	a.mUpdated.SetTitle(formatUpdatedAgo(state.LastUpdate))
+// flushIcon sends the icon held back by pushIcon, if any.
+func (a *App) flushIcon() {
+	a.uiMu.Lock()
+	defer a.uiMu.Unlock()
+	a.iconTimer = nil
+	if a.pendingIcon == nil {
+		return
+	}
+	a.lastUIUpdate = a.clock()
+	setTrayIcon(a.pendingIcon)
+	a.pendingIcon = nil
+}
+
+// blinkInterval is how long each frame shows while the icon blinks.
+const blinkInterval = 500 * time.Millisecond
+
+// Blinker alternates the tray icon with a blank frame to draw attention to
+// a saturated quota. Methods are safe for concurrent use.
+type Blinker struct {
+	setIcon func([]byte)
+	blank   []byte
+
+	mu   sync.Mutex
+	icon []byte        // icon being blinked, nil when stopped
+	stop chan struct{} // closed to end the running goroutine
+	done chan struct{} // closed when that goroutine has exited
+}
+
+// NewBlinker returns a stopped Blinker that sends frames to setIcon and
+// uses blank as the off frame.
+func NewBlinker(setIcon func([]byte), blank []byte) *Blinker {
+	return &Blinker{setIcon: setIcon, blank: blank}
+}
+
+// Start blinks icon, switching frames every interval. Starting with the
+// icon already blinking is a no-op; a different icon restarts the blink.
+func (b *Blinker) Start(icon []byte, interval time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.icon != nil && bytes.Equal(b.icon, icon) {
+		return
+	}
+	b.stopLocked()
+	b.icon = icon
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go b.run(icon, interval, b.stop, b.done)
+}
+
+// Stop ends the blink and leaves the solid icon showing.
+func (b *Blinker) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopLocked()
+}
+
+// Blinking reports whether the blink goroutine is running.
+func (b *Blinker) Blinking() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.icon != nil
+}
+
+// stopLocked stops the goroutine and waits for it to restore the icon.
+// Caller must hold mu.
+func (b *Blinker) stopLocked() {
+	if b.icon == nil {
+		return
+	}
+	close(b.stop)
+	<-b.done
+	b.icon = nil
+}
+
+// run sends the blank and solid frames in turn until stop is closed, then
+// makes sure the solid frame is the one left showing.
+func (b *Blinker) run(icon []byte, interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	visible := true
+	for {
+		select {
+		case <-stop:
+			if !visible {
+				b.setIcon(icon)
+			}
+			return
+		case <-ticker.C:
+			visible = !visible
+			if visible {
+				b.setIcon(icon)
+			} else {
+				b.setIcon(b.blank)
+			}
+		}
+	}
+}
+
+// blankIcon returns a fully transparent encoded icon of the given size, the
+// off frame of a blink.
+func blankIcon(size int) []byte {
+	data, err := iconToBytes(image.NewNRGBA(image.Rect(0, 0, size, size)))
+	if err != nil {
+		slog.Error("Blank icon encode error", "error", err)
+	}
+	return data
+}
+
+// updatedTitle returns the "Updated: ..." menu title, with the token expiry
+// countdown appended when the token expires soon.
+func updatedTitle(state QuotaState, now time.Time) string {
+	title := formatUpdatedAgo(state.LastUpdate, now)
+	if expiry := formatTokenExpiry(state.TokenExpiresAt, now); expiry != "" {
+		title += " " + expiry
+	}
+	return title
 }
 
 // handleUpdateClick dispatches the click based on current update phase.
@@ -381,27 +762,27 @@ func (a *App) doUpdateCheck() {
 	go func() {
 		defer a.mCheckUpdate.Enable()
 
-		log.Printf("Checking for updates (current: %s)...", Version)
+		slog.Info("Checking for updates", "current", Version)
 		latest, err := fetchLatestVersion()
 		if err != nil {
-			log.Printf("Update check failed: %v", err)
+			slog.Error("Update check failed", "error", err)
 			a.mCheckUpdate.SetTitle(fmt.Sprintf("Update check failed: %v", err))
 			return
 		}
 
 		switch semver.Compare(latest, Version) {
 		case 1:
-			log.Printf("Update available: %s", latest)
+			slog.Info("Update available", "latest", latest)
 			a.updateMu.Lock()
 			a.updateVersion = latest
 			a.updatePhase = updatePhaseReady
 			a.updateMu.Unlock()
 			a.mCheckUpdate.SetTitle(fmt.Sprintf("Update available: %s (current: %s)", latest, Version))
 		case -1:
-			log.Printf("Newer than latest release (%s)", latest)
+			slog.Info("Newer than latest release", "latest", latest)
 			a.mCheckUpdate.SetTitle(fmt.Sprintf("Newer than latest release (%s)", latest))
 		default:
-			log.Printf("Up to date (%s)", Version)
+			slog.Info("Up to date", "current", Version)
 			a.mCheckUpdate.SetTitle(fmt.Sprintf("Up to date (%s)", Version))
 		}
 	}()
@@ -413,7 +794,7 @@ func (a *App) doApplyUpdate(version string) {
 	a.mCheckUpdate.Disable()
 	go func() {
 		if err := applyUpdate(version); err != nil {
-			log.Printf("Update error: %v", err)
+			slog.Error("Update error", "error", err)
 			a.mCheckUpdate.SetTitle(fmt.Sprintf("Update failed: %v", err))
 			// Reset to ready so user can retry.
 			a.updateMu.Lock()
@@ -430,33 +811,142 @@ func (a *App) doApplyUpdate(version string) {
 	}()
 }
 
-// buildTooltip generates tooltip text from state.
-func buildTooltip(state QuotaState) string {
+// tooltipOptions holds the config settings that shape the tooltip and menu
+// lines. The zero value shows everything with both reset time formats.
+type tooltipOptions struct {
+	timeDisplay    string            // see formatQuotaLine
+	aliases        map[string]string // window labels, see bucketLabel
+	maxSaturation  time.Duration     // see showSaturation
+	hideProjection bool              // omit the projection lines
+}
+
+// tooltipOptionsFromConfig returns the tooltip options set in cfg.
+func tooltipOptionsFromConfig(cfg Config) tooltipOptions {
+	return tooltipOptions{
+		timeDisplay:    cfg.TimeDisplay,
+		aliases:        cfg.ModelAliases,
+		maxSaturation:  time.Duration(cfg.MaxSaturationDisplayMinutes) * time.Minute,
+		hideProjection: !cfg.ShowProjection,
+	}
+}
+
+// tooltipData holds the fields available to tooltip_format templates: those
+// of title_format, with percentages likewise bare numbers or "" when
+// unknown, plus the 7-day projection and the time left until each window
+// resets ("2h 30m", "" when unknown).
+type tooltipData struct {
+	titleData
+	SevenDayProjected    string
+	FiveHourResets       string
+	SevenDayResets       string
+	SevenDaySonnetResets string
+}
+
+// newTooltipData returns the tooltip_format view of state at now.
+func newTooltipData(state QuotaState, now time.Time) tooltipData {
+	remaining := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return formatTimeRemaining(t, now)
+	}
+	return tooltipData{
+		titleData:            newTitleData(state),
+		SevenDayProjected:    titlePercent(state.SevenDayProjected),
+		FiveHourResets:       remaining(state.FiveHourResets),
+		SevenDayResets:       remaining(state.SevenDayResets),
+		SevenDaySonnetResets: remaining(state.SevenDaySonnetResets),
+	}
+}
+
+// tooltipFuncs returns the helpers available to tooltip_format templates:
+// FiveHourBar, SevenDayBar and SevenDaySonnetBar take a width and return an
+// ASCII bar (see formatASCIIBar); Level takes a percentage field such as
+// .FiveHour and returns its threshold band (see utilizationLevel).
+func tooltipFuncs(state QuotaState, th Thresholds) template.FuncMap {
+	return template.FuncMap{
+		"FiveHourBar":       func(width int) string { return formatASCIIBar(state.FiveHour, width) },
+		"SevenDayBar":       func(width int) string { return formatASCIIBar(state.SevenDay, width) },
+		"SevenDaySonnetBar": func(width int) string { return formatASCIIBar(state.SevenDaySonnet, width) },
+		"Level": func(percent string) string {
+			v, err := strconv.ParseFloat(percent, 64)
+			if err != nil {
+				return utilizationLevel(nil, th)
+			}
+			u := Utilization(v)
+			return utilizationLevel(&u, th)
+		},
+	}
+}
+
+// parseTooltipFormat parses the tooltip_format template s once, at startup.
+// It returns nil for an empty s, and logs a warning and returns nil when s
+// does not parse or fails to render, e.g. on an unknown field or a helper
+// called with the wrong arguments.
+func parseTooltipFormat(s string, th Thresholds) *template.Template {
+	if s == "" {
+		return nil
+	}
+	t, err := template.New("tooltip").Funcs(tooltipFuncs(QuotaState{}, th)).Parse(s)
+	if err == nil {
+		err = t.Execute(io.Discard, tooltipData{})
+	}
+	if err != nil {
+		slog.Warn("Invalid tooltip_format, using default tooltip", "tooltip_format", s, "error", err)
+		return nil
+	}
+	return t
+}
+
+// executeTooltipTemplate renders the parsed tooltip_format template t for
+// state at now, e.g. "5h {{FiveHourBar 10}} {{.FiveHour}}%" →
+// "5h ####------ 42%". Returns "" when t is nil or fails, in which case the
+// caller falls back to buildTooltip.
+func executeTooltipTemplate(t *template.Template, state QuotaState, th Thresholds, now time.Time) string {
+	if t == nil {
+		return ""
+	}
+	// The bar helpers close over state, so bind them on a copy.
+	t, err := t.Clone()
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	if err := t.Funcs(tooltipFuncs(state, th)).Execute(&b, newTooltipData(state, now)); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+// buildTooltip generates tooltip text from state, with relative times
+// computed against clock and lines shaped by opts.
+func buildTooltip(state QuotaState, opts tooltipOptions, clock func() time.Time) string {
+	now := clock()
 	lines := "Claude Quota"
 
 	if state.Error != "" {
 		lines += "\nError: " + state.Error
 	} else {
 		if state.FiveHour != nil {
-			lines += "\n" + formatQuotaLine("5h", state.FiveHour, state.FiveHourResets)
-			if state.FiveHourProjected != nil {
-				lines += "\n" + formatProjectionLine(state.FiveHourProjected)
+			lines += "\n" + formatQuotaLine(bucketLabel(opts.aliases, "five_hour"), state.FiveHour, state.FiveHourResets, now, opts.timeDisplay)
+			if state.FiveHourProjected != nil && !opts.hideProjection {
+				lines += "\n" + formatProjectionLine(state.FiveHourProjected, state.FiveHourProjectedLow, state.FiveHourProjectedHigh)
 			}
-			if state.FiveHourSaturation != nil {
-				lines += "\n" + formatSaturationLine(state.FiveHourSaturation)
+			if showSaturation(state.FiveHourSaturation, now, opts.maxSaturation) {
+				lines += "\n" + formatSaturationLine(state.FiveHourSaturation, now)
 			}
 		}
 		if state.SevenDay != nil {
-			lines += "\n" + formatQuotaLine("7d", state.SevenDay, state.SevenDayResets)
-			if state.SevenDayProjected != nil {
-				lines += "\n" + formatProjectionLine(state.SevenDayProjected)
+			lines += "\n" + formatQuotaLine(bucketLabel(opts.aliases, "seven_day"), state.SevenDay, state.SevenDayResets, now, opts.timeDisplay)
+			if state.SevenDayProjected != nil && !opts.hideProjection {
+				lines += "\n" + formatProjectionLine(state.SevenDayProjected, nil, nil)
 			}
 			if state.SevenDaySaturation != nil {
-				lines += "\n" + formatSaturationLine(state.SevenDaySaturation)
+				lines += "\n" + formatSaturationLine(state.SevenDaySaturation, now)
 			}
 		}
 		if state.SevenDaySonnet != nil {
-			lines += "\n" + formatQuotaLine("Sonnet 7d", state.SevenDaySonnet, state.SevenDaySonnetResets)
+			lines += "\n" + formatQuotaLine(bucketLabel(opts.aliases, "seven_day_sonnet"), state.SevenDaySonnet, state.SevenDaySonnetResets, now, opts.timeDisplay)
 		}
 	}
 
@@ -464,6 +954,9 @@ func buildTooltip(state QuotaState) string {
 		local := state.LastUpdate.Local()
 		lines += fmt.Sprintf("\nUpdated: %s", local.Format("15:04:05"))
 	}
+	if expiry := formatTokenExpiry(state.TokenExpiresAt, now); expiry != "" {
+		lines += "\n" + expiry
+	}
 
 	return lines
 }
@@ -1,22 +1,127 @@
 package main
 
 import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"fyne.io/systray"
 )
 
+// fixedClock returns a clock that always reports t.
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+// testTooltip returns buildTooltip(state) at testNow, with the tooltip
+// options of the default config as changed by configure, which may be nil.
+func testTooltip(state QuotaState, configure func(*Config)) string {
+	cfg := defaultConfig()
+	if configure != nil {
+		configure(&cfg)
+	}
+	return buildTooltip(state, tooltipOptionsFromConfig(cfg), fixedClock(testNow))
+}
+
 func TestBuildTooltip_Empty(t *testing.T) {
 	state := QuotaState{}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if got != "Claude Quota" {
 		t.Errorf("buildTooltip(empty) = %q, want %q", got, "Claude Quota")
 	}
 }
 
+func TestBuildTooltip_MaxSaturation(t *testing.T) {
+	v := Utilization(80)
+	for _, tc := range []struct {
+		in   time.Duration
+		want bool
+	}{
+		{45 * time.Minute, false},
+		{25 * time.Minute, true},
+	} {
+		sat := testNow.Add(tc.in)
+		state := QuotaState{FiveHour: &v, FiveHourSaturation: &sat}
+		got := testTooltip(state, func(c *Config) { c.MaxSaturationDisplayMinutes = 30 })
+		if shown := strings.Contains(got, "saturates in"); shown != tc.want {
+			t.Errorf("saturation in %v with 30m limit: shown = %v, want %v; tooltip:\n%s", tc.in, shown, tc.want, got)
+		}
+	}
+}
+
+func TestBuildTooltip_HideProjection(t *testing.T) {
+	v5, p5 := Utilization(80), Utilization(120)
+	v7, p7 := Utilization(30), Utilization(50)
+	sat := testNow.Add(20 * time.Minute)
+	state := QuotaState{
+		FiveHour: &v5, FiveHourProjected: &p5, FiveHourSaturation: &sat,
+		SevenDay: &v7, SevenDayProjected: &p7,
+	}
+	got := testTooltip(state, func(c *Config) { c.ShowProjection = false })
+	if strings.Contains(got, "  - projected ~") {
+		t.Errorf("buildTooltip() = %q, want no projection lines", got)
+	}
+	if !strings.Contains(got, "saturates in") {
+		t.Errorf("buildTooltip() = %q, want the saturation line kept", got)
+	}
+}
+
+func TestBuildTooltip_ModelAliases(t *testing.T) {
+	v := Utilization(42)
+	state := QuotaState{SevenDaySonnet: &v}
+	got := testTooltip(state, func(c *Config) { c.ModelAliases = map[string]string{"seven_day_sonnet": "S4"} })
+	if !strings.Contains(got, "\nS4: 42%") {
+		t.Errorf("buildTooltip() = %q, want an \"S4: 42%%\" line", got)
+	}
+	if strings.Contains(got, "Sonnet 7d") {
+		t.Errorf("buildTooltip() = %q, default label should be replaced", got)
+	}
+}
+
+func TestExecuteTooltipTemplate(t *testing.T) {
+	five, seven, proj := Utilization(42), Utilization(90), Utilization(97.4)
+	resets := testNow.Add(2*time.Hour + 30*time.Minute)
+	state := QuotaState{FiveHour: &five, SevenDay: &seven, SevenDayProjected: &proj, FiveHourResets: &resets}
+	th := Thresholds{Warning: 60, Critical: 85}
+
+	tests := []struct {
+		tmpl, want string
+	}{
+		{"5h {{FiveHourBar 10}} {{.FiveHour}}%", "5h ####------ 42%"},
+		{"7d {{SevenDayBar 4}} {{Level .SevenDay}}", "7d #### critical"},
+		{"{{Level .FiveHour}}/{{Level .SevenDaySonnet}}", "ok/unknown"},
+		{"{{SevenDaySonnetBar 3}}|{{.SevenDaySonnet}}|{{.Error}}", "---||"},
+		{"{{.SevenDayProjected}}% in {{.FiveHourResets}}{{.SevenDayResets}}", "97% in 2h 30m"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := executeTooltipTemplate(parseTooltipFormat(tt.tmpl, th), state, th, testNow); got != tt.want {
+			t.Errorf("executeTooltipTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
+
+func TestParseTooltipFormat_Invalid(t *testing.T) {
+	buf := captureSlog(t)
+	for _, tmpl := range []string{"{{.FiveHour", "{{.NoSuchField}}", "{{FiveHourBar}}"} {
+		if got := parseTooltipFormat(tmpl, Thresholds{}); got != nil {
+			t.Errorf("parseTooltipFormat(%q) = %v, want nil", tmpl, got)
+		}
+	}
+	if n := strings.Count(buf.String(), "Invalid tooltip_format"); n != 3 {
+		t.Errorf("logged %d warnings, want 3:\n%s", n, buf)
+	}
+}
+
 func TestBuildTooltip_Error(t *testing.T) {
 	state := QuotaState{Error: "something broke"}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if !strings.Contains(got, "Error: something broke") {
 		t.Errorf("buildTooltip(error) = %q, missing error line", got)
 	}
@@ -27,13 +132,13 @@ func TestBuildTooltip_Error(t *testing.T) {
 }
 
 func TestBuildTooltip_WithQuota(t *testing.T) {
-	v5 := 42.0
-	v7 := 10.0
+	v5 := Utilization(42)
+	v7 := Utilization(10)
 	state := QuotaState{
 		FiveHour: &v5,
 		SevenDay: &v7,
 	}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if !strings.Contains(got, "5h: 42%") {
 		t.Errorf("buildTooltip missing 5h line: %q", got)
 	}
@@ -43,39 +148,39 @@ func TestBuildTooltip_WithQuota(t *testing.T) {
 }
 
 func TestBuildTooltip_WithAllQuotas(t *testing.T) {
-	v5 := 42.0
-	v7 := 10.0
-	vs := 5.0
+	v5 := Utilization(42)
+	v7 := Utilization(10)
+	vs := Utilization(5)
 	state := QuotaState{
 		FiveHour:       &v5,
 		SevenDay:       &v7,
 		SevenDaySonnet: &vs,
 	}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if !strings.Contains(got, "Sonnet 7d: 5%") {
 		t.Errorf("buildTooltip missing Sonnet 7d line: %q", got)
 	}
 }
 
 func TestBuildTooltip_WithLastUpdate(t *testing.T) {
-	now := time.Now().UTC()
+	now := testNow
 	state := QuotaState{LastUpdate: &now}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if !strings.Contains(got, "Updated:") {
 		t.Errorf("buildTooltip missing Updated line: %q", got)
 	}
 }
 
 func TestBuildTooltip_WithProjection(t *testing.T) {
-	v5 := 33.0
-	proj := 36.0
-	resets := time.Now().Add(23 * time.Minute)
+	v5 := Utilization(33)
+	proj := Utilization(36)
+	resets := testNow.Add(23 * time.Minute)
 	state := QuotaState{
 		FiveHour:          &v5,
 		FiveHourResets:    &resets,
 		FiveHourProjected: &proj,
 	}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if !strings.Contains(got, "5h: 33%") {
 		t.Errorf("buildTooltip missing 5h line: %q", got)
 	}
@@ -85,32 +190,32 @@ func TestBuildTooltip_WithProjection(t *testing.T) {
 }
 
 func TestBuildTooltip_WithSaturation(t *testing.T) {
-	v5 := 80.0
-	proj := 400.0
-	resets := time.Now().Add(4 * time.Hour)
-	sat := time.Now().Add(15 * time.Minute)
+	v5 := Utilization(80)
+	proj := Utilization(400)
+	resets := testNow.Add(4 * time.Hour)
+	sat := testNow.Add(15 * time.Minute)
 	state := QuotaState{
 		FiveHour:           &v5,
 		FiveHourResets:     &resets,
 		FiveHourProjected:  &proj,
 		FiveHourSaturation: &sat,
 	}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if !strings.Contains(got, "projected ~400% at reset") {
 		t.Errorf("buildTooltip missing uncapped projection: %q", got)
 	}
-	if !strings.Contains(got, "saturates in") {
+	if !strings.Contains(got, "saturates in 15m") {
 		t.Errorf("buildTooltip missing saturation line: %q", got)
 	}
 }
 
 func TestBuildTooltip_ErrorHidesQuota(t *testing.T) {
-	v := 42.0
+	v := Utilization(42)
 	state := QuotaState{
 		FiveHour: &v,
 		Error:    "token expired",
 	}
-	got := buildTooltip(state)
+	got := testTooltip(state, nil)
 	if !strings.Contains(got, "Error: token expired") {
 		t.Errorf("buildTooltip missing error: %q", got)
 	}
@@ -119,3 +224,460 @@ func TestBuildTooltip_ErrorHidesQuota(t *testing.T) {
 		t.Errorf("buildTooltip should hide quota on error: %q", got)
 	}
 }
+
+func TestBuildTooltip_TokenExpiresSoon(t *testing.T) {
+	v5 := Utilization(42)
+	now := testNow
+	expires := testNow.Add(2*time.Hour + 30*time.Second)
+	state := QuotaState{FiveHour: &v5, LastUpdate: &now, TokenExpiresAt: &expires}
+	got := testTooltip(state, nil)
+	if !strings.Contains(got, "token expires in 2h") {
+		t.Errorf("buildTooltip missing token expiry: %q", got)
+	}
+}
+
+func TestBuildTooltip_TokenExpiresLater(t *testing.T) {
+	v5 := Utilization(42)
+	expires := testNow.Add(72 * time.Hour)
+	state := QuotaState{FiveHour: &v5, TokenExpiresAt: &expires}
+	got := testTooltip(state, nil)
+	if strings.Contains(got, "token expires") {
+		t.Errorf("buildTooltip should omit expiry more than 24h out: %q", got)
+	}
+}
+
+func TestUpdatedTitle_TokenExpiresSoon(t *testing.T) {
+	now := testNow
+	expires := testNow.Add(90*time.Minute + 30*time.Second)
+	got := updatedTitle(QuotaState{LastUpdate: &now, TokenExpiresAt: &expires}, testNow)
+	if !strings.HasPrefix(got, "Updated: ") || !strings.HasSuffix(got, "(token expires in 1h 30m)") {
+		t.Errorf("updatedTitle = %q, want Updated line with token expiry suffix", got)
+	}
+}
+
+func TestFetchContext_CanceledOnQuit(t *testing.T) {
+	a := &App{quit: make(chan struct{})}
+	ctx, cancel := a.fetchContext()
+	defer cancel()
+
+	close(a.quit)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("fetch context not canceled after quit")
+	}
+}
+
+func TestWaitForPoll_Trigger(t *testing.T) {
+	a := &App{quit: make(chan struct{}), triggerFetch: make(chan struct{}, 1)}
+	done := make(chan bool, 1)
+	go func() { done <- a.waitForPoll(time.Hour) }()
+
+	a.TriggerFetch()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("waitForPoll = false after trigger, want true")
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("waitForPoll did not return within 100ms of TriggerFetch")
+	}
+}
+
+func TestWaitForPoll_Quit(t *testing.T) {
+	a := &App{quit: make(chan struct{}), triggerFetch: make(chan struct{}, 1)}
+	close(a.quit)
+	if a.waitForPoll(time.Hour) {
+		t.Error("waitForPoll = true after quit, want false")
+	}
+}
+
+func TestTriggerFetch_Coalesces(t *testing.T) {
+	a := &App{quit: make(chan struct{}), triggerFetch: make(chan struct{}, 1)}
+	a.TriggerFetch()
+	a.TriggerFetch() // must not block
+	if n := len(a.triggerFetch); n != 1 {
+		t.Errorf("pending triggers = %d, want 1", n)
+	}
+}
+
+// recordTrayIcon replaces setTrayIcon with a recorder for the test's duration.
+func recordTrayIcon(t *testing.T) func() [][]byte {
+	t.Helper()
+	var mu sync.Mutex
+	var calls [][]byte
+	orig := setTrayIcon
+	t.Cleanup(func() { setTrayIcon = orig })
+	setTrayIcon = func(b []byte) {
+		mu.Lock()
+		calls = append(calls, b)
+		mu.Unlock()
+	}
+	return func() [][]byte {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]byte(nil), calls...)
+	}
+}
+
+func TestApp_FetchOnce(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"five_hour": {"utilization": 42.0}}`))
+	}))
+	defer srv.Close()
+
+	cfg := defaultConfig()
+	cfg.APIURL = srv.URL
+	creds := &OAuthCredentials{accessToken: "tok", expiresAt: time.Now().UnixMilli() + 300_000}
+	a := NewApp(cfg, creds, srv.Client(), nil, nil)
+
+	if !a.QuotaState().IsEmpty() {
+		t.Fatal("state should be empty before the first fetch")
+	}
+	if !a.FetchOnce() {
+		t.Fatalf("FetchOnce() returned false: %q", a.QuotaState().Error)
+	}
+	if u := a.QuotaState().FiveHour; u == nil || *u != 42 {
+		t.Errorf("FiveHour = %v, want 42", u)
+	}
+}
+
+func TestResetDetector(t *testing.T) {
+	now := testNow
+	before := now.Add(-time.Minute)
+	after := now.Add(time.Hour)
+	u := func(v float64) *Utilization { x := Utilization(v); return &x }
+
+	tests := []struct {
+		name string
+		prev QuotaState
+		cur  QuotaState
+		want []string
+	}{
+		{"5h reset", QuotaState{FiveHour: u(80), FiveHourResets: &before}, QuotaState{FiveHour: u(5)}, []string{"5h"}},
+		{"7d reset", QuotaState{SevenDay: u(90), SevenDayResets: &before}, QuotaState{SevenDay: u(1)}, []string{"7d"}},
+		{"both", QuotaState{FiveHour: u(80), FiveHourResets: &before, SevenDay: u(90), SevenDayResets: &before},
+			QuotaState{FiveHour: u(0), SevenDay: u(0)}, []string{"5h", "7d"}},
+		{"reset time not passed", QuotaState{FiveHour: u(80), FiveHourResets: &after}, QuotaState{FiveHour: u(5)}, nil},
+		{"utilization did not drop", QuotaState{FiveHour: u(10), FiveHourResets: &before}, QuotaState{FiveHour: u(12)}, nil},
+		{"no current data", QuotaState{FiveHour: u(80), FiveHourResets: &before}, QuotaState{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d ResetDetector
+			if got := d.Detect(tt.prev, now); got != nil {
+				t.Fatalf("first Detect = %v, want nil", got)
+			}
+			if got := d.Detect(tt.cur, now); !slices.Equal(got, tt.want) {
+				t.Errorf("Detect = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushIcon_Debounce(t *testing.T) {
+	calls := recordTrayIcon(t)
+	a := &App{clock: time.Now}
+
+	for i := range 5 {
+		a.uiMu.Lock()
+		a.pushIcon([]byte{byte(i)}, false)
+		a.uiMu.Unlock()
+	}
+	if got := len(calls()); got != 1 {
+		t.Fatalf("SetIcon called %d times immediately, want 1", got)
+	}
+
+	// The held-back icon is flushed once the debounce expires.
+	deadline := time.Now().Add(2 * iconDebounce)
+	for len(calls()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := calls()
+	if len(got) != 2 {
+		t.Fatalf("SetIcon called %d times, want 2", len(got))
+	}
+	if got[1][0] != 4 {
+		t.Errorf("flushed icon = %v, want the latest (4)", got[1])
+	}
+}
+
+func TestUpdateUI_SamePayloadPushesIconOnce(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("menu items need a live systray outside Linux")
+	}
+	calls := recordTrayIcon(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"five_hour": {"utilization": 42.0}, "seven_day": {"utilization": 10.0}}`))
+	}))
+	defer srv.Close()
+
+	cfg := defaultConfig()
+	cfg.APIURL = srv.URL
+	creds := &OAuthCredentials{accessToken: "tok", expiresAt: time.Now().UnixMilli() + 300_000}
+	a := NewApp(cfg, creds, srv.Client(), nil, nil)
+	now := testNow
+	a.clock = func() time.Time { return now }
+
+	var updates []time.Time
+	for range 2 {
+		if !a.FetchOnce() {
+			t.Fatalf("FetchOnce() failed: %q", a.QuotaState().Error)
+		}
+		updates = append(updates, *a.QuotaState().LastUpdate)
+		a.updateUI()
+		now = now.Add(2 * iconDebounce) // past the debounce, so a push is not held back
+		time.Sleep(time.Millisecond)    // so the second fetch gets a later LastUpdate
+	}
+	if updates[0].Equal(updates[1]) {
+		t.Fatal("both fetches have the same LastUpdate")
+	}
+	if got := len(calls()); got != 1 {
+		t.Errorf("SetIcon called %d times, want 1 for an unchanged payload", got)
+	}
+}
+
+func TestQuotaState_SameIcon(t *testing.T) {
+	five, fiveCopy, other := Utilization(42), Utilization(42), Utilization(43)
+	t1, t2 := testNow, testNow.Add(time.Minute)
+	a := QuotaState{FiveHour: &five, LastUpdate: &t1, TokenExpiresAt: &t1}
+	b := QuotaState{FiveHour: &fiveCopy, LastUpdate: &t2}
+	if !a.sameIcon(b) {
+		t.Error("sameIcon() = false for states differing only in bookkeeping")
+	}
+	for name, c := range map[string]QuotaState{
+		"FiveHour":          {FiveHour: &other},
+		"SevenDay":          {FiveHour: &five, SevenDay: &five},
+		"FiveHourProjected": {FiveHour: &five, FiveHourProjected: &other},
+		"Error":             {FiveHour: &five, Error: "boom"},
+		"TokenExpired":      {FiveHour: &five, TokenExpired: true},
+	} {
+		if a.sameIcon(c) {
+			t.Errorf("sameIcon() = true with %s changed", name)
+		}
+	}
+}
+
+func TestPushIcon_ErrorTransitionBypassesDebounce(t *testing.T) {
+	calls := recordTrayIcon(t)
+	a := &App{clock: fixedClock(testNow)}
+
+	a.pushIcon([]byte{0}, false)
+	a.pushIcon([]byte{1}, true)
+	a.pushIcon([]byte{2}, false)
+	if a.iconTimer != nil {
+		a.iconTimer.Stop()
+	}
+	if got := len(calls()); got != 3 {
+		t.Errorf("SetIcon called %d times, want 3 (each is an error transition)", got)
+	}
+}
+
+func TestUpdatedTicker_NoRace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("menu items need a live systray outside Linux")
+	}
+	recordTrayIcon(t)
+	five := Utilization(42)
+	now := time.Now()
+	creds := &OAuthCredentials{accessToken: "tok", expiresAt: now.UnixMilli() + 300_000}
+	a := NewApp(defaultConfig(), creds, http.DefaultClient, nil, nil)
+	a.quota.state = QuotaState{FiveHour: &five, LastUpdate: &now}
+	a.mUpdated = addMenuItem("Updated: --", "updated")
+
+	go a.updatedTicker()
+	defer close(a.quit)
+
+	// Run the UI refresh and label refresh paths concurrently; go test -race
+	// reports any unsynchronized access.
+	var wg sync.WaitGroup
+	deadline := time.Now().Add(time.Second)
+	for _, update := range []func(){a.updateUI, a.updateUpdatedLabel} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				update()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := a.mUpdated.String(); !strings.Contains(got, "Updated: ") {
+		t.Errorf("mUpdated = %s, want an Updated title", got)
+	}
+}
+
+func TestUpdateUI_InvalidFormatsWarnOnce(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("menu items need a live systray outside Linux")
+	}
+	recordTrayIcon(t)
+	logs := captureSlog(t)
+	cfg := defaultConfig()
+	cfg.TitleFormat = "{{.Unknown}}"
+	cfg.TooltipFormat = "{{FiveHourBar}}"
+	creds := &OAuthCredentials{accessToken: "tok", expiresAt: time.Now().UnixMilli() + 300_000}
+	a := NewApp(cfg, creds, http.DefaultClient, nil, nil)
+
+	for range 3 {
+		a.updateUI()
+	}
+	for _, msg := range []string{"Invalid title_format", "Invalid tooltip_format"} {
+		if n := strings.Count(logs.String(), msg); n != 1 {
+			t.Errorf("logged %q %d times, want once:\n%s", msg, n, logs)
+		}
+	}
+}
+
+func TestBuildMenu_CustomOrder(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("menu items need a live systray outside Linux")
+	}
+	var created []string
+	origItem, origSep := addMenuItem, addSeparator
+	defer func() { addMenuItem, addSeparator = origItem, origSep }()
+	addMenuItem = func(title, tooltip string) *systray.MenuItem {
+		created = append(created, tooltip)
+		return origItem(title, tooltip)
+	}
+	addSeparator = func() { created = append(created, "separator") }
+
+	a := &App{config: defaultConfig()}
+	a.config.MenuOrder = []string{"refresh", "separator", "5h", "7d"}
+	a.buildMenu()
+
+	want := []string{"Refresh quota now", "Check for a newer version", "separator", "5-hour quota", "7-day quota", "Quit the application"}
+	if !slices.Equal(created, want) {
+		t.Errorf("created items = %v, want %v", created, want)
+	}
+	if a.mSevenDaySonnet != nil || a.mUpdated != nil {
+		t.Error("items left out of menu_order should not be created")
+	}
+}
+
+func TestAboutMenu_Paths(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("menu items need a live systray outside Linux")
+	}
+	origConfig, origCreds := configPath, credentialsPath
+	defer func() { configPath, credentialsPath = origConfig, origCreds }()
+	configPath = "/tmp/cq/config.json"
+	credentialsPath = "/tmp/cq/.credentials.json"
+
+	a := &App{config: defaultConfig()}
+	a.config.MenuOrder = []string{"about"}
+	a.buildMenu()
+
+	if len(a.mAboutItems) != 3 {
+		t.Fatalf("About submenu has %d items, want 3", len(a.mAboutItems))
+	}
+	for _, want := range []string{Version, "Config: /tmp/cq/config.json", "Credentials: /tmp/cq/.credentials.json"} {
+		if !aboutItemsContain(a.mAboutItems, want) {
+			t.Errorf("About submenu missing %q: %v", want, a.mAboutItems)
+		}
+	}
+
+	credentialsPath = "/tmp/other/.credentials.json"
+	a.updateAbout()
+	if !aboutItemsContain(a.mAboutItems, "Credentials: /tmp/other/.credentials.json") {
+		t.Errorf("About submenu not updated after credentialsPath change: %v", a.mAboutItems)
+	}
+}
+
+// aboutItemsContain reports whether any item's title contains substr.
+func aboutItemsContain(items []*systray.MenuItem, substr string) bool {
+	for _, m := range items {
+		if strings.Contains(m.String(), substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetMenuHelpers_NilItem(t *testing.T) {
+	// Items left out of menu_order are nil; helpers must not panic.
+	setMenuTitle(nil, "x")
+	setMenuLine(nil, "x")
+	setMenuLine(nil, "")
+	if clickedCh(nil) != nil {
+		t.Error("clickedCh(nil) should be a nil channel")
+	}
+}
+
+// blinkRecorder records frames sent by a Blinker.
+type blinkRecorder struct {
+	mu     sync.Mutex
+	frames [][]byte
+	times  []time.Time
+}
+
+func (r *blinkRecorder) set(b []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, b)
+	r.times = append(r.times, time.Now())
+}
+
+func (r *blinkRecorder) snapshot() ([][]byte, []time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]byte(nil), r.frames...), append([]time.Time(nil), r.times...)
+}
+
+func TestBlinker_Alternates(t *testing.T) {
+	icon, blank := []byte("icon"), []byte("blank")
+	var rec blinkRecorder
+	b := NewBlinker(rec.set, blank)
+
+	const interval = 20 * time.Millisecond
+	start := time.Now()
+	b.Start(icon, interval)
+	time.Sleep(5*interval + interval/2)
+	b.Stop()
+
+	frames, times := rec.snapshot()
+	if len(frames) < 4 {
+		t.Fatalf("got %d frames, want at least 4", len(frames))
+	}
+	for i, f := range frames {
+		want := blank
+		if i%2 == 1 {
+			want = icon
+		}
+		if !bytes.Equal(f, want) {
+			t.Errorf("frame %d = %q, want %q", i, f, want)
+		}
+	}
+	if !bytes.Equal(frames[len(frames)-1], icon) {
+		t.Errorf("last frame = %q, want the solid icon after Stop", frames[len(frames)-1])
+	}
+	if first := times[0].Sub(start); first < interval {
+		t.Errorf("first frame after %v, want at least %v", first, interval)
+	}
+	if gap := times[1].Sub(times[0]); gap < interval/2 {
+		t.Errorf("frames %v apart, want about %v", gap, interval)
+	}
+}
+
+func TestBlinker_StartStop(t *testing.T) {
+	var rec blinkRecorder
+	b := NewBlinker(rec.set, []byte("blank"))
+	if b.Blinking() {
+		t.Fatal("Blinking() = true before Start")
+	}
+	b.Stop() // no-op when stopped
+
+	b.Start([]byte("a"), time.Hour)
+	b.Start([]byte("a"), time.Hour) // same icon keeps the running blink
+	if !b.Blinking() {
+		t.Fatal("Blinking() = false after Start")
+	}
+	b.Stop()
+	if b.Blinking() {
+		t.Error("Blinking() = true after Stop")
+	}
+	if frames, _ := rec.snapshot(); len(frames) != 0 {
+		t.Errorf("got %d frames, want none while the solid icon was showing", len(frames))
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import "fmt"
+
+// Utilization is a quota utilization percentage (0–100, may exceed 100 for projections).
+type Utilization float64
+
+// Fraction returns the utilization as a fraction (42% → 0.42), unclamped.
+func (u Utilization) Fraction() float64 {
+	return float64(u) / 100
+}
+
+// String formats the utilization as a rounded percentage, e.g. "42%".
+func (u Utilization) String() string {
+	return fmt.Sprintf("%.0f%%", float64(u))
+}
@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestUtilization_Fraction(t *testing.T) {
+	tests := []struct {
+		u    Utilization
+		want float64
+	}{
+		{0, 0},
+		{42, 0.42},
+		{100, 1},
+		{150, 1.5},
+	}
+	for _, tc := range tests {
+		if got := tc.u.Fraction(); got != tc.want {
+			t.Errorf("Utilization(%v).Fraction() = %v, want %v", float64(tc.u), got, tc.want)
+		}
+	}
+}
+
+func TestUtilization_String(t *testing.T) {
+	tests := []struct {
+		u    Utilization
+		want string
+	}{
+		{0, "0%"},
+		{42, "42%"},
+		{42.4, "42%"},
+		{150, "150%"},
+	}
+	for _, tc := range tests {
+		if got := tc.u.String(); got != tc.want {
+			t.Errorf("Utilization(%v).String() = %q, want %q", float64(tc.u), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// wslCredentialsFallback is a no-op outside Windows.
+func wslCredentialsFallback() {}
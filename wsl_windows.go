@@ -0,0 +1,83 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// wslOutput runs wsl.exe with the given arguments and returns its stdout.
+// It can be overridden in tests to avoid invoking the real WSL.
+var wslOutput = func(args ...string) ([]byte, error) {
+	return exec.Command("wsl.exe", args...).Output()
+}
+
+// detectWSLHome returns the UNC path of the home directory in the default WSL
+// distribution, e.g. \\wsl$\Ubuntu\home\user.
+func detectWSLHome() (string, error) {
+	out, err := wslOutput("--list", "--quiet")
+	if err != nil {
+		return "", fmt.Errorf("list WSL distributions: %w", err)
+	}
+	// The first listed distribution is the default one.
+	distro := firstLine(decodeWSLOutput(out))
+	if distro == "" {
+		return "", fmt.Errorf("no WSL distribution installed")
+	}
+
+	out, err = wslOutput("-d", distro, "--", "echo", "$HOME")
+	if err != nil {
+		return "", fmt.Errorf("read $HOME in WSL distribution %q: %w", distro, err)
+	}
+	home := firstLine(decodeWSLOutput(out))
+	if !strings.HasPrefix(home, "/") {
+		return "", fmt.Errorf("unexpected $HOME %q in WSL distribution %q", home, distro)
+	}
+
+	return `\\wsl$\` + distro + strings.ReplaceAll(home, "/", `\`), nil
+}
+
+// decodeWSLOutput converts wsl.exe output to a plain string. wsl.exe writes its
+// own messages (such as --list) as UTF-16LE, while commands run inside the
+// distribution produce UTF-8; dropping BOMs and NUL bytes handles both for the
+// ASCII names and paths we care about.
+func decodeWSLOutput(b []byte) string {
+	s := strings.ReplaceAll(string(b), "\x00", "")
+	return strings.TrimPrefix(strings.TrimPrefix(s, "\ufeff"), "\xff\xfe")
+}
+
+// firstLine returns the first non-empty trimmed line of s.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// wslCredentialsFallback points credentialsPath at the default WSL distribution
+// when the native Windows credentials file does not exist. Only called when no
+// claude home was configured explicitly.
+func wslCredentialsFallback() {
+	if _, err := os.Stat(credentialsPath); !os.IsNotExist(err) {
+		return
+	}
+	home, err := detectWSLHome()
+	if err != nil {
+		slog.Info("WSL auto-detection skipped", "error", err)
+		return
+	}
+	path := filepath.Join(home, ".claude", ".credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		slog.Info("No Claude credentials in WSL home", "home", home)
+		return
+	}
+	slog.Info("Using Claude credentials from WSL", "path", path)
+	credentialsPath = path
+}
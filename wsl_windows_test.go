@@ -0,0 +1,65 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// utf16LE encodes an ASCII string the way wsl.exe writes its own output.
+func utf16LE(s string) []byte {
+	b := []byte{0xff, 0xfe}
+	for _, c := range []byte(s) {
+		b = append(b, c, 0)
+	}
+	return b
+}
+
+func mockWSL(t *testing.T, fn func(args ...string) ([]byte, error)) {
+	t.Helper()
+	orig := wslOutput
+	t.Cleanup(func() { wslOutput = orig })
+	wslOutput = fn
+}
+
+func TestDetectWSLHome(t *testing.T) {
+	mockWSL(t, func(args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "--list --quiet":
+			return utf16LE("Ubuntu-22.04\r\nDebian\r\n"), nil
+		case "-d Ubuntu-22.04 -- echo $HOME":
+			return []byte("/home/alice\n"), nil
+		}
+		t.Errorf("unexpected wsl.exe args: %q", args)
+		return nil, errors.New("unexpected")
+	})
+
+	got, err := detectWSLHome()
+	if err != nil {
+		t.Fatalf("detectWSLHome() error: %v", err)
+	}
+	want := `\\wsl$\Ubuntu-22.04\home\alice`
+	if got != want {
+		t.Errorf("detectWSLHome() = %q, want %q", got, want)
+	}
+}
+
+func TestDetectWSLHome_NoDistro(t *testing.T) {
+	mockWSL(t, func(args ...string) ([]byte, error) {
+		return utf16LE("\r\n"), nil
+	})
+	if _, err := detectWSLHome(); err == nil {
+		t.Error("detectWSLHome() should fail when no distribution is installed")
+	}
+}
+
+func TestDetectWSLHome_WSLMissing(t *testing.T) {
+	mockWSL(t, func(args ...string) ([]byte, error) {
+		return nil, errors.New("executable file not found")
+	})
+	if _, err := detectWSLHome(); err == nil {
+		t.Error("detectWSLHome() should fail when wsl.exe is unavailable")
+	}
+}